@@ -8,6 +8,7 @@ require (
 	github.com/containrrr/shoutrrr v0.8.0
 	github.com/golang/mock v1.6.0
 	github.com/mattermost/mattermost/server/public v0.1.10
+	github.com/nicksnyder/go-i18n/v2 v2.5.0
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.10.0
 )
@@ -55,7 +56,7 @@ require (
 	golang.org/x/crypto v0.32.0 // indirect
 	golang.org/x/net v0.34.0 // indirect
 	golang.org/x/sys v0.29.0 // indirect
-	golang.org/x/text v0.21.0 // indirect
+	golang.org/x/text v0.21.0
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250124145028-65684f501c47 // indirect
 	google.golang.org/grpc v1.70.0 // indirect
 	google.golang.org/protobuf v1.36.4 // indirect
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
 	"github.com/mattermost/mattermost/server/public/plugin"
 )
 
@@ -12,21 +15,47 @@ import (
 func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Request) {
 	router := mux.NewRouter()
 
-	// Middleware to require that the user is logged in
-	router.Use(p.MattermostAuthorizationRequired)
+	// Thumbnails are fetched by the notification services, so they're authorized by a signed link
+	router.HandleFunc("/api/v1/files/{fileId}/thumbnail", p.ServeThumbnail).Methods(http.MethodGet)
 
 	apiRouter := router.PathPrefix("/api/v1").Subrouter()
 
+	// Middleware to require that the user is logged in
+	apiRouter.Use(p.MattermostAuthorizationRequired)
+
 	apiRouter.HandleFunc("/hello", p.HelloWorld).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/templates/preview", p.PreviewTemplate).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/subscriptions", p.GetSubscriptions).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/subscriptions", p.CreateSubscription).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/subscriptions/{subscriptionId}", p.DeleteSubscription).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/watches", p.GetWatchRules).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/watches", p.CreateWatchRule).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/watches/{watchId}", p.DeleteWatchRule).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/channels/{channelId}/bridges", p.GetBridges).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/channels/{channelId}/bridges", p.CreateBridge).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/channels/{channelId}/bridges/{bridgeId}", p.DeleteBridge).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/targets/health", p.GetTargetHealth).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/targets/actions/{action}", p.HandleTargetAction).Methods(http.MethodPost)
 
 	router.ServeHTTP(w, r)
 }
 
+var (
+	notAuthorizedMessage = &i18n.Message{
+		ID:    "api.not_authorized",
+		Other: "Not authorized",
+	}
+	invalidRequestBodyMessage = &i18n.Message{
+		ID:    "api.invalid_request_body",
+		Other: "Invalid request body",
+	}
+)
+
 func (p *Plugin) MattermostAuthorizationRequired(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		userID := r.Header.Get("Mattermost-User-ID")
 		if userID == "" {
-			http.Error(w, "Not authorized", http.StatusUnauthorized)
+			http.Error(w, p.localizer.Localize("", notAuthorizedMessage, nil), http.StatusUnauthorized)
 			return
 		}
 
@@ -40,3 +69,40 @@ func (p *Plugin) HelloWorld(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
+
+// PreviewTemplate validates a notification template and renders it with sample data, so that
+// users can check their template before saving it. An empty template previews the default one,
+// the default title template if the request is for a title, in the user's language.
+func (p *Plugin) PreviewTemplate(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	var request struct {
+		Template string `json:"template"`
+		Title    bool   `json:"title"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, p.localizer.Localize(userID, invalidRequestBodyMessage, nil), http.StatusBadRequest)
+		return
+	}
+
+	text := request.Template
+	if text == "" {
+		text = notification.DefaultTemplate(p.localizer, userID, request.Title)
+	}
+
+	preview, err := notification.RenderTemplate(text, &notification.SampleTemplateData, notification.FormatPlain)
+	if err != nil {
+		p.writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	p.writeJSON(w, http.StatusOK, map[string]string{"preview": preview})
+}
+
+func (p *Plugin) writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		p.API.LogError("Failed to write response", "error", err)
+	}
+}
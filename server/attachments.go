@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// defaultImagePreviewExpiry is how long thumbnail links stay valid unless configured otherwise.
+const defaultImagePreviewExpiry = 60 * time.Minute
+
+// getAttachments returns the files attached to a post and, when the admin enabled image previews,
+// a signed public link to the thumbnail of its first image.
+func (p *Plugin) getAttachments(post *model.Post) ([]notification.Attachment, string) {
+	var attachments []notification.Attachment
+	var imageURL string
+
+	for _, fileID := range post.FileIds {
+		info, appErr := p.API.GetFileInfo(fileID)
+		if appErr != nil {
+			p.API.LogWarn("Failed to get attached file for notification", "fileId", fileID, "error", appErr.Error())
+			continue
+		}
+
+		attachments = append(attachments, notification.Attachment{
+			Name:     info.Name,
+			Size:     info.Size,
+			MimeType: info.MimeType,
+		})
+
+		if imageURL == "" && info.IsImage() && info.ThumbnailPath != "" {
+			imageURL = p.getThumbnailURL(info.Id)
+		}
+	}
+
+	return attachments, imageURL
+}
+
+// getThumbnailURL returns a link to the thumbnail of a file that anyone can open until it expires,
+// or an empty string if image previews are disabled.
+func (p *Plugin) getThumbnailURL(fileID string) string {
+	config := p.getConfiguration()
+	if !config.EnableImagePreviews {
+		return ""
+	}
+
+	siteURL := p.API.GetConfig().ServiceSettings.SiteURL
+	if siteURL == nil || *siteURL == "" {
+		return ""
+	}
+
+	key, err := p.kvstore.GetSigningKey()
+	if err != nil {
+		p.API.LogWarn("Failed to get the signing key for image previews", "error", err.Error())
+		return ""
+	}
+
+	expiry := defaultImagePreviewExpiry
+	if config.ImagePreviewExpiryMinutes > 0 {
+		expiry = time.Duration(config.ImagePreviewExpiryMinutes) * time.Minute
+	}
+	expires := time.Now().Add(expiry).Unix()
+
+	return fmt.Sprintf("%s/plugins/%s/api/v1/files/%s/thumbnail?expires=%d&signature=%s",
+		strings.TrimSuffix(*siteURL, "/"), pluginID, fileID, expires, signThumbnail(key, fileID, expires))
+}
+
+// signThumbnail returns the signature of a thumbnail link.
+func signThumbnail(key []byte, fileID string, expires int64) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s:%d", fileID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeThumbnail serves the thumbnail of an image to the notification services. Rather than a
+// Mattermost session, requests are authorized by the signature of the link, until it expires.
+func (p *Plugin) ServeThumbnail(w http.ResponseWriter, r *http.Request) {
+	if !p.getConfiguration().EnableImagePreviews {
+		http.NotFound(w, r)
+		return
+	}
+
+	fileID := mux.Vars(r)["fileId"]
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		http.Error(w, "Link expired", http.StatusForbidden)
+		return
+	}
+
+	key, err := p.kvstore.GetSigningKey()
+	if err != nil {
+		p.API.LogError("Failed to get the signing key for image previews", "error", err.Error())
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	signature := r.URL.Query().Get("signature")
+	if !hmac.Equal([]byte(signature), []byte(signThumbnail(key, fileID, expires))) {
+		http.Error(w, "Invalid signature", http.StatusForbidden)
+		return
+	}
+
+	info, appErr := p.API.GetFileInfo(fileID)
+	if appErr != nil || info.DeleteAt != 0 || info.ThumbnailPath == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, appErr := p.API.ReadFile(info.ThumbnailPath)
+	if appErr != nil {
+		p.API.LogError("Failed to read thumbnail", "fileId", fileID, "error", appErr.Error())
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", expires-time.Now().Unix()))
+	if _, err := w.Write(data); err != nil {
+		p.API.LogError("Failed to write thumbnail", "error", err.Error())
+	}
+}
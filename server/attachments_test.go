@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignThumbnail(t *testing.T) {
+	assert := assert.New(t)
+
+	key := []byte("key")
+	signature := signThumbnail(key, "file-id", 1700000000)
+	assert.Len(signature, 64)
+	assert.Equal(signature, signThumbnail(key, "file-id", 1700000000))
+	assert.NotEqual(signature, signThumbnail(key, "file-id", 1700000001))
+	assert.NotEqual(signature, signThumbnail(key, "other-id", 1700000000))
+	assert.NotEqual(signature, signThumbnail([]byte("other"), "file-id", 1700000000))
+}
+
+func TestServeThumbnailRejectsInvalidLinks(t *testing.T) {
+	assert := assert.New(t)
+
+	get := func(plugin *Plugin, query string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/files/file-id/thumbnail?"+query, nil)
+		plugin.ServeHTTP(nil, w, r)
+		return w.Result().StatusCode
+	}
+
+	disabled := &Plugin{}
+	assert.Equal(http.StatusNotFound, get(disabled, "expires=9999999999&signature=abc"))
+
+	enabled := &Plugin{configuration: &configuration{EnableImagePreviews: true}}
+	assert.Equal(http.StatusForbidden, get(enabled, fmt.Sprintf("expires=%d&signature=abc", time.Now().Add(-time.Minute).Unix())))
+	assert.Equal(http.StatusForbidden, get(enabled, "signature=abc"))
+}
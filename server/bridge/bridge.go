@@ -0,0 +1,166 @@
+package bridge
+
+import (
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/postfilter"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrForbidden is returned when the user isn't an admin of the channel.
+	ErrForbidden = errors.New("only channel admins can manage the bridges of a channel")
+
+	// ErrUnsupportedChannel is returned for direct and group messages, which can't have bridges.
+	ErrUnsupportedChannel = errors.New("only public and private channels can have bridges")
+
+	// ErrNotFound is returned when the channel has no bridge with the given ID.
+	ErrNotFound = errors.New("bridge not found")
+)
+
+// InvalidURLError is returned when Shoutrrr can't send to the URL of a new bridge.
+type InvalidURLError struct {
+	Err error
+}
+
+func (e *InvalidURLError) Error() string {
+	return "invalid Shoutrrr URL: " + e.Err.Error()
+}
+
+func (e *InvalidURLError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	forbiddenMessage = &i18n.Message{
+		ID:    "bridge.error.forbidden",
+		Other: "Only channel admins can manage the bridges of this channel",
+	}
+	unsupportedChannelMessage = &i18n.Message{
+		ID:    "bridge.error.unsupported_channel",
+		Other: "Only public and private channels can have bridges",
+	}
+	notFoundMessage = &i18n.Message{
+		ID:    "bridge.error.not_found",
+		Other: "Bridge not found",
+	}
+	invalidURLMessage = &i18n.Message{
+		ID:    "bridge.error.invalid_url",
+		Other: "Invalid Shoutrrr URL: {{.Error}}",
+	}
+)
+
+// LocalizeError returns the message of an error of the service in the user's language, or the
+// error's own message for unexpected errors.
+func LocalizeError(localizer *i18n.Localizer, userID string, err error) string {
+	var invalidURL *InvalidURLError
+	switch {
+	case errors.Is(err, ErrForbidden):
+		return localizer.Localize(userID, forbiddenMessage, nil)
+	case errors.Is(err, ErrUnsupportedChannel):
+		return localizer.Localize(userID, unsupportedChannelMessage, nil)
+	case errors.Is(err, ErrNotFound):
+		return localizer.Localize(userID, notFoundMessage, nil)
+	case errors.As(err, &invalidURL):
+		return localizer.Localize(userID, invalidURLMessage, map[string]any{"Error": invalidURL.Err.Error()})
+	default:
+		return err.Error()
+	}
+}
+
+// Service manages the bridges of channels, which forward the channel's posts to Shoutrrr URLs
+// shared by the channel. Only the channel admins can manage them.
+type Service struct {
+	client  *pluginapi.Client
+	kvstore kvstore.KVStore
+}
+
+// NewService creates a new bridge service
+func NewService(client *pluginapi.Client, kvstore kvstore.KVStore) *Service {
+	return &Service{
+		client:  client,
+		kvstore: kvstore,
+	}
+}
+
+// checkPermission returns ErrForbidden unless the user can manage the channel's properties, as
+// channel and system admins can.
+func (s *Service) checkPermission(userID, channelID string) error {
+	channel, err := s.client.Channel.Get(channelID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get channel")
+	}
+
+	var permission *model.Permission
+	switch channel.Type {
+	case model.ChannelTypeOpen:
+		permission = model.PermissionManagePublicChannelProperties
+	case model.ChannelTypePrivate:
+		permission = model.PermissionManagePrivateChannelProperties
+	default:
+		return ErrUnsupportedChannel
+	}
+
+	if !s.client.User.HasPermissionToChannel(userID, channelID, permission) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// List returns the bridges of a channel.
+func (s *Service) List(userID, channelID string) ([]*kvstore.Bridge, error) {
+	if err := s.checkPermission(userID, channelID); err != nil {
+		return nil, err
+	}
+	return s.kvstore.GetBridges(channelID)
+}
+
+// Add creates a bridge forwarding the posts of a channel that pass the filter to a Shoutrrr URL.
+func (s *Service) Add(userID, channelID, serviceURL string, filter postfilter.Filter) (*kvstore.Bridge, error) {
+	if err := s.checkPermission(userID, channelID); err != nil {
+		return nil, err
+	}
+
+	if err := notification.ValidateURL(serviceURL); err != nil {
+		return nil, &InvalidURLError{Err: err}
+	}
+
+	bridge := &kvstore.Bridge{
+		ID:        model.NewId(),
+		URL:       serviceURL,
+		Filter:    filter.Normalize(),
+		CreatorID: userID,
+		CreateAt:  model.GetMillis(),
+	}
+	if err := s.kvstore.AddBridge(channelID, bridge); err != nil {
+		return nil, err
+	}
+	return bridge, nil
+}
+
+// Remove deletes a bridge of a channel.
+func (s *Service) Remove(userID, channelID, bridgeID string) error {
+	if err := s.checkPermission(userID, channelID); err != nil {
+		return err
+	}
+
+	deleted, err := s.kvstore.DeleteBridge(channelID, bridgeID)
+	if err != nil {
+		return err
+	}
+	if deleted == nil {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Redact returns a copy of a bridge with its URL redacted, as the URL holds the credentials of the
+// service and shouldn't be shown once saved.
+func Redact(bridge *kvstore.Bridge) *kvstore.Bridge {
+	redacted := *bridge
+	redacted.URL = notification.RedactURL(bridge.URL)
+	return &redacted
+}
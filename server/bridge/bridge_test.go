@@ -0,0 +1,29 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	assert := assert.New(t)
+
+	bridge := &kvstore.Bridge{ID: "bridge", URL: "ntfy://ntfy.sh/secret-topic"}
+	redacted := Redact(bridge)
+
+	assert.Equal("ntfy://ntfy.sh/***", redacted.URL)
+	assert.Equal("bridge", redacted.ID)
+	assert.Equal("ntfy://ntfy.sh/secret-topic", bridge.URL)
+}
+
+func TestLocalizeError(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("Only channel admins can manage the bridges of this channel", LocalizeError(nil, "user", ErrForbidden))
+	assert.Equal("Bridge not found", LocalizeError(nil, "user", errors.Wrap(ErrNotFound, "failed")))
+	assert.Equal("Invalid Shoutrrr URL: unknown service", LocalizeError(nil, "user", &InvalidURLError{Err: errors.New("unknown service")}))
+	assert.Equal("failed to get channel: boom", LocalizeError(nil, "user", errors.Wrap(errors.New("boom"), "failed to get channel")))
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/bridge"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// forwardToBridges forwards a post to the bridges of its channel whose filters match it, with
+// the sender's name so that readers on the other service know who wrote it.
+func (p *Plugin) forwardToBridges(post *model.Post) {
+	if post.IsSystemMessage() {
+		return
+	}
+
+	bridges, err := p.kvstore.GetBridges(post.ChannelId)
+	if err != nil {
+		p.API.LogError("Failed to get channel bridges", "error", err.Error(), "channelId", post.ChannelId)
+		return
+	}
+	if len(bridges) == 0 {
+		return
+	}
+
+	sender, appErr := p.API.GetUser(post.UserId)
+	if appErr != nil {
+		p.API.LogError("Failed to get sender for bridges", "error", appErr.Error())
+		return
+	}
+
+	channel, appErr := p.API.GetChannel(post.ChannelId)
+	if appErr != nil {
+		p.API.LogError("Failed to get channel for bridges", "error", appErr.Error())
+		return
+	}
+
+	team, appErr := p.API.GetTeam(channel.TeamId)
+	if appErr != nil {
+		p.API.LogError("Failed to get team for bridges", "error", appErr.Error())
+		return
+	}
+
+	attachments, imageURL := p.getAttachments(post)
+	bridgedPost := &notification.BridgedPost{
+		PostID:            post.Id,
+		ChannelID:         channel.Id,
+		ChannelName:       channel.Name,
+		TeamName:          team.Name,
+		SenderUsername:    sender.Username,
+		SenderDisplayName: sender.GetDisplayName(*p.API.GetConfig().TeamSettings.TeammateNameDisplay),
+		Message:           p.newExcerptBuilder(channel).Build(post.Message),
+		PostPriority:      getPostPriority(post),
+		Attachments:       attachments,
+		ImageURL:          imageURL,
+	}
+
+	for _, channelBridge := range bridges {
+		if !channelBridge.Matches(post, sender.Username) {
+			continue
+		}
+
+		if err := p.notificationService.SendBridgeNotification(channelBridge.URL, bridgedPost); err != nil {
+			p.API.LogError("Failed to forward post to bridge",
+				"error", err.Error(),
+				"channelId", channel.Id,
+				"bridgeId", channelBridge.ID)
+		}
+	}
+}
+
+// writeBridgeError writes an error of the bridge service with the matching status code.
+func (p *Plugin) writeBridgeError(w http.ResponseWriter, userID string, err error) {
+	var invalidURL *bridge.InvalidURLError
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, bridge.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, bridge.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, bridge.ErrUnsupportedChannel), errors.As(err, &invalidURL):
+		status = http.StatusBadRequest
+	default:
+		p.API.LogError("Failed to manage bridges", "error", err.Error(), "userId", userID)
+	}
+
+	http.Error(w, bridge.LocalizeError(p.localizer, userID, err), status)
+}
+
+// GetBridges returns the bridges of a channel, with their URLs redacted.
+func (p *Plugin) GetBridges(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	bridges, err := p.bridgeService.List(userID, mux.Vars(r)["channelId"])
+	if err != nil {
+		p.writeBridgeError(w, userID, err)
+		return
+	}
+
+	redacted := make([]*kvstore.Bridge, 0, len(bridges))
+	for _, channelBridge := range bridges {
+		redacted = append(redacted, bridge.Redact(channelBridge))
+	}
+
+	p.writeJSON(w, http.StatusOK, redacted)
+}
+
+// CreateBridge adds a bridge to a channel.
+func (p *Plugin) CreateBridge(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	var request kvstore.Bridge
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.URL == "" {
+		http.Error(w, p.localizer.Localize(userID, invalidRequestBodyMessage, nil), http.StatusBadRequest)
+		return
+	}
+
+	created, err := p.bridgeService.Add(userID, mux.Vars(r)["channelId"], request.URL, request.Filter)
+	if err != nil {
+		p.writeBridgeError(w, userID, err)
+		return
+	}
+
+	p.writeJSON(w, http.StatusCreated, bridge.Redact(created))
+}
+
+// DeleteBridge removes a bridge from a channel.
+func (p *Plugin) DeleteBridge(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	vars := mux.Vars(r)
+	if err := p.bridgeService.Remove(userID, vars["channelId"], vars["bridgeId"]); err != nil {
+		p.writeBridgeError(w, userID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,431 @@
+package command
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	adminCommand = "admin"
+
+	adminStatsCommand     = "stats"
+	adminFailingCommand   = "failing"
+	adminDisableCommand   = "disable"
+	adminPauseCommand     = "pause"
+	adminResumeCommand    = "resume"
+	adminFlushCommand     = "flush"
+	adminReplayCommand    = "replay"
+	adminReencryptCommand = "reencrypt"
+)
+
+// deliveryPausedSetting is the key of the kill switch in the plugin's configuration, lowercase
+// like the keys the System Console saves.
+const deliveryPausedSetting = "deliverypaused"
+
+// maxListedFailingUsers caps the users listed by /shoutrrr admin failing.
+const maxListedFailingUsers = 50
+
+var (
+	adminForbiddenMessage = &i18n.Message{
+		ID:    "command.admin.forbidden",
+		Other: "Only system admins can use /shoutrrr admin",
+	}
+	adminUsageMessage = &i18n.Message{
+		ID:    "command.admin.usage",
+		Other: "Usage: /shoutrrr admin [stats|failing|disable|pause|resume|flush|replay|reencrypt]",
+	}
+	adminDisableUsageMessage = &i18n.Message{
+		ID:    "command.admin.disable.usage",
+		Other: "Usage: /shoutrrr admin disable @username [label or number]",
+	}
+	adminErrorMessage = &i18n.Message{
+		ID:    "command.admin.error",
+		Other: "Something went wrong, see the server logs for details",
+	}
+	adminStatsTitleMessage = &i18n.Message{
+		ID:    "command.admin.stats.title",
+		Other: "#### Shoutrrr statistics",
+	}
+	adminStatsActiveMessage = &i18n.Message{
+		ID:    "command.admin.stats.active",
+		Other: "Notifications are being delivered.",
+	}
+	adminStatsPausedMessage = &i18n.Message{
+		ID:    "command.admin.stats.paused",
+		Other: "Delivery is paused: no notification is sent until /shoutrrr admin resume.",
+	}
+	adminStatsTargetsMessage = &i18n.Message{
+		ID:    "command.admin.stats.targets",
+		One:   "{{.Count}} target notified, of {{.Users}} users",
+		Other: "{{.Count}} targets notified, of {{.Users}} users",
+	}
+	adminStatsFailingMessage = &i18n.Message{
+		ID:    "command.admin.stats.failing",
+		One:   "{{.Count}} target failing, of {{.Users}} users",
+		Other: "{{.Count}} targets failing, of {{.Users}} users",
+	}
+	adminStatsWatchersMessage = &i18n.Message{
+		ID:    "command.admin.stats.watchers",
+		One:   "{{.Count}} user with watch rules",
+		Other: "{{.Count}} users with watch rules",
+	}
+	adminStatsSnoozedMessage = &i18n.Message{
+		ID:    "command.admin.stats.snoozed",
+		One:   "{{.Count}} user snoozed",
+		Other: "{{.Count}} users snoozed",
+	}
+	adminStatsRetriesMessage = &i18n.Message{
+		ID:    "command.admin.stats.retries",
+		One:   "{{.Count}} notification waiting for a retry",
+		Other: "{{.Count}} notifications waiting for a retry",
+	}
+	adminFailingNoneMessage = &i18n.Message{
+		ID:    "command.admin.failing.none",
+		Other: "No user has failing targets",
+	}
+	adminFailingTitleMessage = &i18n.Message{
+		ID:    "command.admin.failing.title",
+		Other: "Users with failing targets:",
+	}
+	adminFailingUserMessage = &i18n.Message{
+		ID:    "command.admin.failing.user",
+		One:   "@{{.Username}}: {{.Count}} failing target, last failed {{.Time}}: {{.Error}}",
+		Other: "@{{.Username}}: {{.Count}} failing targets, last failed {{.Time}}: {{.Error}}",
+	}
+	adminFailingMoreMessage = &i18n.Message{
+		ID:    "command.admin.failing.more",
+		One:   "and {{.Count}} more user",
+		Other: "and {{.Count}} more users",
+	}
+	adminUserNotFoundMessage = &i18n.Message{
+		ID:    "command.admin.user_not_found",
+		Other: "No user is named @{{.Username}}",
+	}
+	adminUserTargetNotFoundMessage = &i18n.Message{
+		ID:    "command.admin.disable.not_found",
+		Other: "None of the targets of @{{.Username}} matches {{.Target}}",
+	}
+	adminDisabledMessage = &i18n.Message{
+		ID:    "command.admin.disable.disabled",
+		One:   "Disabled {{.Count}} target of @{{.Username}}",
+		Other: "Disabled {{.Count}} targets of @{{.Username}}",
+	}
+	adminPausedMessage = &i18n.Message{
+		ID:    "command.admin.pause.paused",
+		Other: "Delivery paused: no notification is sent until /shoutrrr admin resume",
+	}
+	adminResumedMessage = &i18n.Message{
+		ID:    "command.admin.pause.resumed",
+		Other: "Delivery resumed",
+	}
+	adminFlushedMessage = &i18n.Message{
+		ID:    "command.admin.flush.flushed",
+		One:   "Dropped {{.Count}} notification waiting for a retry",
+		Other: "Dropped {{.Count}} notifications waiting for a retry",
+	}
+	adminReplayPausedMessage = &i18n.Message{
+		ID:    "command.admin.replay.paused",
+		Other: "Delivery is paused: resume it with /shoutrrr admin resume before replaying the retries",
+	}
+	adminReplayedMessage = &i18n.Message{
+		ID:    "command.admin.replay.replayed",
+		One:   "Sent {{.Count}} notification waiting for a retry, {{.Failed}} could not be sent",
+		Other: "Sent {{.Count}} notifications waiting for a retry, {{.Failed}} could not be sent",
+	}
+	adminReencryptedMessage = &i18n.Message{
+		ID:    "command.admin.reencrypt.reencrypted",
+		One:   "Re-encrypted {{.Count}} stored credential with a new key",
+		Other: "Re-encrypted {{.Count}} stored credentials with a new key",
+	}
+)
+
+func getAdminAutocompleteData() *model.AutocompleteData {
+	admin := model.NewAutocompleteData(adminCommand, "[command]", "Manage Shoutrrr for all users")
+	admin.RoleID = model.SystemAdminRoleId
+
+	admin.AddCommand(model.NewAutocompleteData(adminStatsCommand, "", "Show how many targets are notified and failing"))
+	admin.AddCommand(model.NewAutocompleteData(adminFailingCommand, "", "List the users with failing targets"))
+
+	disable := model.NewAutocompleteData(adminDisableCommand, "@username [label or number]", "Disable one or all of a user's targets")
+	disable.AddTextArgument("User whose targets to disable, and the target to disable, all if empty", "@username [label or number]", "")
+	admin.AddCommand(disable)
+
+	admin.AddCommand(model.NewAutocompleteData(adminPauseCommand, "", "Stop sending all notifications, until resumed"))
+	admin.AddCommand(model.NewAutocompleteData(adminResumeCommand, "", "Resume sending notifications"))
+	admin.AddCommand(model.NewAutocompleteData(adminFlushCommand, "", "Drop the notifications waiting for a retry"))
+	admin.AddCommand(model.NewAutocompleteData(adminReplayCommand, "", "Send the notifications waiting for a retry now"))
+	admin.AddCommand(model.NewAutocompleteData(adminReencryptCommand, "", "Encrypt the stored credentials with a new key"))
+
+	return admin
+}
+
+func (c *Handler) executeAdminCommand(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	userID := args.UserId
+	if !c.client.User.HasPermissionTo(userID, model.PermissionManageSystem) {
+		return c.ephemeral(c.localizer.Localize(userID, adminForbiddenMessage, nil))
+	}
+
+	if len(fields) == 0 {
+		return c.ephemeral(c.localizer.Localize(userID, adminUsageMessage, nil))
+	}
+
+	switch fields[0] {
+	case adminStatsCommand:
+		return c.executeAdminStatsCommand(args)
+	case adminFailingCommand:
+		return c.executeAdminFailingCommand(args)
+	case adminDisableCommand:
+		return c.executeAdminDisableCommand(args, fields[1:])
+	case adminPauseCommand, adminResumeCommand:
+		return c.executeAdminPauseCommand(args, fields[0] == adminPauseCommand)
+	case adminFlushCommand:
+		return c.executeAdminFlushCommand(args)
+	case adminReplayCommand:
+		return c.executeAdminReplayCommand(args)
+	case adminReencryptCommand:
+		return c.executeAdminReencryptCommand(args)
+	default:
+		return c.ephemeral(c.localizer.Localize(userID, adminUsageMessage, nil))
+	}
+}
+
+func (c *Handler) executeAdminStatsCommand(args *model.CommandArgs) *model.CommandResponse {
+	userID := args.UserId
+
+	statuses, err := c.kvstore.ListDeliveryStatuses()
+	if err != nil {
+		c.client.Log.Error("Failed to list delivery statuses", "error", err)
+		return c.ephemeral(c.localizer.Localize(userID, adminErrorMessage, nil))
+	}
+
+	users := map[string]bool{}
+	failingUsers := map[string]bool{}
+	failing := 0
+	for _, status := range statuses {
+		users[status.UserID] = true
+		if status.Failing() {
+			failingUsers[status.UserID] = true
+			failing++
+		}
+	}
+
+	lines := []string{c.localizer.Localize(userID, adminStatsTitleMessage, nil)}
+	if c.notifications.DeliveryPaused() {
+		lines = append(lines, c.localizer.Localize(userID, adminStatsPausedMessage, nil))
+	} else {
+		lines = append(lines, c.localizer.Localize(userID, adminStatsActiveMessage, nil))
+	}
+
+	lines = append(lines,
+		"- "+c.localizer.LocalizeCount(userID, adminStatsTargetsMessage, len(statuses), map[string]any{"Users": len(users)}),
+		"- "+c.localizer.LocalizeCount(userID, adminStatsFailingMessage, failing, map[string]any{"Users": len(failingUsers)}),
+	)
+
+	if watchers, err := c.kvstore.GetWatchers(); err != nil {
+		c.client.Log.Warn("Failed to get watchers", "error", err)
+	} else {
+		lines = append(lines, "- "+c.localizer.LocalizeCount(userID, adminStatsWatchersMessage, len(watchers), nil))
+	}
+
+	if snoozes, err := c.kvstore.ListSnoozes(); err != nil {
+		c.client.Log.Warn("Failed to list snoozes", "error", err)
+	} else {
+		snoozed := 0
+		now := model.GetMillis()
+		for _, snooze := range snoozes {
+			if snooze.Snoozed(now) {
+				snoozed++
+			}
+		}
+		lines = append(lines, "- "+c.localizer.LocalizeCount(userID, adminStatsSnoozedMessage, snoozed, nil))
+	}
+
+	if retries, err := c.kvstore.ListRetries(); err != nil {
+		c.client.Log.Warn("Failed to list retries", "error", err)
+	} else {
+		lines = append(lines, "- "+c.localizer.LocalizeCount(userID, adminStatsRetriesMessage, len(retries), nil))
+	}
+
+	return c.ephemeral(strings.Join(lines, "\n"))
+}
+
+// failingUser sums up the failing targets of a user.
+type failingUser struct {
+	userID string
+	count  int
+	last   *kvstore.DeliveryStatus
+}
+
+func (c *Handler) executeAdminFailingCommand(args *model.CommandArgs) *model.CommandResponse {
+	userID := args.UserId
+
+	statuses, err := c.kvstore.ListDeliveryStatuses()
+	if err != nil {
+		c.client.Log.Error("Failed to list delivery statuses", "error", err)
+		return c.ephemeral(c.localizer.Localize(userID, adminErrorMessage, nil))
+	}
+
+	byUser := map[string]*failingUser{}
+	for _, status := range statuses {
+		if !status.Failing() {
+			continue
+		}
+
+		user, ok := byUser[status.UserID]
+		if !ok {
+			user = &failingUser{userID: status.UserID}
+			byUser[status.UserID] = user
+		}
+		user.count++
+		if user.last == nil || status.LastAttemptAt > user.last.LastAttemptAt {
+			user.last = status
+		}
+	}
+	if len(byUser) == 0 {
+		return c.ephemeral(c.localizer.Localize(userID, adminFailingNoneMessage, nil))
+	}
+
+	users := make([]*failingUser, 0, len(byUser))
+	for _, user := range byUser {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].last.LastAttemptAt > users[j].last.LastAttemptAt
+	})
+
+	lines := []string{c.localizer.Localize(userID, adminFailingTitleMessage, nil)}
+	for i, user := range users {
+		if i == maxListedFailingUsers {
+			lines = append(lines, c.localizer.LocalizeCount(userID, adminFailingMoreMessage, len(users)-i, nil))
+			break
+		}
+
+		username := user.userID
+		if u, err := c.client.User.Get(user.userID); err == nil {
+			username = u.Username
+		}
+		lines = append(lines, "- "+c.localizer.LocalizeCount(userID, adminFailingUserMessage, user.count, map[string]any{
+			"Username": username,
+			"Time":     c.formatUserTime(userID, user.last.LastAttemptAt),
+			"Error":    user.last.LastError,
+		}))
+	}
+
+	return c.ephemeral(strings.Join(lines, "\n"))
+}
+
+func (c *Handler) executeAdminDisableCommand(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	userID := args.UserId
+	if len(fields) < 1 || len(fields) > 2 {
+		return c.ephemeral(c.localizer.Localize(userID, adminDisableUsageMessage, nil))
+	}
+
+	username := strings.TrimPrefix(fields[0], "@")
+	user, err := c.client.User.GetByUsername(username)
+	if err != nil {
+		return c.ephemeral(c.localizer.Localize(userID, adminUserNotFoundMessage, map[string]any{"Username": username}))
+	}
+
+	disabled := 0
+	response := c.updateTargets(user.Id, func(targets []*prefstore.Target) ([]*prefstore.Target, *model.CommandResponse) {
+		disabled = 0
+		selected := targets
+		if len(fields) == 2 {
+			i, ok := findTarget(targets, fields[1])
+			if !ok {
+				return nil, c.ephemeral(c.localizer.Localize(userID, adminUserTargetNotFoundMessage, map[string]any{
+					"Username": user.Username,
+					"Target":   fields[1],
+				}))
+			}
+			selected = targets[i : i+1]
+		}
+
+		for _, target := range selected {
+			if target.Enabled {
+				target.Enabled = false
+				disabled++
+			}
+		}
+		return targets, nil
+	})
+	if response != nil {
+		return response
+	}
+
+	c.client.Log.Info("System admin disabled a user's targets", "adminId", userID, "userId", user.Id, "targets", disabled)
+	return c.ephemeral(c.localizer.LocalizeCount(userID, adminDisabledMessage, disabled, map[string]any{"Username": user.Username}))
+}
+
+func (c *Handler) executeAdminPauseCommand(args *model.CommandArgs, paused bool) *model.CommandResponse {
+	userID := args.UserId
+
+	config := c.client.Configuration.GetPluginConfig()
+	if config == nil {
+		config = map[string]any{}
+	}
+	for key := range config {
+		if strings.EqualFold(key, deliveryPausedSetting) {
+			delete(config, key)
+		}
+	}
+	config[deliveryPausedSetting] = paused
+
+	if err := c.client.Configuration.SavePluginConfig(config); err != nil {
+		c.client.Log.Error("Failed to save the plugin configuration", "error", err)
+		return c.ephemeral(c.localizer.Localize(userID, adminErrorMessage, nil))
+	}
+
+	c.client.Log.Info("System admin toggled the delivery of notifications", "adminId", userID, "paused", paused)
+	if paused {
+		return c.ephemeral(c.localizer.Localize(userID, adminPausedMessage, nil))
+	}
+	return c.ephemeral(c.localizer.Localize(userID, adminResumedMessage, nil))
+}
+
+func (c *Handler) executeAdminFlushCommand(args *model.CommandArgs) *model.CommandResponse {
+	userID := args.UserId
+
+	flushed, err := c.notifications.FlushRetries()
+	if err != nil {
+		c.client.Log.Error("Failed to flush retries", "error", err)
+		return c.ephemeral(c.localizer.Localize(userID, adminErrorMessage, nil))
+	}
+
+	c.client.Log.Info("System admin flushed the retries", "adminId", userID, "retries", flushed)
+	return c.ephemeral(c.localizer.LocalizeCount(userID, adminFlushedMessage, flushed, nil))
+}
+
+func (c *Handler) executeAdminReplayCommand(args *model.CommandArgs) *model.CommandResponse {
+	userID := args.UserId
+	if c.notifications.DeliveryPaused() {
+		return c.ephemeral(c.localizer.Localize(userID, adminReplayPausedMessage, nil))
+	}
+
+	sent, failed, err := c.notifications.ReplayRetries()
+	if err != nil {
+		c.client.Log.Error("Failed to replay retries", "error", err)
+		return c.ephemeral(c.localizer.Localize(userID, adminErrorMessage, nil))
+	}
+
+	c.client.Log.Info("System admin replayed the retries", "adminId", userID, "sent", sent, "failed", failed)
+	return c.ephemeral(c.localizer.LocalizeCount(userID, adminReplayedMessage, sent, map[string]any{"Failed": failed}))
+}
+
+func (c *Handler) executeAdminReencryptCommand(args *model.CommandArgs) *model.CommandResponse {
+	userID := args.UserId
+
+	count, err := c.kvstore.ReencryptCredentials()
+	if err != nil {
+		c.client.Log.Error("Failed to re-encrypt credentials", "error", err)
+		return c.ephemeral(c.localizer.Localize(userID, adminErrorMessage, nil))
+	}
+
+	c.client.Log.Info("System admin re-encrypted the stored credentials", "adminId", userID, "credentials", count)
+	return c.ephemeral(c.localizer.LocalizeCount(userID, adminReencryptedMessage, count, nil))
+}
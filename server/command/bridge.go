@@ -0,0 +1,195 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/bridge"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/postfilter"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const bridgeCommand = "bridge"
+
+var (
+	bridgeUsageMessage = &i18n.Message{
+		ID:    "command.bridge.usage",
+		Other: "Usage: /shoutrrr bridge add <url> [--from user1,user2] [--keywords word1,word2] [--root-only] | list | remove <id>",
+	}
+	bridgeAddedMessage = &i18n.Message{
+		ID:    "command.bridge.added",
+		Other: "Added bridge `{{.ID}}` forwarding the posts of this channel to {{.URL}}",
+	}
+	bridgeRemovedMessage = &i18n.Message{
+		ID:    "command.bridge.removed",
+		Other: "Removed bridge `{{.ID}}`",
+	}
+	bridgeNoneMessage = &i18n.Message{
+		ID:    "command.bridge.none",
+		Other: "This channel has no bridges",
+	}
+	bridgeListMessage = &i18n.Message{
+		ID:    "command.bridge.list",
+		Other: "Bridges of this channel:",
+	}
+	bridgeFromMessage = &i18n.Message{
+		ID:    "command.bridge.from",
+		Other: "from {{.Senders}}",
+	}
+	bridgeKeywordsMessage = &i18n.Message{
+		ID:    "command.bridge.keywords",
+		Other: "containing {{.Keywords}}",
+	}
+	bridgeRootOnlyMessage = &i18n.Message{
+		ID:    "command.bridge.root_only",
+		Other: "root posts only",
+	}
+)
+
+// errInvalidBridgeArgs is returned when the arguments of bridge add can't be parsed.
+var errInvalidBridgeArgs = errors.New("invalid bridge arguments")
+
+func getBridgeAutocompleteData() *model.AutocompleteData {
+	bridgeData := model.NewAutocompleteData(bridgeCommand, "[add|list|remove]", "Forward the posts of this channel to a Shoutrrr URL")
+
+	add := model.NewAutocompleteData(addCommand, "<url> [--from user1,user2] [--keywords word1,word2] [--root-only]", "Add a bridge to this channel")
+	add.AddTextArgument("Shoutrrr URL to forward the posts to", "<url>", "")
+	bridgeData.AddCommand(add)
+
+	bridgeData.AddCommand(model.NewAutocompleteData(listCommand, "", "List the bridges of this channel"))
+
+	remove := model.NewAutocompleteData(removeCommand, "<id>", "Remove a bridge from this channel")
+	remove.AddTextArgument("ID of the bridge, as listed", "<id>", "")
+	bridgeData.AddCommand(remove)
+
+	return bridgeData
+}
+
+// executeBridgeCommand manages the bridges of the channel the command is run in.
+func (c *Handler) executeBridgeCommand(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	usage := c.ephemeral(c.localizer.Localize(args.UserId, bridgeUsageMessage, nil))
+	if len(fields) == 0 {
+		return usage
+	}
+
+	switch fields[0] {
+	case addCommand:
+		serviceURL, filter, err := parseBridgeAddArgs(fields[1:])
+		if err != nil {
+			return usage
+		}
+
+		created, err := c.bridges.Add(args.UserId, args.ChannelId, serviceURL, filter)
+		if err != nil {
+			return c.bridgeError(args.UserId, err)
+		}
+		return c.ephemeral(c.localizer.Localize(args.UserId, bridgeAddedMessage, map[string]any{
+			"ID":  created.ID,
+			"URL": bridge.Redact(created).URL,
+		}))
+
+	case listCommand:
+		bridges, err := c.bridges.List(args.UserId, args.ChannelId)
+		if err != nil {
+			return c.bridgeError(args.UserId, err)
+		}
+		return c.ephemeral(c.formatBridges(args.UserId, bridges))
+
+	case removeCommand:
+		if len(fields) != 2 {
+			return usage
+		}
+		if err := c.bridges.Remove(args.UserId, args.ChannelId, fields[1]); err != nil {
+			return c.bridgeError(args.UserId, err)
+		}
+		return c.ephemeral(c.localizer.Localize(args.UserId, bridgeRemovedMessage, map[string]any{"ID": fields[1]}))
+
+	default:
+		return usage
+	}
+}
+
+func (c *Handler) bridgeError(userID string, err error) *model.CommandResponse {
+	if !isBridgeError(err) {
+		c.client.Log.Error("Failed to manage bridges", "userId", userID, "error", err)
+	}
+	return c.ephemeral(bridge.LocalizeError(c.localizer, userID, err))
+}
+
+// isBridgeError returns whether err is one of the errors the bridge service returns for invalid
+// requests, as opposed to unexpected failures.
+func isBridgeError(err error) bool {
+	var invalidURL *bridge.InvalidURLError
+	return errors.Is(err, bridge.ErrForbidden) ||
+		errors.Is(err, bridge.ErrUnsupportedChannel) ||
+		errors.Is(err, bridge.ErrNotFound) ||
+		errors.As(err, &invalidURL)
+}
+
+// formatBridges lists bridges with their redacted URL and filters, one per line.
+func (c *Handler) formatBridges(userID string, bridges []*kvstore.Bridge) string {
+	if len(bridges) == 0 {
+		return c.localizer.Localize(userID, bridgeNoneMessage, nil)
+	}
+
+	lines := []string{c.localizer.Localize(userID, bridgeListMessage, nil)}
+	for _, channelBridge := range bridges {
+		var filters []string
+		if len(channelBridge.Senders) > 0 {
+			filters = append(filters, c.localizer.Localize(userID, bridgeFromMessage, map[string]any{
+				"Senders": "@" + strings.Join(channelBridge.Senders, ", @"),
+			}))
+		}
+		if len(channelBridge.Keywords) > 0 {
+			filters = append(filters, c.localizer.Localize(userID, bridgeKeywordsMessage, map[string]any{
+				"Keywords": strings.Join(channelBridge.Keywords, ", "),
+			}))
+		}
+		if channelBridge.RootOnly {
+			filters = append(filters, c.localizer.Localize(userID, bridgeRootOnlyMessage, nil))
+		}
+
+		line := "- `" + channelBridge.ID + "`: " + bridge.Redact(channelBridge).URL
+		if len(filters) > 0 {
+			line += " (" + strings.Join(filters, "; ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseBridgeAddArgs parses the URL and the filter options of bridge add.
+func parseBridgeAddArgs(fields []string) (string, postfilter.Filter, error) {
+	var serviceURL string
+	var filter postfilter.Filter
+
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "--from", "--keywords":
+			if i+1 == len(fields) {
+				return "", filter, errInvalidBridgeArgs
+			}
+			values := strings.Split(fields[i+1], ",")
+			if fields[i] == "--from" {
+				filter.Senders = append(filter.Senders, values...)
+			} else {
+				filter.Keywords = append(filter.Keywords, values...)
+			}
+			i++
+		case "--root-only":
+			filter.RootOnly = true
+		default:
+			if serviceURL != "" || strings.HasPrefix(fields[i], "--") {
+				return "", filter, errInvalidBridgeArgs
+			}
+			serviceURL = fields[i]
+		}
+	}
+
+	if serviceURL == "" {
+		return "", filter, errInvalidBridgeArgs
+	}
+	return serviceURL, filter.Normalize(), nil
+}
@@ -1,38 +1,67 @@
 package command
 
 import (
-	"fmt"
 	"strings"
 
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/bridge"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
 )
 
 type Handler struct {
-	client *pluginapi.Client
+	client        *pluginapi.Client
+	bridges       *bridge.Service
+	notifications *notification.Service
+	kvstore       kvstore.KVStore
+	preferences   prefstore.PreferenceStore
+	localizer     *i18n.Localizer
 }
 
 type Command interface {
 	Handle(args *model.CommandArgs) (*model.CommandResponse, error)
-	executeHelloCommand(args *model.CommandArgs) *model.CommandResponse
 }
 
-const helloCommandTrigger = "hello"
+const shoutrrrCommandTrigger = "shoutrrr"
+
+var (
+	unknownCommandMessage = &i18n.Message{
+		ID:    "command.unknown",
+		Other: "Unknown command: {{.Command}}",
+	}
+	shoutrrrUsageMessage = &i18n.Message{
+		ID:    "command.shoutrrr.usage",
+		Other: "Usage: /shoutrrr [add|list|remove|enable|disable|rename|test|status|snooze|mute|unmute|bridge]",
+	}
+	shoutrrrAdminUsageMessage = &i18n.Message{
+		ID:    "command.shoutrrr.admin_usage",
+		Other: "Usage: /shoutrrr [add|list|remove|enable|disable|rename|test|status|snooze|mute|unmute|bridge|admin]",
+	}
+)
 
 // Register all your slash commands in the NewCommandHandler function.
-func NewCommandHandler(client *pluginapi.Client) Command {
+func NewCommandHandler(client *pluginapi.Client, bridges *bridge.Service, notifications *notification.Service, kvstore kvstore.KVStore, preferences prefstore.PreferenceStore, localizer *i18n.Localizer) Command {
 	err := client.SlashCommand.Register(&model.Command{
-		Trigger:          helloCommandTrigger,
+		Trigger:          shoutrrrCommandTrigger,
 		AutoComplete:     true,
-		AutoCompleteDesc: "Say hello to someone",
-		AutoCompleteHint: "[@username]",
-		AutocompleteData: model.NewAutocompleteData(helloCommandTrigger, "[@username]", "Username to say hello to"),
+		AutoCompleteDesc: "Manage Shoutrrr notifications",
+		AutoCompleteHint: "[command]",
+		AutocompleteData: getShoutrrrAutocompleteData(),
 	})
 	if err != nil {
 		client.Log.Error("Failed to register command", "error", err)
 	}
+
 	return &Handler{
-		client: client,
+		client:        client,
+		bridges:       bridges,
+		notifications: notifications,
+		kvstore:       kvstore,
+		preferences:   preferences,
+		localizer:     localizer,
 	}
 }
 
@@ -40,25 +69,78 @@ func NewCommandHandler(client *pluginapi.Client) Command {
 func (c *Handler) Handle(args *model.CommandArgs) (*model.CommandResponse, error) {
 	trigger := strings.TrimPrefix(strings.Fields(args.Command)[0], "/")
 	switch trigger {
-	case helloCommandTrigger:
-		return c.executeHelloCommand(args), nil
+	case shoutrrrCommandTrigger:
+		return c.executeShoutrrrCommand(args), nil
 	default:
 		return &model.CommandResponse{
 			ResponseType: model.CommandResponseTypeEphemeral,
-			Text:         fmt.Sprintf("Unknown command: %s", args.Command),
+			Text:         c.localizer.Localize(args.UserId, unknownCommandMessage, map[string]any{"Command": args.Command}),
 		}, nil
 	}
 }
 
-func (c *Handler) executeHelloCommand(args *model.CommandArgs) *model.CommandResponse {
-	if len(strings.Fields(args.Command)) < 2 {
-		return &model.CommandResponse{
-			ResponseType: model.CommandResponseTypeEphemeral,
-			Text:         "Please specify a username",
-		}
+func getShoutrrrAutocompleteData() *model.AutocompleteData {
+	shoutrrr := model.NewAutocompleteData(shoutrrrCommandTrigger, "[command]", "Manage Shoutrrr notifications")
+	for _, subcommand := range getTargetAutocompleteData() {
+		shoutrrr.AddCommand(subcommand)
+	}
+	for _, subcommand := range getStatusAutocompleteData() {
+		shoutrrr.AddCommand(subcommand)
 	}
-	username := strings.Fields(args.Command)[1]
+	for _, subcommand := range getSnoozeAutocompleteData() {
+		shoutrrr.AddCommand(subcommand)
+	}
+	shoutrrr.AddCommand(getBridgeAutocompleteData())
+	shoutrrr.AddCommand(getAdminAutocompleteData())
+	return shoutrrr
+}
+
+func (c *Handler) executeShoutrrrCommand(args *model.CommandArgs) *model.CommandResponse {
+	fields := strings.Fields(args.Command)
+	if len(fields) < 2 {
+		return c.ephemeral(c.usage(args.UserId))
+	}
+
+	switch fields[1] {
+	case addCommand:
+		return c.executeAddCommand(args, fields[2:])
+	case listCommand:
+		return c.executeListCommand(args)
+	case removeCommand, enableCommand, disableCommand:
+		return c.executeTargetCommand(args, fields[1], fields[2:])
+	case renameCommand:
+		return c.executeRenameCommand(args, fields[2:])
+	case testCommand:
+		return c.executeTestCommand(args, fields[2:])
+	case statusCommand:
+		return c.executeStatusCommand(args)
+	case snoozeCommand:
+		return c.executeSnoozeCommand(args, fields[2:])
+	case muteCommand:
+		return c.executeMuteCommand(args, fields[2:])
+	case unmuteCommand:
+		return c.executeUnmuteCommand(args, fields[2:])
+	case bridgeCommand:
+		return c.executeBridgeCommand(args, fields[2:])
+	case adminCommand:
+		return c.executeAdminCommand(args, fields[2:])
+	default:
+		return c.ephemeral(c.usage(args.UserId))
+	}
+}
+
+// usage returns the list of subcommands, which includes admin for system admins only.
+func (c *Handler) usage(userID string) string {
+	if c.client.User.HasPermissionTo(userID, model.PermissionManageSystem) {
+		return c.localizer.Localize(userID, shoutrrrAdminUsageMessage, nil)
+	}
+	return c.localizer.Localize(userID, shoutrrrUsageMessage, nil)
+}
+
+// ephemeral returns a response only the user who ran the command sees.
+func (c *Handler) ephemeral(text string) *model.CommandResponse {
 	return &model.CommandResponse{
-		Text: "Hello, " + username,
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
 	}
 }
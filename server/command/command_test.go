@@ -1,12 +1,20 @@
 package command
 
 import (
+	"net/http"
 	"testing"
+	"time"
 
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/bridge"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/postfilter"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 type env struct {
@@ -25,23 +33,505 @@ func setupTest() *env {
 	}
 }
 
-func TestHelloCommand(t *testing.T) {
-	assert := assert.New(t)
+// memoryPreferences is a preference store keeping the preferences of a single user in memory.
+type memoryPreferences map[string]string
+
+func (m memoryPreferences) GetPreference(_, name string) (string, error) {
+	return m[name], nil
+}
+
+func (m memoryPreferences) SetPreferences(_ string, values map[string]string) error {
+	for name, value := range values {
+		m[name] = value
+	}
+	return nil
+}
+
+func TestRegisterCommand(t *testing.T) {
 	env := setupTest()
 
 	env.api.On("RegisterCommand", &model.Command{
-		Trigger:          helloCommandTrigger,
+		Trigger:          shoutrrrCommandTrigger,
 		AutoComplete:     true,
-		AutoCompleteDesc: "Say hello to someone",
-		AutoCompleteHint: "[@username]",
-		AutocompleteData: model.NewAutocompleteData("hello", "[@username]", "Username to say hello to"),
+		AutoCompleteDesc: "Manage Shoutrrr notifications",
+		AutoCompleteHint: "[command]",
+		AutocompleteData: getShoutrrrAutocompleteData(),
 	}).Return(nil)
-	cmdHandler := NewCommandHandler(env.client)
+	NewCommandHandler(env.client, nil, nil, nil, nil, nil)
+
+	env.api.AssertExpectations(t)
+
+	// The admin commands are only suggested to system admins
+	var admin *model.AutocompleteData
+	for _, subcommand := range getShoutrrrAutocompleteData().SubCommands {
+		if subcommand.Trigger == adminCommand {
+			admin = subcommand
+		}
+	}
+	if assert.NotNil(t, admin) {
+		assert.Equal(t, model.SystemAdminRoleId, admin.RoleID)
+	}
+}
+
+func TestTargetCommands(t *testing.T) {
+	assert := assert.New(t)
+	env := setupTest()
+
+	env.api.On("RegisterCommand", mock.Anything).Return(nil)
+	preferences := memoryPreferences{}
+	phoneID := prefstore.TargetID("ntfy://ntfy.sh/secret-topic")
+	store := &memoryKVStore{deliveries: map[string]*kvstore.DeliveryStatus{
+		phoneID: {TargetID: phoneID, ConsecutiveFailures: 5},
+	}}
+	cmdHandler := NewCommandHandler(env.client, nil, nil, store, preferences, nil)
 
-	args := &model.CommandArgs{
-		Command: "/hello world",
+	run := func(command string) string {
+		response, err := cmdHandler.Handle(&model.CommandArgs{Command: command, UserId: "user"})
+		assert.Nil(err)
+		assert.Equal(model.CommandResponseTypeEphemeral, response.ResponseType)
+		return response.Text
 	}
-	response, err := cmdHandler.Handle(args)
+
+	assert.Equal("You have no targets yet. Add one with /shoutrrr add <url> [label].", run("/shoutrrr list"))
+
+	assert.Equal("Added **phone**", run("/shoutrrr add ntfy://ntfy.sh/secret-topic phone"))
+	assert.Equal("Added `discord://***@123456789`", run("/shoutrrr add discord://token@123456789"))
+	assert.Equal("ntfy://ntfy.sh/secret-topic,discord://token@123456789", preferences[prefstore.NotificationServices])
+
+	assert.Equal("This URL is already one of your targets", run("/shoutrrr add ntfy://ntfy.sh/secret-topic"))
+	assert.Equal("You already have a target labeled Phone", run("/shoutrrr add ntfy://ntfy.sh/other Phone"))
+	assert.Contains(run("/shoutrrr add unknown://example.com"), "Invalid Shoutrrr URL: ")
+	assert.Equal("Labels are single words of up to 32 letters, digits, dots, dashes and underscores", run("/shoutrrr add ntfy://ntfy.sh/other my*phone"))
+
+	assert.Equal("Renamed `discord://***@123456789` to **team**", run("/shoutrrr rename 2 team"))
+	assert.Equal("You already have a target labeled PHONE", run("/shoutrrr rename team PHONE"))
+	assert.Equal("Disabled **phone**, which won't get notifications until you enable it again", run("/shoutrrr disable PHONE"))
+	assert.Equal(prefstore.TargetID("ntfy://ntfy.sh/secret-topic"), preferences[prefstore.DisabledServices])
+
+	assert.Equal("Your targets:\n1. **phone** `ntfy://ntfy.sh/***` (disabled)\n2. **team** `discord://***@123456789`", run("/shoutrrr list"))
+
+	// Enabling a target again forgets its past failures
+	assert.Contains(store.deliveries, phoneID)
+	assert.Equal("Enabled **phone**", run("/shoutrrr enable phone"))
+	assert.Equal("", preferences[prefstore.DisabledServices])
+	assert.NotContains(store.deliveries, phoneID)
+
+	assert.Equal("None of your targets matches 3. Use /shoutrrr list to see them.", run("/shoutrrr remove 3"))
+	assert.Equal("Usage: /shoutrrr remove <label or number>", run("/shoutrrr remove"))
+	assert.Equal("Removed **phone**", run("/shoutrrr remove phone"))
+	assert.Equal("discord://token@123456789", preferences[prefstore.NotificationServices])
+	assert.Equal(`{"`+prefstore.TargetID("discord://token@123456789")+`":"team"}`, preferences[prefstore.ServiceLabels])
+}
+
+func TestParseBridgeAddArgs(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		fields []string
+		url    string
+		filter postfilter.Filter
+		err    bool
+	}{
+		{
+			name:   "url only",
+			fields: []string{"ntfy://ntfy.sh/topic"},
+			url:    "ntfy://ntfy.sh/topic",
+		},
+		{
+			name:   "all options",
+			fields: []string{"--from", "@Alice,bob", "ntfy://ntfy.sh/topic", "--keywords", "Deploy,", "--root-only"},
+			url:    "ntfy://ntfy.sh/topic",
+			filter: postfilter.Filter{
+				Senders:  []string{"alice", "bob"},
+				Keywords: []string{"deploy"},
+				RootOnly: true,
+			},
+		},
+		{
+			name:   "missing url",
+			fields: []string{"--root-only"},
+			err:    true,
+		},
+		{
+			name:   "missing option value",
+			fields: []string{"ntfy://ntfy.sh/topic", "--from"},
+			err:    true,
+		},
+		{
+			name:   "unknown option",
+			fields: []string{"ntfy://ntfy.sh/topic", "--all"},
+			err:    true,
+		},
+		{
+			name:   "two urls",
+			fields: []string{"ntfy://ntfy.sh/a", "ntfy://ntfy.sh/b"},
+			err:    true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			url, filter, err := parseBridgeAddArgs(tc.fields)
+			if tc.err {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.url, url)
+			assert.Equal(tc.filter, filter)
+		})
+	}
+}
+
+func TestBridgeCommandForbidden(t *testing.T) {
+	assert := assert.New(t)
+	env := setupTest()
+
+	env.api.On("RegisterCommand", mock.Anything).Return(nil)
+	env.api.On("GetChannel", "channel").Return(&model.Channel{Id: "channel", Type: model.ChannelTypeOpen}, nil)
+	env.api.On("HasPermissionToChannel", "user", "channel", model.PermissionManagePublicChannelProperties).Return(false)
+	cmdHandler := NewCommandHandler(env.client, bridge.NewService(env.client, nil), nil, nil, nil, nil)
+
+	response, err := cmdHandler.Handle(&model.CommandArgs{
+		Command:   "/shoutrrr bridge add ntfy://ntfy.sh/topic",
+		UserId:    "user",
+		ChannelId: "channel",
+	})
 	assert.Nil(err)
-	assert.Equal("Hello, world", response.Text)
+	assert.Equal(model.CommandResponseTypeEphemeral, response.ResponseType)
+	assert.Equal("Only channel admins can manage the bridges of this channel", response.Text)
+}
+
+// memoryKVStore keeps the KV data the commands read and write for a single user in memory,
+// failing on anything else.
+type memoryKVStore struct {
+	kvstore.KVStore
+	deliveries    map[string]*kvstore.DeliveryStatus
+	subscriptions []*kvstore.Subscription
+	snooze        *kvstore.Snooze
+	retries       map[string]*kvstore.Retry
+}
+
+func (s *memoryKVStore) SaveRetry(retry *kvstore.Retry) error {
+	s.retries[retry.ID] = retry
+	return nil
+}
+
+func (s *memoryKVStore) ListRetries() ([]*kvstore.Retry, error) {
+	var retries []*kvstore.Retry
+	for _, retry := range s.retries {
+		retries = append(retries, retry)
+	}
+	return retries, nil
+}
+
+func (s *memoryKVStore) TakeRetry(id string) (*kvstore.Retry, error) {
+	retry := s.retries[id]
+	delete(s.retries, id)
+	return retry, nil
+}
+
+func (s *memoryKVStore) ReencryptCredentials() (int, error) {
+	return len(s.retries), nil
+}
+
+func (s *memoryKVStore) GetDeliveryStatus(_, targetID string) (*kvstore.DeliveryStatus, error) {
+	return s.deliveries[targetID], nil
+}
+
+func (s *memoryKVStore) ResetDeliveryStatus(_, targetID string) error {
+	delete(s.deliveries, targetID)
+	return nil
+}
+
+func (s *memoryKVStore) GetSubscriptions(string) ([]*kvstore.Subscription, error) {
+	return s.subscriptions, nil
+}
+
+func (s *memoryKVStore) GetWatchRules(string) ([]*kvstore.WatchRule, error) {
+	return nil, nil
+}
+
+func (s *memoryKVStore) ListDeliveryStatuses() ([]*kvstore.DeliveryStatus, error) {
+	var statuses []*kvstore.DeliveryStatus
+	for _, status := range s.deliveries {
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (s *memoryKVStore) GetWatchers() ([]string, error) {
+	return []string{"watcher"}, nil
+}
+
+func (s *memoryKVStore) ListSnoozes() ([]*kvstore.Snooze, error) {
+	if s.snooze == nil {
+		return nil, nil
+	}
+	return []*kvstore.Snooze{s.snooze}, nil
+}
+
+func (s *memoryKVStore) GetSnooze(string) (*kvstore.Snooze, error) {
+	return s.snooze, nil
+}
+
+func (s *memoryKVStore) UpdateSnooze(userID string, update func(snooze *kvstore.Snooze)) (*kvstore.Snooze, error) {
+	snooze := &kvstore.Snooze{UserID: userID, MutedChannels: map[string]int64{}}
+	if s.snooze != nil {
+		snooze.Until = s.snooze.Until
+		for channelID, until := range s.snooze.MutedChannels {
+			snooze.MutedChannels[channelID] = until
+		}
+	}
+
+	update(snooze)
+	if snooze.IsEmpty() {
+		s.snooze = nil
+	} else {
+		s.snooze = snooze
+	}
+	return snooze, nil
+}
+
+func TestStatusCommand(t *testing.T) {
+	assert := assert.New(t)
+	env := setupTest()
+
+	env.api.On("RegisterCommand", mock.Anything).Return(nil)
+	preferences := memoryPreferences{
+		prefstore.NotificationServices: "ntfy://ntfy.sh/secret-topic,discord://token@123456789,slack://hooks.slack.com",
+		prefstore.ServiceLabels:        `{"` + prefstore.TargetID("ntfy://ntfy.sh/secret-topic") + `":"phone"}`,
+		prefstore.DisabledServices:     prefstore.TargetID("slack://hooks.slack.com"),
+		prefstore.DigestInterval:       "15",
+		prefstore.EditNotifications:    "false",
+	}
+	store := &memoryKVStore{
+		deliveries: map[string]*kvstore.DeliveryStatus{
+			prefstore.TargetID("ntfy://ntfy.sh/secret-topic"): {
+				LastAttemptAt: 1700000000000,
+				LastSuccessAt: 1700000000000,
+			},
+			prefstore.TargetID("discord://token@123456789"): {
+				LastAttemptAt:       1700003600000,
+				LastSuccessAt:       1700000000000,
+				LastError:           "got response status 401",
+				ConsecutiveFailures: 2,
+			},
+		},
+		subscriptions: []*kvstore.Subscription{{ID: "subscription"}},
+		snooze:        &kvstore.Snooze{MutedChannels: map[string]int64{"channel": 0, "expired": 1}},
+	}
+	env.api.On("GetUser", "user").Return(&model.User{Id: "user", Timezone: model.StringMap{"useAutomaticTimezone": "false", "manualTimezone": "Europe/Berlin"}}, nil)
+	paused := false
+	notifications := notification.NewService(env.client, nil, nil, nil, func() *notification.Config {
+		return &notification.Config{DeliveryPaused: paused}
+	}, nil)
+	cmdHandler := NewCommandHandler(env.client, nil, notifications, store, preferences, nil)
+
+	response, err := cmdHandler.Handle(&model.CommandArgs{Command: "/shoutrrr status", UserId: "user"})
+	assert.Nil(err)
+	assert.Equal(`#### Shoutrrr notifications
+You're notified on 2 of your 3 targets.
+You muted 1 channel.
+
+Targets:
+1. **phone** `+"`ntfy://ntfy.sh/***`"+`: healthy, last delivered Tue Nov 14 23:13 CET
+2. `+"`discord://***@123456789`"+`: degraded, last failed Wed Nov 15 00:13 CET: got response status 401
+3. `+"`slack://hooks.slack.com`"+` (disabled): never used
+
+Triggers:
+- 1 channel subscription
+- Mentions are batched into a digest every 15 minutes`, response.Text)
+
+	// The admin's pause stops every notification
+	paused = true
+	response, err = cmdHandler.Handle(&model.CommandArgs{Command: "/shoutrrr status", UserId: "user"})
+	assert.Nil(err)
+	assert.Contains(response.Text, "You're not notified: a system admin paused the delivery of all notifications.")
+	assert.NotContains(response.Text, "You're notified on")
+
+	response, err = cmdHandler.Handle(&model.CommandArgs{Command: "/shoutrrr test phone extra", UserId: "user"})
+	assert.Nil(err)
+	assert.Equal("Usage: /shoutrrr test [label or number]", response.Text)
+
+	response, err = cmdHandler.Handle(&model.CommandArgs{Command: "/shoutrrr test laptop", UserId: "user"})
+	assert.Nil(err)
+	assert.Equal("None of your targets matches laptop. Use /shoutrrr list to see them.", response.Text)
+}
+
+func TestParseDuration(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		duration time.Duration
+		ok       bool
+	}{
+		{value: "30m", duration: 30 * time.Minute, ok: true},
+		{value: "1h30m", duration: 90 * time.Minute, ok: true},
+		{value: "7d", duration: 7 * 24 * time.Hour, ok: true},
+		{value: "30s", duration: 30 * time.Second},
+		{value: "31d", duration: 31 * 24 * time.Hour},
+		{value: "-2h", duration: -2 * time.Hour},
+		{value: "2 hours"},
+		{value: "d"},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			duration, ok := parseDuration(tc.value)
+			assert.Equal(t, tc.duration, duration)
+			assert.Equal(t, tc.ok, ok)
+		})
+	}
+}
+
+func TestNextTimeOfDay(t *testing.T) {
+	assert := assert.New(t)
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.Nil(err)
+	now := time.Date(2024, time.March, 30, 22, 0, 0, 0, time.UTC) // Midnight in Berlin, before the switch to summer time
+
+	next, ok := nextTimeOfDay("9:00", now, berlin)
+	assert.True(ok)
+	assert.Equal(time.Date(2024, time.March, 31, 7, 0, 0, 0, time.UTC), next.UTC())
+
+	next, ok = nextTimeOfDay("23:30", now, time.UTC)
+	assert.True(ok)
+	assert.Equal(time.Date(2024, time.March, 30, 23, 30, 0, 0, time.UTC), next)
+
+	next, ok = nextTimeOfDay("22:00", now, time.UTC)
+	assert.True(ok)
+	assert.Equal(time.Date(2024, time.March, 31, 22, 0, 0, 0, time.UTC), next)
+
+	_, ok = nextTimeOfDay("25:00", now, time.UTC)
+	assert.False(ok)
+}
+
+func TestSnoozeCommands(t *testing.T) {
+	assert := assert.New(t)
+	env := setupTest()
+
+	env.api.On("RegisterCommand", mock.Anything).Return(nil)
+	env.api.On("GetUser", "user").Return(&model.User{Id: "user"}, nil)
+	env.api.On("GetChannelByName", "team", "town-square", false).Return(&model.Channel{Id: "channel", Name: "town-square"}, nil)
+	env.api.On("GetChannelByName", "team", "secret", false).Return(&model.Channel{Id: "secret", Name: "secret"}, nil)
+	env.api.On("GetChannelByName", "team", "unknown", false).Return(nil, model.NewAppError("GetChannelByName", "not_found", nil, "", http.StatusNotFound))
+	env.api.On("GetChannelMember", "channel", "user").Return(&model.ChannelMember{}, nil)
+	env.api.On("GetChannelMember", "secret", "user").Return(nil, model.NewAppError("GetChannelMember", "not_found", nil, "", http.StatusNotFound))
+	store := &memoryKVStore{}
+	cmdHandler := NewCommandHandler(env.client, nil, nil, store, memoryPreferences{}, nil)
+
+	run := func(command string) string {
+		response, err := cmdHandler.Handle(&model.CommandArgs{Command: command, UserId: "user", TeamId: "team"})
+		assert.Nil(err)
+		return response.Text
+	}
+
+	assert.Equal("Durations are between 1 minute and 30 days, e.g. 30m, 2h or 7d", run("/shoutrrr snooze 10s"))
+	assert.Equal("Times are in your timezone as HH:MM, e.g. 09:00", run("/shoutrrr snooze until noon"))
+	assert.Equal("Usage: /shoutrrr snooze <duration, e.g. 30m or 2h> or /shoutrrr snooze until <HH:MM>", run("/shoutrrr snooze"))
+	assert.Nil(store.snooze)
+
+	before := time.Now()
+	assert.Contains(run("/shoutrrr snooze 2h"), "Notifications snoozed until ")
+	assert.InDelta(before.Add(2*time.Hour).UnixMilli(), store.snooze.Until, float64(time.Minute.Milliseconds()))
+
+	assert.Equal("Muted ~town-square. Use /shoutrrr unmute ~town-square to unmute it.", run("/shoutrrr mute ~town-square"))
+	assert.Equal(map[string]int64{"channel": 0}, store.snooze.MutedChannels)
+	assert.Equal("You aren't a member of a channel named ~secret in this team", run("/shoutrrr mute ~secret"))
+	assert.Equal("You aren't a member of a channel named ~unknown in this team", run("/shoutrrr mute unknown 2h"))
+
+	assert.Equal("Unmuted ~town-square", run("/shoutrrr unmute ~town-square"))
+	assert.Equal("~town-square isn't muted", run("/shoutrrr unmute town-square"))
+	assert.NotNil(store.snooze)
+
+	assert.Contains(run("/shoutrrr mute ~town-square 1h"), "Muted ~town-square until ")
+	assert.Equal("Notifications resumed in every channel", run("/shoutrrr unmute"))
+	assert.Nil(store.snooze)
+}
+
+func TestAdminCommands(t *testing.T) {
+	assert := assert.New(t)
+	env := setupTest()
+
+	env.api.On("RegisterCommand", mock.Anything).Return(nil)
+	env.api.On("HasPermissionTo", "admin", model.PermissionManageSystem).Return(true)
+	env.api.On("HasPermissionTo", "user", model.PermissionManageSystem).Return(false)
+	env.api.On("GetUser", "admin").Return(&model.User{Id: "admin"}, nil)
+	env.api.On("GetUser", "alice").Return(&model.User{Id: "alice", Username: "alice"}, nil)
+	env.api.On("GetUserByUsername", "alice").Return(&model.User{Id: "alice", Username: "alice"}, nil)
+	env.api.On("GetUserByUsername", "nobody").Return(nil, model.NewAppError("GetUserByUsername", "not_found", nil, "", http.StatusNotFound))
+	env.api.On("GetPluginConfig").Return(map[string]any{"DeliveryPaused": false, "userratelimit": 10})
+	env.api.On("SavePluginConfig", map[string]any{"deliverypaused": true, "userratelimit": 10}).Return(nil)
+	env.api.On("LogInfo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	env.api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Return()
+
+	paused := false
+	preferences := memoryPreferences{
+		prefstore.NotificationServices: "ntfy://ntfy.sh/secret-topic,discord://token@123456789",
+		prefstore.ServiceLabels:        `{"` + prefstore.TargetID("ntfy://ntfy.sh/secret-topic") + `":"phone"}`,
+	}
+	store := &memoryKVStore{
+		retries: map[string]*kvstore.Retry{
+			"retry": {ID: "retry", UserID: "alice", TargetID: prefstore.TargetID("ntfy://ntfy.sh/secret-topic"), ServiceURL: "ntfy://ntfy.sh/secret-topic"},
+		},
+		deliveries: map[string]*kvstore.DeliveryStatus{
+			"phone": {UserID: "alice", LastAttemptAt: 1700000000000, LastSuccessAt: 1700000000000},
+			"team": {
+				UserID:              "alice",
+				LastAttemptAt:       1700003600000,
+				LastError:           "got response status 401",
+				ConsecutiveFailures: 3,
+			},
+		},
+	}
+	notifications := notification.NewService(env.client, preferences, store, nil, func() *notification.Config {
+		return &notification.Config{DeliveryPaused: paused}
+	}, nil)
+	cmdHandler := NewCommandHandler(env.client, nil, notifications, store, preferences, nil)
+
+	run := func(userID, command string) string {
+		response, err := cmdHandler.Handle(&model.CommandArgs{Command: command, UserId: userID})
+		assert.Nil(err)
+		return response.Text
+	}
+
+	assert.Equal("Only system admins can use /shoutrrr admin", run("user", "/shoutrrr admin stats"))
+	assert.Equal("Only system admins can use /shoutrrr admin", run("user", "/shoutrrr admin pause"))
+	assert.Equal("Usage: /shoutrrr admin [stats|failing|disable|pause|resume|flush|replay|reencrypt]", run("admin", "/shoutrrr admin"))
+
+	// Only system admins are told about the admin commands
+	assert.Equal("Usage: /shoutrrr [add|list|remove|enable|disable|rename|test|status|snooze|mute|unmute|bridge|admin]", run("admin", "/shoutrrr"))
+	assert.Equal("Usage: /shoutrrr [add|list|remove|enable|disable|rename|test|status|snooze|mute|unmute|bridge]", run("user", "/shoutrrr"))
+	assert.Equal("Usage: /shoutrrr [add|list|remove|enable|disable|rename|test|status|snooze|mute|unmute|bridge]", run("user", "/shoutrrr help"))
+
+	assert.Equal(`#### Shoutrrr statistics
+Notifications are being delivered.
+- 2 targets notified, of 1 users
+- 1 target failing, of 1 users
+- 1 user with watch rules
+- 0 users snoozed
+- 1 notification waiting for a retry`, run("admin", "/shoutrrr admin stats"))
+
+	assert.Equal("Users with failing targets:\n- @alice: 1 failing target, last failed Tue Nov 14 23:13 UTC: got response status 401", run("admin", "/shoutrrr admin failing"))
+
+	assert.Equal("No user is named @nobody", run("admin", "/shoutrrr admin disable @nobody"))
+	assert.Equal("None of the targets of @alice matches laptop", run("admin", "/shoutrrr admin disable @alice laptop"))
+	assert.Equal("Disabled 1 target of @alice", run("admin", "/shoutrrr admin disable @alice phone"))
+	assert.Equal(prefstore.TargetID("ntfy://ntfy.sh/secret-topic"), preferences[prefstore.DisabledServices])
+	assert.Equal("Disabled 1 target of @alice", run("admin", "/shoutrrr admin disable alice"))
+	assert.Equal("Disabled 0 targets of @alice", run("admin", "/shoutrrr admin disable alice"))
+
+	// The retries of disabled targets are dropped rather than sent
+	assert.Equal("Sent 0 notifications waiting for a retry, 1 could not be sent", run("admin", "/shoutrrr admin replay"))
+	assert.Empty(store.retries)
+
+	store.retries["retry"] = &kvstore.Retry{ID: "retry", UserID: "alice"}
+	assert.Equal("Re-encrypted 1 stored credential with a new key", run("admin", "/shoutrrr admin reencrypt"))
+	assert.Equal("Dropped 1 notification waiting for a retry", run("admin", "/shoutrrr admin flush"))
+	assert.Empty(store.retries)
+
+	assert.Equal("Delivery paused: no notification is sent until /shoutrrr admin resume", run("admin", "/shoutrrr admin pause"))
+	env.api.AssertCalled(t, "SavePluginConfig", map[string]any{"deliverypaused": true, "userratelimit": 10})
+
+	paused = true
+	assert.Contains(run("admin", "/shoutrrr admin stats"), "Delivery is paused: no notification is sent until /shoutrrr admin resume.")
+	assert.Equal("Delivery is paused: resume it with /shoutrrr admin resume before replaying the retries", run("admin", "/shoutrrr admin replay"))
 }
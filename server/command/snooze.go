@@ -0,0 +1,270 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	snoozeCommand = "snooze"
+	muteCommand   = "mute"
+	unmuteCommand = "unmute"
+)
+
+const (
+	// minSnoozeDuration and maxSnoozeDuration bound how long notifications can be snoozed or a
+	// channel muted for.
+	minSnoozeDuration = time.Minute
+	maxSnoozeDuration = 30 * 24 * time.Hour
+
+	// userTimeFormat is how times are shown, in the user's timezone.
+	userTimeFormat = "Mon Jan 2 15:04 MST"
+)
+
+var (
+	snoozeUsageMessage = &i18n.Message{
+		ID:    "command.snooze.usage",
+		Other: "Usage: /shoutrrr snooze <duration, e.g. 30m or 2h> or /shoutrrr snooze until <HH:MM>",
+	}
+	muteUsageMessage = &i18n.Message{
+		ID:    "command.mute.usage",
+		Other: "Usage: /shoutrrr mute ~channel [duration, e.g. 2h or 7d]",
+	}
+	unmuteUsageMessage = &i18n.Message{
+		ID:    "command.unmute.usage",
+		Other: "Usage: /shoutrrr unmute [~channel]",
+	}
+	snoozeInvalidDurationMessage = &i18n.Message{
+		ID:    "command.snooze.invalid_duration",
+		Other: "Durations are between 1 minute and 30 days, e.g. 30m, 2h or 7d",
+	}
+	snoozeInvalidTimeMessage = &i18n.Message{
+		ID:    "command.snooze.invalid_time",
+		Other: "Times are in your timezone as HH:MM, e.g. 09:00",
+	}
+	snoozedMessage = &i18n.Message{
+		ID:    "command.snooze.snoozed",
+		Other: "Notifications snoozed until {{.Time}}. Use /shoutrrr unmute to resume them sooner.",
+	}
+	mutedMessage = &i18n.Message{
+		ID:    "command.mute.muted",
+		Other: "Muted ~{{.Channel}}. Use /shoutrrr unmute ~{{.Channel}} to unmute it.",
+	}
+	mutedUntilMessage = &i18n.Message{
+		ID:    "command.mute.muted_until",
+		Other: "Muted ~{{.Channel}} until {{.Time}}",
+	}
+	channelNotFoundMessage = &i18n.Message{
+		ID:    "command.mute.channel_not_found",
+		Other: "You aren't a member of a channel named ~{{.Channel}} in this team",
+	}
+	resumedMessage = &i18n.Message{
+		ID:    "command.unmute.resumed",
+		Other: "Notifications resumed in every channel",
+	}
+	unmutedMessage = &i18n.Message{
+		ID:    "command.unmute.unmuted",
+		Other: "Unmuted ~{{.Channel}}",
+	}
+	notMutedMessage = &i18n.Message{
+		ID:    "command.unmute.not_muted",
+		Other: "~{{.Channel}} isn't muted",
+	}
+	snoozeErrorMessage = &i18n.Message{
+		ID:    "command.snooze.error",
+		Other: "Your snooze couldn't be saved, please try again",
+	}
+)
+
+func getSnoozeAutocompleteData() []*model.AutocompleteData {
+	snooze := model.NewAutocompleteData(snoozeCommand, "<duration> | until <HH:MM>", "Pause all your notifications for a while")
+	snooze.AddTextArgument("How long to snooze for, e.g. 30m or 2h, or until a time of day", "<duration> | until <HH:MM>", "")
+
+	mute := model.NewAutocompleteData(muteCommand, "~channel [duration]", "Stop the notifications from a channel")
+	mute.AddTextArgument("Channel to mute and how long for, until unmuted if empty", "~channel [duration]", "")
+
+	unmute := model.NewAutocompleteData(unmuteCommand, "[~channel]", "Unmute a channel, or end your snooze and unmute every channel")
+	unmute.AddTextArgument("Channel to unmute, every channel if empty", "[~channel]", "")
+
+	return []*model.AutocompleteData{snooze, mute, unmute}
+}
+
+// parseDuration parses a duration for a snooze or a mute, which can also be a number of days.
+func parseDuration(value string) (time.Duration, bool) {
+	var duration time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, false
+		}
+		duration = time.Duration(count) * 24 * time.Hour
+	} else {
+		var err error
+		if duration, err = time.ParseDuration(value); err != nil {
+			return 0, false
+		}
+	}
+	return duration, duration >= minSnoozeDuration && duration <= maxSnoozeDuration
+}
+
+// nextTimeOfDay returns the next time the clock shows the time of day, as HH:MM, in a location.
+func nextTimeOfDay(value string, now time.Time, location *time.Location) (time.Time, bool) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	now = now.In(location)
+	next := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, location)
+	if !next.After(now) {
+		next = time.Date(now.Year(), now.Month(), now.Day()+1, clock.Hour(), clock.Minute(), 0, 0, location)
+	}
+	return next, true
+}
+
+// getUserLocation returns the user's timezone, UTC if it's unknown.
+func (c *Handler) getUserLocation(userID string) *time.Location {
+	user, err := c.client.User.Get(userID)
+	if err != nil {
+		c.client.Log.Warn("Failed to get user timezone", "userId", userID, "error", err)
+		return time.UTC
+	}
+
+	location, err := time.LoadLocation(user.GetPreferredTimezone())
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// formatUserTime formats a time, in milliseconds since the epoch, in the user's timezone.
+func (c *Handler) formatUserTime(userID string, millis int64) string {
+	return time.UnixMilli(millis).In(c.getUserLocation(userID)).Format(userTimeFormat)
+}
+
+// findChannel returns the channel of the team the reference, with or without its tilde, names,
+// if the user is a member of it.
+func (c *Handler) findChannel(userID, teamID, ref string) (*model.Channel, bool) {
+	channel, err := c.client.Channel.GetByName(teamID, strings.TrimPrefix(ref, "~"), false)
+	if err != nil {
+		return nil, false
+	}
+	if _, err := c.client.Channel.GetMember(channel.Id, userID); err != nil {
+		return nil, false
+	}
+	return channel, true
+}
+
+func (c *Handler) executeSnoozeCommand(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	userID := args.UserId
+	now := time.Now()
+
+	var until time.Time
+	switch {
+	case len(fields) == 1:
+		duration, ok := parseDuration(fields[0])
+		if !ok {
+			return c.ephemeral(c.localizer.Localize(userID, snoozeInvalidDurationMessage, nil))
+		}
+		until = now.Add(duration)
+	case len(fields) == 2 && fields[0] == "until":
+		var ok bool
+		if until, ok = nextTimeOfDay(fields[1], now, c.getUserLocation(userID)); !ok {
+			return c.ephemeral(c.localizer.Localize(userID, snoozeInvalidTimeMessage, nil))
+		}
+	default:
+		return c.ephemeral(c.localizer.Localize(userID, snoozeUsageMessage, nil))
+	}
+
+	_, err := c.kvstore.UpdateSnooze(userID, func(snooze *kvstore.Snooze) {
+		snooze.Until = until.UnixMilli()
+	})
+	if err != nil {
+		c.client.Log.Error("Failed to snooze notifications", "userId", userID, "error", err)
+		return c.ephemeral(c.localizer.Localize(userID, snoozeErrorMessage, nil))
+	}
+
+	return c.ephemeral(c.localizer.Localize(userID, snoozedMessage, map[string]any{"Time": c.formatUserTime(userID, until.UnixMilli())}))
+}
+
+func (c *Handler) executeMuteCommand(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	userID := args.UserId
+	if len(fields) < 1 || len(fields) > 2 {
+		return c.ephemeral(c.localizer.Localize(userID, muteUsageMessage, nil))
+	}
+
+	var until int64
+	if len(fields) == 2 {
+		duration, ok := parseDuration(fields[1])
+		if !ok {
+			return c.ephemeral(c.localizer.Localize(userID, snoozeInvalidDurationMessage, nil))
+		}
+		until = time.Now().Add(duration).UnixMilli()
+	}
+
+	channel, ok := c.findChannel(userID, args.TeamId, fields[0])
+	if !ok {
+		return c.ephemeral(c.localizer.Localize(userID, channelNotFoundMessage, map[string]any{"Channel": strings.TrimPrefix(fields[0], "~")}))
+	}
+
+	_, err := c.kvstore.UpdateSnooze(userID, func(snooze *kvstore.Snooze) {
+		snooze.MutedChannels[channel.Id] = until
+	})
+	if err != nil {
+		c.client.Log.Error("Failed to mute channel", "userId", userID, "error", err)
+		return c.ephemeral(c.localizer.Localize(userID, snoozeErrorMessage, nil))
+	}
+
+	if until == 0 {
+		return c.ephemeral(c.localizer.Localize(userID, mutedMessage, map[string]any{"Channel": channel.Name}))
+	}
+	return c.ephemeral(c.localizer.Localize(userID, mutedUntilMessage, map[string]any{
+		"Channel": channel.Name,
+		"Time":    c.formatUserTime(userID, until),
+	}))
+}
+
+func (c *Handler) executeUnmuteCommand(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	userID := args.UserId
+	if len(fields) > 1 {
+		return c.ephemeral(c.localizer.Localize(userID, unmuteUsageMessage, nil))
+	}
+
+	if len(fields) == 0 {
+		_, err := c.kvstore.UpdateSnooze(userID, func(snooze *kvstore.Snooze) {
+			snooze.Until = 0
+			snooze.MutedChannels = nil
+		})
+		if err != nil {
+			c.client.Log.Error("Failed to resume notifications", "userId", userID, "error", err)
+			return c.ephemeral(c.localizer.Localize(userID, snoozeErrorMessage, nil))
+		}
+		return c.ephemeral(c.localizer.Localize(userID, resumedMessage, nil))
+	}
+
+	name := strings.TrimPrefix(fields[0], "~")
+	channel, ok := c.findChannel(userID, args.TeamId, name)
+	if !ok {
+		return c.ephemeral(c.localizer.Localize(userID, channelNotFoundMessage, map[string]any{"Channel": name}))
+	}
+
+	var muted bool
+	_, err := c.kvstore.UpdateSnooze(userID, func(snooze *kvstore.Snooze) {
+		_, muted = snooze.MutedChannels[channel.Id]
+		delete(snooze.MutedChannels, channel.Id)
+	})
+	if err != nil {
+		c.client.Log.Error("Failed to unmute channel", "userId", userID, "error", err)
+		return c.ephemeral(c.localizer.Localize(userID, snoozeErrorMessage, nil))
+	}
+
+	if !muted {
+		return c.ephemeral(c.localizer.Localize(userID, notMutedMessage, map[string]any{"Channel": channel.Name}))
+	}
+	return c.ephemeral(c.localizer.Localize(userID, unmutedMessage, map[string]any{"Channel": channel.Name}))
+}
@@ -0,0 +1,317 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	testCommand   = "test"
+	statusCommand = "status"
+)
+
+var (
+	testUsageMessage = &i18n.Message{
+		ID:    "command.test.usage",
+		Other: "Usage: /shoutrrr test [label or number]",
+	}
+	testSucceededMessage = &i18n.Message{
+		ID:    "command.test.succeeded",
+		Other: "{{.Target}}: sent",
+	}
+	testFailedMessage = &i18n.Message{
+		ID:    "command.test.failed",
+		Other: "{{.Target}}: failed, {{.Error}}",
+	}
+	statusTitleMessage = &i18n.Message{
+		ID:    "command.status.title",
+		Other: "#### Shoutrrr notifications",
+	}
+	statusActiveMessage = &i18n.Message{
+		ID:    "command.status.active",
+		One:   "You're notified on {{.Count}} of your {{.Total}} targets.",
+		Other: "You're notified on {{.Count}} of your {{.Total}} targets.",
+	}
+	statusInactiveMessage = &i18n.Message{
+		ID:    "command.status.inactive",
+		Other: "You're not notified: you have no enabled targets.",
+	}
+	statusPausedMessage = &i18n.Message{
+		ID:    "command.status.paused",
+		Other: "You're not notified: a system admin paused the delivery of all notifications.",
+	}
+	statusSnoozedMessage = &i18n.Message{
+		ID:    "command.status.snoozed",
+		Other: "You're not notified: your notifications are snoozed until {{.Time}}.",
+	}
+	statusMutedMessage = &i18n.Message{
+		ID:    "command.status.muted",
+		One:   "You muted {{.Count}} channel.",
+		Other: "You muted {{.Count}} channels.",
+	}
+	statusTargetsMessage = &i18n.Message{
+		ID:    "command.status.targets",
+		Other: "Targets:",
+	}
+	statusNeverUsedMessage = &i18n.Message{
+		ID:    "command.status.never_used",
+		Other: "never used",
+	}
+	statusDeliveredMessage = &i18n.Message{
+		ID:    "command.status.delivered",
+		Other: "last delivered {{.Time}}",
+	}
+	statusFailedMessage = &i18n.Message{
+		ID:    "command.status.failed",
+		Other: "last failed {{.Time}}: {{.Error}}",
+	}
+	statusTriggersMessage = &i18n.Message{
+		ID:    "command.status.triggers",
+		Other: "Triggers:",
+	}
+	statusSubscriptionsMessage = &i18n.Message{
+		ID:    "command.status.subscriptions",
+		One:   "{{.Count}} channel subscription",
+		Other: "{{.Count}} channel subscriptions",
+	}
+	statusWatchRulesMessage = &i18n.Message{
+		ID:    "command.status.watch_rules",
+		One:   "{{.Count}} watch rule",
+		Other: "{{.Count}} watch rules",
+	}
+	statusDigestMessage = &i18n.Message{
+		ID:    "command.status.digest",
+		One:   "Mentions are batched into a digest every minute",
+		Other: "Mentions are batched into a digest every {{.Count}} minutes",
+	}
+	statusEditsMessage = &i18n.Message{
+		ID:    "command.status.edits",
+		Other: "Edits mentioning you are notified",
+	}
+	statusReactionsMessage = &i18n.Message{
+		ID:    "command.status.reactions",
+		Other: "Reactions to your posts are notified",
+	}
+	statusPushSettingsMessage = &i18n.Message{
+		ID:    "command.status.push_settings",
+		Other: "Notifications follow your Mattermost push settings",
+	}
+)
+
+// healthMessages describe the health of the targets that were notified.
+var healthMessages = map[string]*i18n.Message{
+	kvstore.HealthHealthy: {
+		ID:    "command.status.health.healthy",
+		Other: "healthy",
+	},
+	kvstore.HealthDegraded: {
+		ID:    "command.status.health.degraded",
+		Other: "degraded",
+	},
+	kvstore.HealthBroken: {
+		ID:    "command.status.health.broken",
+		Other: "broken",
+	},
+}
+
+func getStatusAutocompleteData() []*model.AutocompleteData {
+	test := model.NewAutocompleteData(testCommand, "[label or number]", "Send a test notification to one or all of your targets")
+	test.AddTextArgument("Label or number of the target, as listed, all targets if empty", "[label or number]", "")
+
+	status := model.NewAutocompleteData(statusCommand, "", "Show your targets, triggers and the outcome of the last notifications")
+
+	return []*model.AutocompleteData{test, status}
+}
+
+// executeTestCommand sends a test notification to a target, or to every enabled target, and
+// reports the outcome for each of them.
+func (c *Handler) executeTestCommand(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	if len(fields) > 1 {
+		return c.ephemeral(c.localizer.Localize(args.UserId, testUsageMessage, nil))
+	}
+
+	targets, err := prefstore.GetTargets(c.preferences, args.UserId)
+	if err != nil {
+		c.client.Log.Error("Failed to get targets", "userId", args.UserId, "error", err)
+		return c.ephemeral(c.localizer.Localize(args.UserId, targetErrorMessage, nil))
+	}
+
+	var tested []*prefstore.Target
+	if len(fields) == 1 {
+		i, ok := findTarget(targets, fields[0])
+		if !ok {
+			return c.ephemeral(c.localizer.Localize(args.UserId, targetNotFoundMessage, map[string]any{"Target": fields[0]}))
+		}
+		tested = append(tested, targets[i])
+	} else {
+		for _, target := range targets {
+			if target.Enabled {
+				tested = append(tested, target)
+			}
+		}
+	}
+	if len(tested) == 0 {
+		return c.ephemeral(c.localizer.Localize(args.UserId, targetNoneMessage, nil))
+	}
+
+	lines := make([]string, 0, len(tested))
+	for _, target := range tested {
+		if err := c.notifications.SendTestNotification(args.UserId, target.URL); err != nil {
+			lines = append(lines, "- "+c.localizer.Localize(args.UserId, testFailedMessage, map[string]any{
+				"Target": describeTarget(target),
+				"Error":  err.Error(),
+			}))
+		} else {
+			lines = append(lines, "- "+c.localizer.Localize(args.UserId, testSucceededMessage, map[string]any{"Target": describeTarget(target)}))
+		}
+	}
+	return c.ephemeral(strings.Join(lines, "\n"))
+}
+
+// executeStatusCommand shows the user's effective configuration and the outcome of the last
+// notifications sent to each of their targets.
+func (c *Handler) executeStatusCommand(args *model.CommandArgs) *model.CommandResponse {
+	userID := args.UserId
+
+	targets, err := prefstore.GetTargets(c.preferences, userID)
+	if err != nil {
+		c.client.Log.Error("Failed to get targets", "userId", userID, "error", err)
+		return c.ephemeral(c.localizer.Localize(userID, targetErrorMessage, nil))
+	}
+
+	enabled := 0
+	for _, target := range targets {
+		if target.Enabled {
+			enabled++
+		}
+	}
+
+	snooze, err := c.kvstore.GetSnooze(userID)
+	if err != nil {
+		c.client.Log.Warn("Failed to get snooze", "userId", userID, "error", err)
+	}
+	now := model.GetMillis()
+
+	lines := []string{c.localizer.Localize(userID, statusTitleMessage, nil)}
+	switch {
+	case enabled == 0:
+		lines = append(lines, c.localizer.Localize(userID, statusInactiveMessage, nil))
+	case c.notifications.DeliveryPaused():
+		lines = append(lines, c.localizer.Localize(userID, statusPausedMessage, nil))
+	case snooze.Snoozed(now):
+		lines = append(lines, c.localizer.Localize(userID, statusSnoozedMessage, map[string]any{"Time": c.formatUserTime(userID, snooze.Until)}))
+	default:
+		lines = append(lines, c.localizer.LocalizeCount(userID, statusActiveMessage, enabled, map[string]any{"Total": len(targets)}))
+	}
+	if muted := countMutedChannels(snooze, now); muted > 0 {
+		lines = append(lines, c.localizer.LocalizeCount(userID, statusMutedMessage, muted, nil))
+	}
+
+	if len(targets) > 0 {
+		lines = append(lines, "", c.localizer.Localize(userID, statusTargetsMessage, nil))
+		for i, target := range targets {
+			lines = append(lines, strconv.Itoa(i+1)+". "+c.describeTargetStatus(userID, target))
+		}
+	}
+
+	lines = append(lines, "", c.localizer.Localize(userID, statusTriggersMessage, nil))
+	for _, trigger := range c.describeTriggers(userID) {
+		lines = append(lines, "- "+trigger)
+	}
+
+	return c.ephemeral(strings.Join(lines, "\n"))
+}
+
+// describeTargetStatus describes a target with its state and the outcome of its last notification.
+func (c *Handler) describeTargetStatus(userID string, target *prefstore.Target) string {
+	line := describeTarget(target)
+	if target.Label != "" {
+		line += " `" + notification.RedactURL(target.URL) + "`"
+	}
+	if !target.Enabled {
+		line += " (" + c.localizer.Localize(userID, targetDisabledLabel, nil) + ")"
+	}
+
+	status, err := c.kvstore.GetDeliveryStatus(userID, target.ID)
+	if err != nil {
+		c.client.Log.Warn("Failed to get delivery status", "userId", userID, "error", err)
+		return line
+	}
+
+	if status == nil {
+		return line + ": " + c.localizer.Localize(userID, statusNeverUsedMessage, nil)
+	}
+
+	line += ": " + c.localizer.Localize(userID, healthMessages[status.Health()], nil) + ", "
+	if status.Failing() {
+		line += c.localizer.Localize(userID, statusFailedMessage, map[string]any{
+			"Time":  c.formatUserTime(userID, status.LastAttemptAt),
+			"Error": status.LastError,
+		})
+	} else {
+		line += c.localizer.Localize(userID, statusDeliveredMessage, map[string]any{"Time": c.formatUserTime(userID, status.LastSuccessAt)})
+	}
+	return line
+}
+
+// describeTriggers lists what the user is notified of besides their mentions.
+func (c *Handler) describeTriggers(userID string) []string {
+	var triggers []string
+
+	if subscriptions, err := c.kvstore.GetSubscriptions(userID); err != nil {
+		c.client.Log.Warn("Failed to get subscriptions", "userId", userID, "error", err)
+	} else if len(subscriptions) > 0 {
+		triggers = append(triggers, c.localizer.LocalizeCount(userID, statusSubscriptionsMessage, len(subscriptions), nil))
+	}
+
+	if rules, err := c.kvstore.GetWatchRules(userID); err != nil {
+		c.client.Log.Warn("Failed to get watch rules", "userId", userID, "error", err)
+	} else if len(rules) > 0 {
+		triggers = append(triggers, c.localizer.LocalizeCount(userID, statusWatchRulesMessage, len(rules), nil))
+	}
+
+	if minutes, err := strconv.Atoi(c.getPreference(userID, prefstore.DigestInterval)); err == nil && minutes > 0 {
+		triggers = append(triggers, c.localizer.LocalizeCount(userID, statusDigestMessage, minutes, nil))
+	}
+	if c.getPreference(userID, prefstore.EditNotifications) != "false" {
+		triggers = append(triggers, c.localizer.Localize(userID, statusEditsMessage, nil))
+	}
+	if c.getPreference(userID, prefstore.ReactionNotifications) == "true" {
+		triggers = append(triggers, c.localizer.Localize(userID, statusReactionsMessage, nil))
+	}
+	if c.getPreference(userID, prefstore.FollowPushSettings) == "true" {
+		triggers = append(triggers, c.localizer.Localize(userID, statusPushSettingsMessage, nil))
+	}
+
+	return triggers
+}
+
+// getPreference returns one of the user's preferences, empty if it can't be read.
+func (c *Handler) getPreference(userID, name string) string {
+	value, err := c.preferences.GetPreference(userID, name)
+	if err != nil {
+		c.client.Log.Warn("Failed to get user preference", "userId", userID, "preference", name, "error", err)
+	}
+	return value
+}
+
+// countMutedChannels returns the number of channels the user muted at the time, in milliseconds.
+func countMutedChannels(snooze *kvstore.Snooze, now int64) int {
+	if snooze == nil {
+		return 0
+	}
+
+	count := 0
+	for channelID := range snooze.MutedChannels {
+		if snooze.Muted(channelID, now) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,316 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	addCommand     = "add"
+	listCommand    = "list"
+	removeCommand  = "remove"
+	enableCommand  = "enable"
+	disableCommand = "disable"
+	renameCommand  = "rename"
+)
+
+// labelPattern restricts labels to single words, so that they can be typed as command arguments.
+var labelPattern = regexp.MustCompile(`^[\p{L}\p{N}_.-]{1,32}$`)
+
+var (
+	addUsageMessage = &i18n.Message{
+		ID:    "command.target.add.usage",
+		Other: "Usage: /shoutrrr add <url> [label]",
+	}
+	targetUsageMessage = &i18n.Message{
+		ID:    "command.target.usage",
+		Other: "Usage: /shoutrrr {{.Command}} <label or number>",
+	}
+	renameUsageMessage = &i18n.Message{
+		ID:    "command.target.rename.usage",
+		Other: "Usage: /shoutrrr rename <label or number> <new label>",
+	}
+	targetAddedMessage = &i18n.Message{
+		ID:    "command.target.added",
+		Other: "Added {{.Target}}",
+	}
+	targetRemovedMessage = &i18n.Message{
+		ID:    "command.target.removed",
+		Other: "Removed {{.Target}}",
+	}
+	targetEnabledMessage = &i18n.Message{
+		ID:    "command.target.enabled",
+		Other: "Enabled {{.Target}}",
+	}
+	targetDisabledMessage = &i18n.Message{
+		ID:    "command.target.disabled",
+		Other: "Disabled {{.Target}}, which won't get notifications until you enable it again",
+	}
+	targetRenamedMessage = &i18n.Message{
+		ID:    "command.target.renamed",
+		Other: "Renamed {{.Target}} to {{.Label}}",
+	}
+	targetInvalidURLMessage = &i18n.Message{
+		ID:    "command.target.invalid_url",
+		Other: "Invalid Shoutrrr URL: {{.Error}}",
+	}
+	targetDuplicateMessage = &i18n.Message{
+		ID:    "command.target.duplicate",
+		Other: "This URL is already one of your targets",
+	}
+	targetInvalidLabelMessage = &i18n.Message{
+		ID:    "command.target.invalid_label",
+		Other: "Labels are single words of up to 32 letters, digits, dots, dashes and underscores",
+	}
+	targetLabelTakenMessage = &i18n.Message{
+		ID:    "command.target.label_taken",
+		Other: "You already have a target labeled {{.Label}}",
+	}
+	targetNotFoundMessage = &i18n.Message{
+		ID:    "command.target.not_found",
+		Other: "None of your targets matches {{.Target}}. Use /shoutrrr list to see them.",
+	}
+	targetNoneMessage = &i18n.Message{
+		ID:    "command.target.none",
+		Other: "You have no targets yet. Add one with /shoutrrr add <url> [label].",
+	}
+	targetListMessage = &i18n.Message{
+		ID:    "command.target.list",
+		Other: "Your targets:",
+	}
+	targetDisabledLabel = &i18n.Message{
+		ID:    "command.target.disabled_label",
+		Other: "disabled",
+	}
+	targetErrorMessage = &i18n.Message{
+		ID:    "command.target.error",
+		Other: "Failed to update your targets, please try again later",
+	}
+)
+
+func getTargetAutocompleteData() []*model.AutocompleteData {
+	add := model.NewAutocompleteData(addCommand, "<url> [label]", "Add a target to send your notifications to")
+	add.AddTextArgument("Shoutrrr URL of the target", "<url>", "")
+	add.AddTextArgument("Optional label to refer to the target", "[label]", "")
+
+	list := model.NewAutocompleteData(listCommand, "", "List your targets")
+
+	subcommands := []*model.AutocompleteData{add, list}
+	for _, subcommand := range []struct {
+		trigger  string
+		helpText string
+	}{
+		{removeCommand, "Remove a target"},
+		{enableCommand, "Send notifications to a disabled target again"},
+		{disableCommand, "Stop sending notifications to a target without removing it"},
+	} {
+		data := model.NewAutocompleteData(subcommand.trigger, "<label or number>", subcommand.helpText)
+		data.AddTextArgument("Label or number of the target, as listed", "<label or number>", "")
+		subcommands = append(subcommands, data)
+	}
+
+	rename := model.NewAutocompleteData(renameCommand, "<label or number> <new label>", "Change the label of a target")
+	rename.AddTextArgument("Label or number of the target, as listed", "<label or number>", "")
+	rename.AddTextArgument("New label of the target", "<new label>", "")
+	subcommands = append(subcommands, rename)
+
+	return subcommands
+}
+
+// findTarget returns the target with the given label, case-insensitively, or at the given
+// position in the list, starting at 1.
+func findTarget(targets []*prefstore.Target, ref string) (int, bool) {
+	for i, target := range targets {
+		if target.Label != "" && strings.EqualFold(target.Label, ref) {
+			return i, true
+		}
+	}
+	if n, err := strconv.Atoi(ref); err == nil && n >= 1 && n <= len(targets) {
+		return n - 1, true
+	}
+	return 0, false
+}
+
+// labelTaken returns whether another target than the one at index skip has the label.
+func labelTaken(targets []*prefstore.Target, label string, skip int) bool {
+	for i, target := range targets {
+		if i != skip && strings.EqualFold(target.Label, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeTarget returns the label of a target, or its redacted URL if it has none.
+func describeTarget(target *prefstore.Target) string {
+	if target.Label != "" {
+		return "**" + target.Label + "**"
+	}
+	return "`" + notification.RedactURL(target.URL) + "`"
+}
+
+// updateTargets loads the user's targets, applies update and saves them unless update returns a
+// response, which is then returned as is.
+func (c *Handler) updateTargets(userID string, update func(targets []*prefstore.Target) ([]*prefstore.Target, *model.CommandResponse)) *model.CommandResponse {
+	targets, err := prefstore.GetTargets(c.preferences, userID)
+	if err != nil {
+		c.client.Log.Error("Failed to get targets", "userId", userID, "error", err)
+		return c.ephemeral(c.localizer.Localize(userID, targetErrorMessage, nil))
+	}
+
+	targets, response := update(targets)
+	if response != nil {
+		return response
+	}
+
+	if err := prefstore.SaveTargets(c.preferences, userID, targets); err != nil {
+		c.client.Log.Error("Failed to save targets", "userId", userID, "error", err)
+		return c.ephemeral(c.localizer.Localize(userID, targetErrorMessage, nil))
+	}
+	return nil
+}
+
+func (c *Handler) executeAddCommand(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	if len(fields) < 1 || len(fields) > 2 {
+		return c.ephemeral(c.localizer.Localize(args.UserId, addUsageMessage, nil))
+	}
+
+	target := &prefstore.Target{URL: fields[0], Enabled: true}
+	if len(fields) == 2 {
+		target.Label = fields[1]
+		if !labelPattern.MatchString(target.Label) {
+			return c.ephemeral(c.localizer.Localize(args.UserId, targetInvalidLabelMessage, nil))
+		}
+	}
+
+	if err := notification.ValidateURL(target.URL); err != nil {
+		return c.ephemeral(c.localizer.Localize(args.UserId, targetInvalidURLMessage, map[string]any{"Error": err.Error()}))
+	}
+
+	response := c.updateTargets(args.UserId, func(targets []*prefstore.Target) ([]*prefstore.Target, *model.CommandResponse) {
+		for _, existing := range targets {
+			if existing.URL == target.URL {
+				return nil, c.ephemeral(c.localizer.Localize(args.UserId, targetDuplicateMessage, nil))
+			}
+		}
+		if target.Label != "" && labelTaken(targets, target.Label, -1) {
+			return nil, c.ephemeral(c.localizer.Localize(args.UserId, targetLabelTakenMessage, map[string]any{"Label": target.Label}))
+		}
+		return append(targets, target), nil
+	})
+	if response != nil {
+		return response
+	}
+
+	return c.ephemeral(c.localizer.Localize(args.UserId, targetAddedMessage, map[string]any{"Target": describeTarget(target)}))
+}
+
+func (c *Handler) executeListCommand(args *model.CommandArgs) *model.CommandResponse {
+	targets, err := prefstore.GetTargets(c.preferences, args.UserId)
+	if err != nil {
+		c.client.Log.Error("Failed to get targets", "userId", args.UserId, "error", err)
+		return c.ephemeral(c.localizer.Localize(args.UserId, targetErrorMessage, nil))
+	}
+	if len(targets) == 0 {
+		return c.ephemeral(c.localizer.Localize(args.UserId, targetNoneMessage, nil))
+	}
+
+	lines := []string{c.localizer.Localize(args.UserId, targetListMessage, nil)}
+	for i, target := range targets {
+		line := fmt.Sprintf("%d. ", i+1)
+		if target.Label != "" {
+			line += "**" + target.Label + "** "
+		}
+		line += "`" + notification.RedactURL(target.URL) + "`"
+		if !target.Enabled {
+			line += " (" + c.localizer.Localize(args.UserId, targetDisabledLabel, nil) + ")"
+		}
+		lines = append(lines, line)
+	}
+	return c.ephemeral(strings.Join(lines, "\n"))
+}
+
+// executeTargetCommand removes, enables or disables one of the user's targets.
+func (c *Handler) executeTargetCommand(args *model.CommandArgs, command string, fields []string) *model.CommandResponse {
+	if len(fields) != 1 {
+		return c.ephemeral(c.localizer.Localize(args.UserId, targetUsageMessage, map[string]any{"Command": command}))
+	}
+
+	var changed *prefstore.Target
+	var reset bool
+	response := c.updateTargets(args.UserId, func(targets []*prefstore.Target) ([]*prefstore.Target, *model.CommandResponse) {
+		i, ok := findTarget(targets, fields[0])
+		if !ok {
+			return nil, c.ephemeral(c.localizer.Localize(args.UserId, targetNotFoundMessage, map[string]any{"Target": fields[0]}))
+		}
+
+		changed = targets[i]
+		reset = command == removeCommand || (command == enableCommand && !changed.Enabled)
+		switch command {
+		case removeCommand:
+			return append(targets[:i:i], targets[i+1:]...), nil
+		case enableCommand:
+			changed.Enabled = true
+		case disableCommand:
+			changed.Enabled = false
+		}
+		return targets, nil
+	})
+	if response != nil {
+		return response
+	}
+
+	// A target enabled again starts afresh, so that its past failures don't disable it again
+	if reset {
+		if err := c.kvstore.ResetDeliveryStatus(args.UserId, changed.ID); err != nil {
+			c.client.Log.Warn("Failed to reset delivery status", "userId", args.UserId, "error", err)
+		}
+	}
+
+	message := targetRemovedMessage
+	switch command {
+	case enableCommand:
+		message = targetEnabledMessage
+	case disableCommand:
+		message = targetDisabledMessage
+	}
+	return c.ephemeral(c.localizer.Localize(args.UserId, message, map[string]any{"Target": describeTarget(changed)}))
+}
+
+func (c *Handler) executeRenameCommand(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	if len(fields) != 2 {
+		return c.ephemeral(c.localizer.Localize(args.UserId, renameUsageMessage, nil))
+	}
+
+	label := fields[1]
+	if !labelPattern.MatchString(label) {
+		return c.ephemeral(c.localizer.Localize(args.UserId, targetInvalidLabelMessage, nil))
+	}
+
+	var previous string
+	response := c.updateTargets(args.UserId, func(targets []*prefstore.Target) ([]*prefstore.Target, *model.CommandResponse) {
+		i, ok := findTarget(targets, fields[0])
+		if !ok {
+			return nil, c.ephemeral(c.localizer.Localize(args.UserId, targetNotFoundMessage, map[string]any{"Target": fields[0]}))
+		}
+		if labelTaken(targets, label, i) {
+			return nil, c.ephemeral(c.localizer.Localize(args.UserId, targetLabelTakenMessage, map[string]any{"Label": label}))
+		}
+
+		previous = describeTarget(targets[i])
+		targets[i].Label = label
+		return targets, nil
+	})
+	if response != nil {
+		return response
+	}
+
+	return c.ephemeral(c.localizer.Localize(args.UserId, targetRenamedMessage, map[string]any{"Target": previous, "Label": "**" + label + "**"}))
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"reflect"
 
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
 	"github.com/pkg/errors"
 )
 
@@ -18,6 +19,45 @@ import (
 // If you add non-reference types to your configuration struct, be sure to rewrite Clone as a deep
 // copy appropriate for your types.
 type configuration struct {
+	// PriorityMapping is a JSON object overriding the Shoutrrr params sent for each priority,
+	// keyed by scheme and priority.
+	PriorityMapping string
+
+	// MessageTemplate is the default Go text/template used to render notifications.
+	MessageTemplate string
+
+	// TitleTemplate is the default Go text/template used to render notification titles and email subjects.
+	TitleTemplate string
+
+	// ExcerptLength is the maximum number of characters of a post included in a notification.
+	ExcerptLength int
+
+	// EnableImagePreviews includes signed public links to the thumbnails of attached images in notifications.
+	EnableImagePreviews bool
+
+	// ImagePreviewExpiryMinutes is how long the links to thumbnails stay valid.
+	ImagePreviewExpiryMinutes int
+
+	// UserRateLimit is the maximum number of notifications per minute sent to a user, zero for no limit.
+	UserRateLimit int
+
+	// TargetRateLimit is the maximum number of notifications per minute sent to each of a user's
+	// services, zero for no limit.
+	TargetRateLimit int
+
+	// DeliveryPaused stops sending notifications anywhere, as a kill switch.
+	DeliveryPaused bool
+
+	// FailureAlertThreshold is the number of consecutive failures of a user's target after which
+	// the bot warns the user, zero to never warn.
+	FailureAlertThreshold int
+
+	// AutoDisableThreshold is the number of consecutive failures of a user's target after which
+	// the hourly job disables it, zero to never disable targets.
+	AutoDisableThreshold int
+
+	// priorityMapping is the parsed form of PriorityMapping.
+	priorityMapping notification.PriorityMapping
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -77,7 +117,41 @@ func (p *Plugin) OnConfigurationChange() error {
 		return errors.Wrap(err, "failed to load plugin configuration")
 	}
 
+	priorityMapping, err := notification.ParsePriorityMapping(configuration.PriorityMapping)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse priority mapping")
+	}
+	configuration.priorityMapping = priorityMapping
+
+	if configuration.MessageTemplate != "" {
+		if _, err := notification.ParseTemplate(configuration.MessageTemplate); err != nil {
+			return errors.Wrap(err, "invalid message template")
+		}
+	}
+
+	if configuration.TitleTemplate != "" {
+		if _, err := notification.ParseTemplate(configuration.TitleTemplate); err != nil {
+			return errors.Wrap(err, "invalid title template")
+		}
+	}
+
 	p.setConfiguration(configuration)
 
 	return nil
 }
+
+// getNotificationConfig returns the parts of the configuration used by the notification service.
+func (p *Plugin) getNotificationConfig() *notification.Config {
+	configuration := p.getConfiguration()
+
+	return &notification.Config{
+		PriorityMapping: configuration.priorityMapping,
+		MessageTemplate: configuration.MessageTemplate,
+		TitleTemplate:   configuration.TitleTemplate,
+		RateLimits: notification.RateLimits{
+			User:   configuration.UserRateLimit,
+			Target: configuration.TargetRateLimit,
+		},
+		DeliveryPaused: configuration.DeliveryPaused,
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+)
+
+// MessageHasBeenUpdated is called after a message has been edited. Only the users the edit
+// mentioned for the first time are notified, unless they opted out of edit notifications.
+func (p *Plugin) MessageHasBeenUpdated(c *plugin.Context, newPost, oldPost *model.Post) {
+	// Pinning or reacting to a post also updates it
+	if newPost.Message == oldPost.Message {
+		return
+	}
+
+	newMentions, err := p.GetAllMentions(newPost)
+	if err != nil {
+		p.API.LogError("Failed to get mentions from edited post", "error", err.Error(), "postId", newPost.Id)
+		return
+	}
+
+	oldMentions, err := p.GetAllMentions(oldPost)
+	if err != nil {
+		p.API.LogError("Failed to get mentions from original post", "error", err.Error(), "postId", oldPost.Id)
+		return
+	}
+
+	mentionTypes := make(map[string]string)
+	for userID, mentionType := range getNewMentions(oldMentions, newMentions) {
+		if p.wantsEditNotifications(userID) {
+			mentionTypes[userID] = formatMentionType(mentionType)
+		}
+	}
+	if len(mentionTypes) == 0 {
+		return
+	}
+
+	p.notifyMentionedUsers(newPost, mentionTypes, 0)
+}
+
+// getNewMentions returns the users mentioned by the edited post who weren't mentioned by the original.
+func getNewMentions(oldMentions, newMentions *MentionResults) map[string]MentionType {
+	mentions := make(map[string]MentionType)
+	for userID, mentionType := range newMentions.Mentions {
+		if _, ok := oldMentions.Mentions[userID]; !ok {
+			mentions[userID] = mentionType
+		}
+	}
+	return mentions
+}
+
+// wantsEditNotifications returns whether the user wants to be notified when an edit mentions them.
+func (p *Plugin) wantsEditNotifications(userID string) bool {
+	value, err := p.prefstore.GetPreference(userID, prefstore.EditNotifications)
+	if err != nil {
+		p.API.LogWarn("Failed to get user edit notifications preference", "error", err.Error(), "userId", userID)
+	}
+	return value != "false"
+}
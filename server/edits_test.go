@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNewMentions(t *testing.T) {
+	assert := assert.New(t)
+
+	oldMentions := &MentionResults{Mentions: map[string]MentionType{
+		"alice": KeywordMention,
+		"carol": ThreadMention,
+	}}
+	newMentions := &MentionResults{Mentions: map[string]MentionType{
+		"alice": KeywordMention,
+		"bob":   KeywordMention,
+		"carol": KeywordMention,
+		"dave":  GroupMention,
+	}}
+
+	assert.Equal(map[string]MentionType{"bob": KeywordMention, "dave": GroupMention}, getNewMentions(oldMentions, newMentions))
+	assert.Empty(getNewMentions(newMentions, oldMentions))
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+var targetAutoDisabledMessage = &i18n.Message{
+	ID:    "alert.auto_disabled",
+	Other: "Disabled {{.Target}} after {{.Count}} failed notifications in a row. The last error was: {{.Error}}\nEnable it again with /shoutrrr enable once it's fixed.",
+}
+
+// TargetHealth is the health of one of the user's targets, as shown in the settings.
+type TargetHealth struct {
+	Health              string `json:"health"`
+	ConsecutiveFailures int    `json:"consecutive_failures,omitempty"`
+	LastError           string `json:"last_error,omitempty"`
+	LastAttemptAt       int64  `json:"last_attempt_at,omitempty"`
+	LastSuccessAt       int64  `json:"last_success_at,omitempty"`
+}
+
+// checkTargetHealth disables the targets that failed as many times in a row as the admin allows,
+// and lets their owners know. Shoutrrr has no way to check a service without sending it a
+// message, so the health of targets is told by their latest deliveries.
+func (p *Plugin) checkTargetHealth() {
+	threshold := p.getConfiguration().AutoDisableThreshold
+	if threshold <= 0 {
+		return
+	}
+
+	statuses, err := p.kvstore.ListDeliveryStatuses()
+	if err != nil {
+		p.API.LogError("Failed to list delivery statuses", "error", err.Error())
+		return
+	}
+
+	failing := map[string]map[string]*kvstore.DeliveryStatus{}
+	for _, status := range statuses {
+		if status.ConsecutiveFailures < threshold {
+			continue
+		}
+		if failing[status.UserID] == nil {
+			failing[status.UserID] = map[string]*kvstore.DeliveryStatus{}
+		}
+		failing[status.UserID][status.TargetID] = status
+	}
+
+	for userID, statuses := range failing {
+		p.disableFailingTargets(userID, statuses)
+	}
+}
+
+// disableFailingTargets disables the user's targets with the statuses, keyed by target ID, and
+// sends the user a message from the bot for each target disabled.
+func (p *Plugin) disableFailingTargets(userID string, statuses map[string]*kvstore.DeliveryStatus) {
+	targets, err := prefstore.GetTargets(p.prefstore, userID)
+	if err != nil {
+		p.API.LogError("Failed to get targets", "error", err.Error(), "userId", userID)
+		return
+	}
+
+	var disabled []*prefstore.Target
+	for _, target := range targets {
+		if _, ok := statuses[target.ID]; ok && target.Enabled {
+			target.Enabled = false
+			disabled = append(disabled, target)
+		}
+	}
+	if len(disabled) == 0 {
+		return
+	}
+
+	if err := prefstore.SaveTargets(p.prefstore, userID, targets); err != nil {
+		p.API.LogError("Failed to disable failing targets", "error", err.Error(), "userId", userID)
+		return
+	}
+	p.API.LogInfo("Disabled failing targets", "userId", userID, "targets", len(disabled))
+
+	for _, target := range disabled {
+		status := statuses[target.ID]
+		post := &model.Post{
+			Message: p.localizer.Localize(userID, targetAutoDisabledMessage, map[string]any{
+				"Target": describeTarget(target),
+				"Count":  status.ConsecutiveFailures,
+				"Error":  status.LastError,
+			}),
+		}
+		if err := p.client.Post.DM(p.botUserID, userID, post); err != nil {
+			p.API.LogError("Failed to send target disabled message", "error", err.Error(), "userId", userID)
+		}
+	}
+}
+
+// GetTargetHealth returns the health of the user's targets, keyed by target ID.
+func (p *Plugin) GetTargetHealth(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	targets, err := prefstore.GetTargets(p.prefstore, userID)
+	if err != nil {
+		p.API.LogError("Failed to get targets", "error", err.Error(), "userId", userID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	health := make(map[string]*TargetHealth, len(targets))
+	for _, target := range targets {
+		status, err := p.kvstore.GetDeliveryStatus(userID, target.ID)
+		if err != nil {
+			p.API.LogError("Failed to get delivery status", "error", err.Error(), "userId", userID)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		health[target.ID] = &TargetHealth{Health: status.Health()}
+		if status != nil {
+			health[target.ID].ConsecutiveFailures = status.ConsecutiveFailures
+			health[target.ID].LastError = status.LastError
+			health[target.ID].LastAttemptAt = status.LastAttemptAt
+			health[target.ID].LastSuccessAt = status.LastSuccessAt
+		}
+	}
+
+	p.writeJSON(w, http.StatusOK, health)
+}
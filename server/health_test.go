@@ -0,0 +1,131 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/command"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDisableFailingTargets(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &plugintest.API{}
+	preferences := memoryPreferences{
+		prefstore.NotificationServices: "ntfy://ntfy.sh/secret-topic,discord://token@123456789",
+		prefstore.ServiceLabels:        `{"` + prefstore.TargetID("ntfy://ntfy.sh/secret-topic") + `":"phone"}`,
+	}
+	plugin := &Plugin{
+		client:    pluginapi.NewClient(api, &plugintest.Driver{}),
+		prefstore: preferences,
+		botUserID: "bot",
+	}
+	plugin.SetAPI(api)
+	targetID := prefstore.TargetID("ntfy://ntfy.sh/secret-topic")
+
+	var messages []string
+	api.On("LogInfo", "Disabled failing targets", "userId", "user", "targets", 1).Return()
+	api.On("GetDirectChannel", "bot", "user").Return(&model.Channel{Id: "dm"}, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		messages = append(messages, post.Message)
+		return post.Clone()
+	}, nil)
+
+	statuses := map[string]*kvstore.DeliveryStatus{
+		targetID:  {UserID: "user", TargetID: targetID, ConsecutiveFailures: 5, LastError: "got response status 401"},
+		"removed": {UserID: "user", TargetID: "removed", ConsecutiveFailures: 5},
+	}
+	plugin.disableFailingTargets("user", statuses)
+
+	assert.Equal(targetID, preferences[prefstore.DisabledServices])
+	assert.Equal([]string{"Disabled **phone** after 5 failed notifications in a row. The last error was: got response status 401\nEnable it again with /shoutrrr enable once it's fixed."}, messages)
+
+	// Targets already disabled aren't disabled again
+	plugin.disableFailingTargets("user", statuses)
+	assert.Len(messages, 1)
+}
+
+func TestEnabledTargetIsNotDisabledAgain(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &plugintest.API{}
+	client := pluginapi.NewClient(api, &plugintest.Driver{})
+	preferences := memoryPreferences{prefstore.NotificationServices: "ntfy://ntfy.sh/secret-topic"}
+	targetID := prefstore.TargetID("ntfy://ntfy.sh/secret-topic")
+	store := &memoryKVStore{deliveries: map[string]*kvstore.DeliveryStatus{
+		targetID: {UserID: "user", TargetID: targetID, ConsecutiveFailures: 5},
+	}}
+	plugin := &Plugin{
+		client:    client,
+		prefstore: preferences,
+		kvstore:   store,
+		botUserID: "bot",
+	}
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{AutoDisableThreshold: 5})
+
+	api.On("RegisterCommand", mock.Anything).Return(nil)
+	api.On("LogInfo", "Disabled failing targets", "userId", "user", "targets", 1).Return().Once()
+	api.On("GetDirectChannel", "bot", "user").Return(&model.Channel{Id: "dm"}, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		return post.Clone()
+	}, nil).Once()
+
+	plugin.checkTargetHealth()
+	assert.Equal(targetID, preferences[prefstore.DisabledServices])
+
+	// Once the user enables it again, its past failures don't disable it on the next run
+	handler := command.NewCommandHandler(client, nil, nil, store, preferences, nil)
+	response, err := handler.Handle(&model.CommandArgs{Command: "/shoutrrr enable 1", UserId: "user"})
+	assert.Nil(err)
+	assert.Equal("Enabled `ntfy://ntfy.sh/***`", response.Text)
+
+	plugin.checkTargetHealth()
+	assert.Empty(preferences[prefstore.DisabledServices])
+	api.AssertExpectations(t)
+}
+
+func TestRemovedTargetsAreReset(t *testing.T) {
+	assert := assert.New(t)
+
+	kept := prefstore.TargetID("ntfy://ntfy.sh/kept")
+	removed := prefstore.TargetID("ntfy://ntfy.sh/removed")
+	unchanged := prefstore.TargetID("ntfy://ntfy.sh/unchanged")
+	store := &memoryKVStore{
+		deliveries: map[string]*kvstore.DeliveryStatus{
+			kept:      {UserID: "user", TargetID: kept},
+			removed:   {UserID: "user", TargetID: removed, ConsecutiveFailures: 2},
+			unchanged: {UserID: "user", TargetID: unchanged},
+		},
+		targetIDs: []string{kept, removed},
+	}
+	plugin := &Plugin{kvstore: store}
+	changed := func(services string) {
+		plugin.PreferencesHaveChanged(nil, []model.Preference{{UserId: "user", Category: prefstore.Category, Name: prefstore.NotificationServices, Value: services}})
+	}
+
+	// Only the targets removed from the previous ones are reset
+	changed("")
+	assert.Len(store.deliveries, 1)
+	assert.Contains(store.deliveries, unchanged)
+	assert.Nil(store.targetIDs)
+
+	// Nothing is done while the targets don't change
+	store.targetIDs = []string{kept}
+	store.deliveries[removed] = &kvstore.DeliveryStatus{UserID: "user", TargetID: removed}
+	store.deliveries[kept] = &kvstore.DeliveryStatus{UserID: "user", TargetID: kept}
+	changed("ntfy://ntfy.sh/kept")
+	assert.Len(store.deliveries, 3)
+
+	// The targets saved before their IDs were recorded are told by their delivery statuses
+	store.targetIDs = nil
+	plugin.resetRemovedTargets("user", nil, []string{kept})
+	assert.Len(store.deliveries, 1)
+	assert.Contains(store.deliveries, kept)
+}
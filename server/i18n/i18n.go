@@ -0,0 +1,156 @@
+// Package i18n renders the plugin's strings in the language of the user reading them.
+package i18n
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/plugin"
+	pluginapii18n "github.com/mattermost/mattermost/server/public/pluginapi/i18n"
+	goi18n "github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/nicksnyder/go-i18n/v2/i18n/template"
+	"github.com/pkg/errors"
+	"golang.org/x/text/language"
+)
+
+// catalogPath is where the translation catalogs are bundled, relative to the plugin's bundle.
+const catalogPath = "assets/i18n"
+
+// defaultLocale is the language of the messages defined in code.
+const defaultLocale = "en"
+
+// localeTTL is how long a user's language is cached. A notification or command response renders
+// dozens of messages, which would otherwise each fetch the user and the server's configuration.
+const localeTTL = time.Minute
+
+// maxCachedLocales bounds the locale cache, which is cleared once full.
+const maxCachedLocales = 1000
+
+// Message is a localizable string. Its Other field holds the English text, used when the catalog
+// of the user's language doesn't translate it.
+type Message = pluginapii18n.Message
+
+// Localizer renders messages in the user's Mattermost language. A nil Localizer renders the
+// English text, which keeps tests and early hooks working without the catalogs.
+type Localizer struct {
+	api    plugin.API
+	bundle *goi18n.Bundle
+
+	lock    sync.Mutex
+	locales map[string]cachedLocale
+}
+
+type cachedLocale struct {
+	locale    string
+	expiresAt time.Time
+}
+
+// NewLocalizer loads the translation catalogs bundled with the plugin.
+func NewLocalizer(api plugin.API) (*Localizer, error) {
+	bundle, err := pluginapii18n.InitBundle(api, catalogPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load translations")
+	}
+
+	return &Localizer{
+		api:     api,
+		bundle:  bundle.Bundle,
+		locales: map[string]cachedLocale{},
+	}, nil
+}
+
+// Locale returns the language the user chose in Mattermost, or the server's default language if
+// they didn't choose one. An empty user ID returns the server's default language. Languages are
+// cached for a minute, so a change of language applies to the following notifications.
+func (l *Localizer) Locale(userID string) string {
+	if l == nil {
+		return defaultLocale
+	}
+
+	now := time.Now()
+	l.lock.Lock()
+	cached, ok := l.locales[userID]
+	l.lock.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.locale
+	}
+
+	locale := l.getLocale(userID)
+
+	l.lock.Lock()
+	if len(l.locales) >= maxCachedLocales {
+		l.locales = map[string]cachedLocale{}
+	}
+	l.locales[userID] = cachedLocale{locale: locale, expiresAt: now.Add(localeTTL)}
+	l.lock.Unlock()
+
+	return locale
+}
+
+func (l *Localizer) getLocale(userID string) string {
+	if userID != "" {
+		user, appErr := l.api.GetUser(userID)
+		if appErr != nil {
+			l.api.LogWarn("Failed to get user locale", "userId", userID, "error", appErr.Error())
+		} else if user.Locale != "" {
+			return user.Locale
+		}
+	}
+
+	if config := l.api.GetConfig(); config != nil && config.LocalizationSettings.DefaultServerLocale != nil && *config.LocalizationSettings.DefaultServerLocale != "" {
+		return *config.LocalizationSettings.DefaultServerLocale
+	}
+	return defaultLocale
+}
+
+// Localize renders a message in the user's language, filling its {{.Field}} placeholders with data.
+func (l *Localizer) Localize(userID string, message *Message, data map[string]any) string {
+	return l.localize(userID, &goi18n.LocalizeConfig{
+		DefaultMessage: message,
+		TemplateData:   data,
+	})
+}
+
+// LocalizeCount renders a message with plural forms in the user's language, choosing the form
+// for count, which is available to the message as {{.Count}}.
+func (l *Localizer) LocalizeCount(userID string, message *Message, count int, data map[string]any) string {
+	templateData := map[string]any{"Count": count}
+	for key, value := range data {
+		templateData[key] = value
+	}
+
+	return l.localize(userID, &goi18n.LocalizeConfig{
+		DefaultMessage: message,
+		PluralCount:    count,
+		TemplateData:   templateData,
+	})
+}
+
+// Template returns a message in the user's language without rendering it, for messages that are
+// Go templates rendered later with their own data, such as the default notification template.
+func (l *Localizer) Template(userID string, message *Message) string {
+	return l.localize(userID, &goi18n.LocalizeConfig{
+		DefaultMessage: message,
+		TemplateParser: template.IdentityParser{},
+	})
+}
+
+func (l *Localizer) localize(userID string, config *goi18n.LocalizeConfig) string {
+	bundle := goi18n.NewBundle(language.English)
+	if l != nil {
+		bundle = l.bundle
+	}
+
+	text, err := goi18n.NewLocalizer(bundle, l.Locale(userID)).Localize(config)
+	if err != nil {
+		// A message missing from the user's catalog still renders in English.
+		var notFound *goi18n.MessageNotFoundErr
+		if !errors.As(err, &notFound) && l != nil {
+			l.api.LogWarn("Failed to localize message", "id", config.DefaultMessage.ID, "error", err.Error())
+		}
+		if text == "" {
+			return config.DefaultMessage.Other
+		}
+	}
+	return text
+}
@@ -0,0 +1,96 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testMessage = &Message{
+	ID:    "command.target.added",
+	Other: "Added {{.Target}}",
+}
+
+func setupLocalizer(t *testing.T, serverLocale string) *Localizer {
+	api := &plugintest.API{}
+	api.On("GetBundlePath").Return("../..", nil)
+
+	config := &model.Config{}
+	config.SetDefaults()
+	config.LocalizationSettings.DefaultServerLocale = model.NewPointer(serverLocale)
+	api.On("GetConfig").Return(config)
+
+	api.On("GetUser", "german-user").Return(&model.User{Locale: "de"}, nil)
+	api.On("GetUser", "spanish-user").Return(&model.User{Locale: "es"}, nil)
+	api.On("GetUser", "french-user").Return(&model.User{Locale: "fr"}, nil)
+	api.On("GetUser", "default-user").Return(&model.User{}, nil)
+	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	localizer, err := NewLocalizer(api)
+	require.NoError(t, err)
+	return localizer
+}
+
+func TestLocalize(t *testing.T) {
+	assert := assert.New(t)
+	localizer := setupLocalizer(t, "en")
+	data := map[string]any{"Target": "alice"}
+
+	assert.Equal("alice hinzugefügt", localizer.Localize("german-user", testMessage, data))
+	assert.Equal("Se añadió alice", localizer.Localize("spanish-user", testMessage, data))
+
+	// Languages without a catalog and users without a language use English
+	assert.Equal("Added alice", localizer.Localize("french-user", testMessage, data))
+	assert.Equal("Added alice", localizer.Localize("default-user", testMessage, data))
+
+	// Messages missing from a catalog use English
+	assert.Equal("Untranslated", localizer.Localize("german-user", &Message{ID: "test.untranslated", Other: "Untranslated"}, nil))
+}
+
+func TestLocalizeFallsBackToServerLocale(t *testing.T) {
+	assert := assert.New(t)
+	localizer := setupLocalizer(t, "es")
+
+	assert.Equal("es", localizer.Locale("default-user"))
+	assert.Equal("es", localizer.Locale(""))
+	assert.Equal("de", localizer.Locale("german-user"))
+	assert.Equal("Se añadió bob", localizer.Localize("default-user", testMessage, map[string]any{"Target": "bob"}))
+}
+
+func TestLocaleIsCached(t *testing.T) {
+	assert := assert.New(t)
+	localizer := setupLocalizer(t, "en")
+	api := localizer.api.(*plugintest.API)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal("alice hinzugefügt", localizer.Localize("german-user", testMessage, map[string]any{"Target": "alice"}))
+	}
+	api.AssertNumberOfCalls(t, "GetUser", 1)
+
+	// Expired languages are fetched again
+	localizer.locales["german-user"] = cachedLocale{locale: "de", expiresAt: time.Now().Add(-time.Second)}
+	assert.Equal("de", localizer.Locale("german-user"))
+	api.AssertNumberOfCalls(t, "GetUser", 2)
+}
+
+func TestTemplate(t *testing.T) {
+	assert := assert.New(t)
+	localizer := setupLocalizer(t, "en")
+
+	assert.Equal("{{.Target}} hinzugefügt", localizer.Template("german-user", testMessage))
+	assert.Equal("Added {{.Target}}", localizer.Template("french-user", testMessage))
+}
+
+func TestNilLocalizer(t *testing.T) {
+	assert := assert.New(t)
+
+	var localizer *Localizer
+	assert.Equal("en", localizer.Locale("user-id"))
+	assert.Equal("Added alice", localizer.Localize("user-id", testMessage, map[string]any{"Target": "alice"}))
+	assert.Equal("Added {{.Target}}", localizer.Template("user-id", testMessage))
+}
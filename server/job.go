@@ -1,6 +1,7 @@
 package main
 
+// runJob runs the plugin's hourly maintenance.
 func (p *Plugin) runJob() {
-	// Include job logic here
-	p.API.LogInfo("Job is currently running")
+	p.checkTargetHealth()
+	p.refreshPushFollowers()
 }
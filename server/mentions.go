@@ -20,6 +20,9 @@ const (
 	// A placeholder that should never be used in practice
 	NoMention MentionType = iota
 
+	// The post matches one of the user's watch rules
+	WatchMention
+
 	// The post is in a GM
 	GMMention
 
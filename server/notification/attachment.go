@@ -0,0 +1,131 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/containrrr/shoutrrr/pkg/types"
+)
+
+// Attachment describes a file attached to a post.
+type Attachment struct {
+	Name     string
+	Size     int64
+	MimeType string
+}
+
+// String describes the attachment, e.g. "report.pdf (1.2 MB, application/pdf)".
+func (a Attachment) String() string {
+	if a.MimeType == "" {
+		return fmt.Sprintf("%s (%s)", a.Name, formatFileSize(a.Size))
+	}
+	return fmt.Sprintf("%s (%s, %s)", a.Name, formatFileSize(a.Size), a.MimeType)
+}
+
+// formatAttachments lists attachments for the message templates.
+func formatAttachments(attachments []Attachment) string {
+	descriptions := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		descriptions = append(descriptions, attachment.String())
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// formatFileSize returns a human readable file size, e.g. "1.2 MB".
+func formatFileSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit && exp < 3; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGT"[exp])
+}
+
+// imageParamKeys maps the schemes that can attach an image by URL to the param holding that URL.
+var imageParamKeys = map[string]string{
+	"ntfy": "attach",
+}
+
+// addImage adds an image preview to a message for the services that can display one, returning
+// the URL and message to send. Discord only shows images in embeds, which Shoutrrr sends as is
+// when the URL enables its JSON mode. Telegram previews the first link of a message, so the image
+// link goes first, while Matrix clients get the link after the message.
+func addImage(serviceURL, scheme string, format Format, message, title, imageURL string, params types.Params) (string, string, error) {
+	if key, ok := imageParamKeys[scheme]; ok {
+		params[key] = imageURL
+		return serviceURL, message, nil
+	}
+
+	switch scheme {
+	case "discord":
+		return discordImageMessage(serviceURL, message, title, imageURL)
+	case "telegram":
+		if format == FormatPlain {
+			return serviceURL, imageURL + "\n" + message, nil
+		}
+		return serviceURL, getFormatter(format).link("🖼", imageURL) + "\n" + message, nil
+	case "matrix":
+		return serviceURL, message + "\n" + imageURL, nil
+	}
+
+	return serviceURL, message, nil
+}
+
+type discordEmbed struct {
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description"`
+	Image       *discordEmbedImage `json:"image,omitempty"`
+}
+
+type discordEmbedImage struct {
+	URL string `json:"url"`
+}
+
+type discordPayload struct {
+	Embeds    []discordEmbed `json:"embeds"`
+	Username  string         `json:"username,omitempty"`
+	AvatarURL string         `json:"avatar_url,omitempty"`
+}
+
+// discordImageMessage turns a message into a Discord webhook payload embedding the image, keeping
+// the username and avatar configured in the URL since Shoutrrr doesn't apply them in JSON mode.
+func discordImageMessage(serviceURL, message, title, imageURL string) (string, string, error) {
+	parsed, err := url.Parse(serviceURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	query := parsed.Query()
+	payload, err := json.Marshal(&discordPayload{
+		Embeds: []discordEmbed{{
+			Title:       title,
+			Description: message,
+			Image:       &discordEmbedImage{URL: imageURL},
+		}},
+		Username:  query.Get("username"),
+		AvatarURL: firstNonEmpty(query.Get("avatar"), query.Get("avatarurl")),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	query.Set("json", "Yes")
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), string(payload), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package notification
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+)
+
+var bridgeTitleMessage = &i18n.Message{
+	ID:    "notification.bridge.title",
+	Other: "@{{.Sender}} in ~{{.Channel}}",
+}
+
+// BridgedPost describes a post forwarded to the bridges of its channel
+type BridgedPost struct {
+	PostID      string
+	ChannelID   string
+	ChannelName string
+	TeamName    string
+
+	// SenderUsername and SenderDisplayName attribute the post to its author on the other service.
+	SenderUsername    string
+	SenderDisplayName string
+
+	// Message is an excerpt of the post.
+	Message *Excerpt
+
+	// PostPriority is the priority the sender set on the post, if any.
+	PostPriority string
+
+	// Attachments are the files attached to the post.
+	Attachments []Attachment
+
+	// ImageURL is a public link to a preview of an image attached to the post, if enabled.
+	ImageURL string
+}
+
+// SendBridgeNotification forwards a post to one of its channel's bridges. Bridges belong to the
+// channel rather than to a user, so the admin's priority mapping, the default formats and the
+// server's default language apply.
+func (s *Service) SendBridgeNotification(serviceURL string, post *BridgedPost) error {
+	if s.DeliveryPaused() {
+		return nil
+	}
+
+	// The channel stands for the recipient, so that each post is forwarded once per bridge
+	if !s.claimNotification(post.ChannelID, post.PostID, targetID(serviceURL)) {
+		return nil
+	}
+
+	permalink := s.getPermalink("", post.TeamName, post.PostID)
+	title := s.localizer.Localize("", bridgeTitleMessage, map[string]any{
+		"Sender":  post.SenderUsername,
+		"Channel": post.ChannelName,
+	})
+	notification := &Notification{
+		Message: &bridgeMessage{
+			post:      post,
+			permalink: permalink,
+		},
+		Priority: priorityForMention("", post.PostPriority),
+		Title:    title,
+		Link:     permalink,
+		ImageURL: post.ImageURL,
+	}
+
+	if err := s.sendToService(serviceURL, notification, s.getAdminPriorityMapping(), MessageFormats{}); err != nil {
+		s.releaseNotification(post.ChannelID, post.PostID, targetID(serviceURL))
+		return err
+	}
+	return nil
+}
+
+// bridgeMessage renders a forwarded post attributed to its author, followed by its attachments and
+// a link to the post.
+type bridgeMessage struct {
+	post      *BridgedPost
+	permalink string
+}
+
+func (m *bridgeMessage) Render(format Format) string {
+	f := getFormatter(format)
+
+	sender := f.bold("@" + m.post.SenderUsername)
+	if m.post.SenderDisplayName != "" && m.post.SenderDisplayName != m.post.SenderUsername {
+		sender = f.bold(m.post.SenderDisplayName) + f.escape(" (@"+m.post.SenderUsername+")")
+	}
+
+	lines := []string{sender + f.escape(": ") + m.post.Message.Format(format)}
+	if len(m.post.Attachments) > 0 {
+		lines = append(lines, f.escape("📎 "+formatAttachments(m.post.Attachments)))
+	}
+	if m.permalink != "" {
+		lines = append(lines, f.url(m.permalink))
+	}
+
+	return strings.Join(lines, "\n")
+}
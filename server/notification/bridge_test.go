@@ -0,0 +1,33 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBridgeMessageRender(t *testing.T) {
+	message := &bridgeMessage{
+		post: &BridgedPost{
+			SenderUsername:    "alice",
+			SenderDisplayName: "Alice Smith",
+			Message:           (&ExcerptBuilder{MaxLength: 100}).Build("Deploy is **done**"),
+			Attachments:       []Attachment{{Name: "log.txt", Size: 2048}},
+		},
+		permalink: "https://example.com/team/pl/1",
+	}
+
+	for format, expected := range map[Format]string{
+		FormatPlain:    "Alice Smith (@alice): Deploy is done\n📎 log.txt (2.0 KB)\nhttps://example.com/team/pl/1",
+		FormatMarkdown: "**Alice Smith** (@alice): Deploy is **done**\n📎 log.txt (2.0 KB)\nhttps://example.com/team/pl/1",
+	} {
+		t.Run(string(format), func(t *testing.T) {
+			assert.Equal(t, expected, message.Render(format))
+		})
+	}
+
+	message.post.SenderDisplayName = ""
+	message.post.Attachments = nil
+	message.permalink = ""
+	assert.Equal(t, "@alice: Deploy is done", message.Render(FormatPlain))
+}
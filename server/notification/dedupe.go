@@ -0,0 +1,61 @@
+package notification
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// sentNotificationTTL is how long a sent notification is remembered to avoid sending it again,
+// which covers the hooks of every cluster node and later edits of the post.
+const sentNotificationTTL = 24 * time.Hour
+
+// digestTarget stands for the user's digest in idempotency keys, as mentions added to a digest
+// aren't sent to a target yet.
+const digestTarget = "digest"
+
+// mentionIdempotencyKey identifies the notification of a mention, which is sent once per post
+// however many ways the user was mentioned. Each reminder of an urgent post is a new notification.
+func mentionIdempotencyKey(mention *Mention) string {
+	if mention.Reminder > 0 {
+		return mention.PostID + "-reminder-" + strconv.Itoa(mention.Reminder)
+	}
+	return mention.PostID
+}
+
+// idempotencyKey identifies a notification sent to one of a user's targets in the KV store.
+func idempotencyKey(userID, notificationKey, target string) string {
+	hash := sha256.Sum256([]byte(userID + "/" + notificationKey + "/" + target))
+	return hex.EncodeToString(hash[:16])
+}
+
+// claimNotification returns whether the notification wasn't sent to the target yet, and marks it
+// as sent. Notifications are sent when the KV store fails, as a duplicate beats a missed mention.
+func (s *Service) claimNotification(userID, notificationKey, target string) bool {
+	claimed, err := s.kvstore.ClaimNotification(idempotencyKey(userID, notificationKey, target), sentNotificationTTL)
+	if err != nil {
+		s.client.Log.Warn("Failed to check for duplicate notification", "userId", userID, "error", err)
+		return true
+	}
+	return claimed
+}
+
+// releaseNotification forgets a claimed notification that failed to send, so that another
+// attempt can send it.
+func (s *Service) releaseNotification(userID, notificationKey, target string) {
+	if err := s.kvstore.ReleaseNotification(idempotencyKey(userID, notificationKey, target)); err != nil {
+		s.client.Log.Warn("Failed to release notification", "userId", userID, "error", err)
+	}
+}
+
+// claimServices returns the services the notification wasn't sent to yet.
+func (s *Service) claimServices(userID, notificationKey string, services []string) []string {
+	var claimed []string
+	for _, serviceURL := range services {
+		if s.claimNotification(userID, notificationKey, targetID(serviceURL)) {
+			claimed = append(claimed, serviceURL)
+		}
+	}
+	return claimed
+}
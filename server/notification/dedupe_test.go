@@ -0,0 +1,26 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMentionIdempotencyKey(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("post", mentionIdempotencyKey(&Mention{PostID: "post", Type: MentionTypeChannel}))
+	assert.Equal("post", mentionIdempotencyKey(&Mention{PostID: "post", Type: MentionTypeKeyword}))
+	assert.Equal("post-reminder-2", mentionIdempotencyKey(&Mention{PostID: "post", Reminder: 2}))
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	assert := assert.New(t)
+
+	key := idempotencyKey("user", "post", targetID("ntfy://ntfy.sh/topic"))
+	assert.Len(key, 32)
+	assert.Equal(key, idempotencyKey("user", "post", targetID("ntfy://ntfy.sh/topic")))
+	assert.NotEqual(key, idempotencyKey("user", "post", targetID("ntfy://ntfy.sh/other")))
+	assert.NotEqual(key, idempotencyKey("other", "post", targetID("ntfy://ntfy.sh/topic")))
+	assert.NotEqual(key, idempotencyKey("user", "post", digestTarget))
+}
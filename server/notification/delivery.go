@@ -0,0 +1,69 @@
+package notification
+
+import (
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+)
+
+var (
+	testTitleMessage = &i18n.Message{
+		ID:    "notification.test.title",
+		Other: "Test notification",
+	}
+	testPausedMessage = &i18n.Message{
+		ID:    "notification.test.paused",
+		Other: "a system admin paused the delivery of all notifications",
+	}
+	testMessageMessage = &i18n.Message{
+		ID:    "notification.test.message",
+		Other: "This is a test notification from Mattermost. If you can read it, this target works.",
+	}
+)
+
+// recordDelivery records the outcome of a notification sent to one of the user's targets, so that
+// the user can see which targets fail. The outcome is informative, so failing to record it is only logged.
+func (s *Service) recordDelivery(userID, serviceURL string, sendErr error) {
+	var deliveryErr string
+	if sendErr != nil {
+		deliveryErr = RedactError(serviceURL, sendErr)
+	}
+
+	status, err := s.kvstore.RecordDelivery(userID, targetID(serviceURL), deliveryErr)
+	if err != nil {
+		s.client.Log.Warn("Failed to record delivery", "userId", userID, "error", err)
+		return
+	}
+
+	if sendErr != nil && s.onDeliveryFailed != nil {
+		s.onDeliveryFailed(userID, serviceURL, status)
+	}
+}
+
+// SendTestNotification sends a test notification to one of the user's targets, whether or not it's
+// enabled, and returns the error of the service with the target's credentials redacted.
+func (s *Service) SendTestNotification(userID, serviceURL string) error {
+	if s.DeliveryPaused() {
+		return &DeliveryError{Message: s.localizer.Localize(userID, testPausedMessage, nil)}
+	}
+
+	notification := &Notification{
+		Message:  Text(s.localizer.Localize(userID, testMessageMessage, nil)),
+		Priority: PriorityDefault,
+		Title:    s.localizer.Localize(userID, testTitleMessage, nil),
+	}
+
+	err := s.sendToService(serviceURL, notification, s.getPriorityMapping(userID), s.getMessageFormats(userID))
+	s.recordDelivery(userID, serviceURL, err)
+	if err != nil {
+		return &DeliveryError{Message: RedactError(serviceURL, err)}
+	}
+	return nil
+}
+
+// DeliveryError is an error sending to a target, with the target's credentials redacted.
+type DeliveryError struct {
+	Message string
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,257 @@
+package notification
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// maxDigestExcerpts is the number of excerpts shown for each channel of a digest.
+const maxDigestExcerpts = 3
+
+var (
+	digestTitleMessage = &i18n.Message{
+		ID:    "notification.digest.title",
+		One:   "{{.Count}} new mention",
+		Other: "{{.Count}} new mentions",
+	}
+	digestMentionCountMessage = &i18n.Message{
+		ID:    "notification.digest.mention_count",
+		One:   "{{.Count}} mention",
+		Other: "{{.Count}} mentions",
+	}
+)
+
+// digestSettings are the user's preferences for batching their notifications.
+type digestSettings struct {
+	// interval is how long mentions are accumulated before being sent, zero to disable digests.
+	interval time.Duration
+
+	// immediateDMs sends direct messages right away rather than in the digest.
+	immediateDMs bool
+}
+
+// getDigestSettings returns the user's digest preferences. Digests are disabled unless the user
+// chose an interval, and direct messages are sent immediately unless the user opted out.
+func (s *Service) getDigestSettings(userID string) digestSettings {
+	settings := digestSettings{immediateDMs: true}
+
+	intervalStr, err := s.preferences.GetPreference(userID, prefstore.DigestInterval)
+	if err != nil {
+		s.client.Log.Warn("Failed to get user digest interval", "userId", userID, "error", err)
+		return settings
+	}
+	if minutes, err := strconv.Atoi(intervalStr); err == nil && minutes > 0 {
+		settings.interval = time.Duration(minutes) * time.Minute
+	}
+
+	immediateDMs, err := s.preferences.GetPreference(userID, prefstore.DigestImmediateDMs)
+	if err != nil {
+		s.client.Log.Warn("Failed to get user digest preferences", "userId", userID, "error", err)
+	}
+	settings.immediateDMs = immediateDMs != "false"
+
+	return settings
+}
+
+// includes returns whether a mention waits for the digest. Urgent posts are always delivered
+// immediately, so that persistent notifications keep their interval.
+func (settings digestSettings) includes(mention *Mention) bool {
+	if settings.interval <= 0 || mention.PostPriority == PostPriorityUrgent {
+		return false
+	}
+	return !settings.immediateDMs || mention.Type != MentionTypeDM
+}
+
+// addToDigest accumulates a mention in the user's digest, which is due an interval after its first mention.
+func (s *Service) addToDigest(mention *Mention, interval time.Duration) error {
+	now := model.GetMillis()
+	digestMention := &kvstore.DigestMention{
+		PostID:         mention.PostID,
+		SenderUsername: mention.MentionedBy,
+		Excerpt:        mention.Message.String(),
+		Permalink:      s.getPermalink(mention.UserID, mention.TeamName, mention.PostID),
+		Priority:       string(priorityForMention(mention.Type, mention.PostPriority)),
+		CreateAt:       now,
+	}
+
+	return s.kvstore.UpdateDigest(mention.UserID, func(digest *kvstore.Digest) {
+		if digest.SendAt == 0 {
+			digest.SendAt = now + interval.Milliseconds()
+		}
+		if digest.Channels == nil {
+			digest.Channels = map[string]*kvstore.DigestChannel{}
+		}
+
+		key := mention.TeamName + "/" + mention.ChannelName
+		channel, ok := digest.Channels[key]
+		if !ok {
+			channel = &kvstore.DigestChannel{
+				Channel:     mention.Channel,
+				ChannelName: mention.ChannelName,
+				TeamName:    mention.TeamName,
+			}
+			digest.Channels[key] = channel
+		}
+
+		channel.Count++
+		channel.Mentions = topDigestMentions(append(channel.Mentions, digestMention))
+	})
+}
+
+// topDigestMentions keeps the most important mentions of a channel, the most recent first among
+// mentions of the same priority.
+func topDigestMentions(mentions []*kvstore.DigestMention) []*kvstore.DigestMention {
+	sort.SliceStable(mentions, func(i, j int) bool {
+		if mentions[i].Priority != mentions[j].Priority {
+			return priorityOrder[Priority(mentions[i].Priority)] > priorityOrder[Priority(mentions[j].Priority)]
+		}
+		return mentions[i].CreateAt > mentions[j].CreateAt
+	})
+
+	if len(mentions) > maxDigestExcerpts {
+		mentions = mentions[:maxDigestExcerpts]
+	}
+	return mentions
+}
+
+// SendDueDigests sends the digests whose interval elapsed. It's called periodically by a cluster job.
+func (s *Service) SendDueDigests() {
+	digests, err := s.kvstore.ListDigests()
+	if err != nil {
+		s.client.Log.Error("Failed to list digests", "error", err)
+		return
+	}
+
+	// Digests hold mentions from before the snooze or the pause, so they wait for its end rather
+	// than being dropped
+	if s.DeliveryPaused() {
+		return
+	}
+
+	now := model.GetMillis()
+	for _, pending := range digests {
+		if pending.SendAt > now || s.getSnooze(pending.UserID).Snoozed(now) {
+			continue
+		}
+
+		// Take the digest before sending it, so that mentions arriving meanwhile start a new one
+		// and another server running the job can't send it twice.
+		digest, err := s.kvstore.TakeDigest(pending.UserID)
+		if err != nil {
+			s.client.Log.Error("Failed to take digest", "userId", pending.UserID, "error", err)
+			continue
+		}
+		if digest == nil {
+			continue
+		}
+
+		if err := s.sendDigest(digest); err != nil {
+			s.client.Log.Error("Failed to send digest", "userId", digest.UserID, "error", err)
+			if err := s.restoreDigest(digest); err != nil {
+				s.client.Log.Error("Failed to restore digest", "userId", digest.UserID, "error", err)
+			}
+		}
+	}
+}
+
+// restoreDigest puts back the mentions of a digest that failed to send, merged with those that
+// arrived meanwhile, so that it's retried on the next run.
+func (s *Service) restoreDigest(failed *kvstore.Digest) error {
+	return s.kvstore.UpdateDigest(failed.UserID, func(digest *kvstore.Digest) {
+		if digest.SendAt == 0 || failed.SendAt < digest.SendAt {
+			digest.SendAt = failed.SendAt
+		}
+		if digest.Channels == nil {
+			digest.Channels = map[string]*kvstore.DigestChannel{}
+		}
+
+		for key, failedChannel := range failed.Channels {
+			channel, ok := digest.Channels[key]
+			if !ok {
+				digest.Channels[key] = failedChannel
+				continue
+			}
+			channel.Count += failedChannel.Count
+			channel.Mentions = topDigestMentions(append(channel.Mentions, failedChannel.Mentions...))
+		}
+	})
+}
+
+// sendDigest sends a single message summarizing the mentions of a digest, grouped by channel.
+func (s *Service) sendDigest(digest *kvstore.Digest) error {
+	channels := make([]*kvstore.DigestChannel, 0, len(digest.Channels))
+	count := 0
+	priority := PriorityMin
+	for _, channel := range digest.Channels {
+		channels = append(channels, channel)
+		count += channel.Count
+		for _, mention := range channel.Mentions {
+			if priorityOrder[Priority(mention.Priority)] > priorityOrder[priority] {
+				priority = Priority(mention.Priority)
+			}
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+
+	// The busiest channels first
+	sort.Slice(channels, func(i, j int) bool {
+		if channels[i].Count != channels[j].Count {
+			return channels[i].Count > channels[j].Count
+		}
+		return channels[i].ChannelName < channels[j].ChannelName
+	})
+
+	return s.SendUserNotification(digest.UserID, &Notification{
+		Message: &digestMessage{
+			service:  s,
+			userID:   digest.UserID,
+			channels: channels,
+		},
+		Priority: priority,
+		Title:    s.localizer.LocalizeCount(digest.UserID, digestTitleMessage, count, nil),
+	})
+}
+
+// digestMessage lists the channels of a digest with their mention count and top excerpts.
+type digestMessage struct {
+	service  *Service
+	userID   string
+	channels []*kvstore.DigestChannel
+}
+
+func (m *digestMessage) Render(format Format) string {
+	f := getFormatter(format)
+
+	var lines []string
+	for _, channel := range m.channels {
+		label := channel.Channel
+		if label == "" && len(channel.Mentions) > 0 {
+			// Direct message channels have no display name
+			label = "@" + channel.Mentions[0].SenderUsername
+		}
+
+		count := m.service.localizer.LocalizeCount(m.userID, digestMentionCountMessage, channel.Count, nil)
+		lines = append(lines, f.bold(label)+f.escape(": "+count))
+
+		for _, mention := range channel.Mentions {
+			sender := "@" + mention.SenderUsername
+			if mention.Permalink != "" {
+				sender = f.link(sender, mention.Permalink)
+			} else {
+				sender = f.escape(sender)
+			}
+			lines = append(lines, f.escape("• ")+sender+f.escape(": "+mention.Excerpt))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
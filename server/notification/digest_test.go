@@ -0,0 +1,166 @@
+package notification
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDigestSettingsIncludes(t *testing.T) {
+	assert := assert.New(t)
+
+	channelMention := &Mention{Type: MentionTypeChannel}
+	dmMention := &Mention{Type: MentionTypeDM}
+	urgentMention := &Mention{Type: MentionTypeChannel, PostPriority: PostPriorityUrgent}
+
+	disabled := digestSettings{immediateDMs: true}
+	assert.False(disabled.includes(channelMention))
+
+	enabled := digestSettings{interval: 15 * time.Minute, immediateDMs: true}
+	assert.True(enabled.includes(channelMention))
+	assert.False(enabled.includes(dmMention))
+	assert.False(enabled.includes(urgentMention))
+
+	withDMs := digestSettings{interval: 15 * time.Minute}
+	assert.True(withDMs.includes(dmMention))
+	assert.False(withDMs.includes(urgentMention))
+}
+
+func TestTopDigestMentions(t *testing.T) {
+	assert := assert.New(t)
+
+	mentions := topDigestMentions([]*kvstore.DigestMention{
+		{PostID: "old", Priority: string(PriorityDefault), CreateAt: 1},
+		{PostID: "important", Priority: string(PriorityHigh), CreateAt: 2},
+		{PostID: "new", Priority: string(PriorityDefault), CreateAt: 4},
+		{PostID: "newer", Priority: string(PriorityDefault), CreateAt: 5},
+	})
+
+	postIDs := []string{}
+	for _, mention := range mentions {
+		postIDs = append(postIDs, mention.PostID)
+	}
+	assert.Equal([]string{"important", "newer", "new"}, postIDs)
+}
+
+func TestDigestMessageRender(t *testing.T) {
+	message := &digestMessage{
+		service: &Service{},
+		userID:  "user",
+		channels: []*kvstore.DigestChannel{
+			{
+				Channel: "Town Square",
+				Count:   4,
+				Mentions: []*kvstore.DigestMention{
+					{SenderUsername: "alice", Excerpt: "ship it!", Permalink: "https://example.com/team/pl/1"},
+				},
+			},
+			{
+				Count: 1,
+				Mentions: []*kvstore.DigestMention{
+					{SenderUsername: "bob", Excerpt: "lunch?"},
+				},
+			},
+		},
+	}
+
+	for format, expected := range map[Format]string{
+		FormatPlain:    "Town Square: 4 mentions\n• @alice: ship it!\n@bob: 1 mention\n• @bob: lunch?",
+		FormatMarkdown: "**Town Square**: 4 mentions\n• [@alice](https://example.com/team/pl/1): ship it!\n**@bob**: 1 mention\n• @bob: lunch?",
+	} {
+		t.Run(string(format), func(t *testing.T) {
+			assert.Equal(t, expected, message.Render(format))
+		})
+	}
+}
+
+func TestSendDueDigestsWaitsForSnoozeAndPause(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &plugintest.API{}
+	api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	paused := true
+	store := &memoryKVStore{
+		digest: &kvstore.Digest{
+			UserID:   "user",
+			SendAt:   1,
+			Channels: map[string]*kvstore.DigestChannel{"town-square": {ChannelName: "town-square", Count: 1}},
+		},
+		snooze: &kvstore.Snooze{UserID: "user", Until: model.GetMillis() + time.Hour.Milliseconds()},
+	}
+	service := NewService(pluginapi.NewClient(api, &plugintest.Driver{}), memoryPreferences{}, store, nil, func() *Config {
+		return &Config{DeliveryPaused: paused}
+	}, nil)
+
+	// The digest is kept while delivery is paused or the user is snoozed
+	service.SendDueDigests()
+	assert.NotNil(store.digest)
+
+	paused = false
+	service.SendDueDigests()
+	assert.NotNil(store.digest)
+
+	// And sent once the snooze ends
+	store.snooze = nil
+	service.SendDueDigests()
+	assert.Nil(store.digest)
+}
+
+func TestSendDueDigestsRestoresFailures(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	failing := "generic://" + strings.TrimPrefix(server.URL, "http://") + "/failing?disabletls=yes"
+
+	api := &plugintest.API{}
+	api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	store := &memoryKVStore{
+		digest: &kvstore.Digest{
+			UserID: "user",
+			SendAt: 1,
+			Channels: map[string]*kvstore.DigestChannel{
+				"team/town-square": {ChannelName: "town-square", Count: 2, Mentions: []*kvstore.DigestMention{{PostID: "post1"}}},
+			},
+		},
+		deliveries: map[string]string{},
+	}
+	preferences := memoryPreferences{prefstore.NotificationServices: failing}
+	service := NewService(pluginapi.NewClient(api, &plugintest.Driver{}), preferences, store, nil, func() *Config { return nil }, nil)
+
+	// The digest that failed to send is kept for the next run
+	service.SendDueDigests()
+	assert.NotEmpty(store.deliveries[targetID(failing)])
+	if assert.NotNil(store.digest) {
+		assert.Equal(int64(1), store.digest.SendAt)
+		assert.Equal(2, store.digest.Channels["team/town-square"].Count)
+	}
+
+	// Merged with the mentions that arrived meanwhile
+	failed := store.digest
+	store.digest = &kvstore.Digest{
+		UserID: "user",
+		SendAt: 100,
+		Channels: map[string]*kvstore.DigestChannel{
+			"team/town-square": {ChannelName: "town-square", Count: 1, Mentions: []*kvstore.DigestMention{{PostID: "post2", CreateAt: 2}}},
+			"team/dev":         {ChannelName: "dev", Count: 1},
+		},
+	}
+	assert.NoError(service.restoreDigest(failed))
+	assert.Equal(int64(1), store.digest.SendAt)
+	assert.Equal(3, store.digest.Channels["team/town-square"].Count)
+	assert.Len(store.digest.Channels["team/town-square"].Mentions, 2)
+	assert.Equal(1, store.digest.Channels["team/dev"].Count)
+}
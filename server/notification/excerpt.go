@@ -0,0 +1,335 @@
+package notification
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/shared/markdown"
+)
+
+// DefaultExcerptLength is the number of characters of a post included in a notification by default.
+const DefaultExcerptLength = 100
+
+const ellipsis = "…"
+
+var (
+	headingPattern          = regexp.MustCompile(`^#{1,6}\s+`)
+	emphasisPattern         = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__|~~(.+?)~~`)
+	emphasisMarkerPattern   = regexp.MustCompile(`\*\*|__|~~`)
+	userReferencePattern    = regexp.MustCompile(`@[A-Za-z0-9.\-_]+`)
+	channelReferencePattern = regexp.MustCompile(`~[a-z0-9\-_]+`)
+	whitespacePattern       = regexp.MustCompile(`\s+`)
+)
+
+type segmentStyle int
+
+const (
+	stylePlain segmentStyle = iota
+	styleBold
+	styleStrike
+	styleCode
+	styleLink
+)
+
+// segment is a run of text of an excerpt sharing the same style.
+type segment struct {
+	text  string
+	style segmentStyle
+
+	// url is the destination of link segments.
+	url string
+}
+
+// Excerpt is a short, single line version of a post message that keeps enough of its formatting
+// to be rendered in each service's format.
+type Excerpt struct {
+	segments []segment
+}
+
+// String returns the excerpt as plain text.
+func (e *Excerpt) String() string {
+	return e.Format(FormatPlain)
+}
+
+// ExcerptBuilder turns post messages into short excerpts suitable for notifications.
+type ExcerptBuilder struct {
+	// MaxLength is the maximum number of characters in an excerpt, including the ellipsis.
+	MaxLength int
+
+	// ResolveUser returns the display name of the user with the given username, if any.
+	ResolveUser func(username string) (string, bool)
+
+	// ResolveChannel returns the display name of the channel with the given name, if any.
+	ResolveChannel func(channelName string) (string, bool)
+}
+
+// Build parses the markdown of the message, resolves the @user and ~channel references and
+// truncates the result to MaxLength characters without splitting any of them.
+func (b *ExcerptBuilder) Build(message string) *Excerpt {
+	segments := parseSegments(message)
+
+	for i := range segments {
+		if segments[i].style != styleCode {
+			segments[i].text = b.resolveReferences(segments[i].text)
+		}
+	}
+
+	maxLength := b.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultExcerptLength
+	}
+
+	return &Excerpt{segments: truncateSegments(normalizeSegments(segments), maxLength)}
+}
+
+// parseSegments returns the text content of a markdown message split by style.
+func parseSegments(message string) []segment {
+	var segments []segment
+	paragraphStart := false
+
+	markdown.Inspect(message, func(node any) bool {
+		switch node := node.(type) {
+		case *markdown.Paragraph:
+			segments = append(segments, segment{text: " "})
+			paragraphStart = true
+		case *markdown.FencedCode:
+			segments = append(segments, segment{text: " "}, segment{text: node.Code(), style: styleCode})
+		case *markdown.IndentedCode:
+			segments = append(segments, segment{text: " "}, segment{text: node.Code(), style: styleCode})
+		case *markdown.Text:
+			text := node.Text
+			if paragraphStart {
+				text = headingPattern.ReplaceAllString(text, "")
+			}
+			segments = append(segments, parseEmphasis(text)...)
+		case *markdown.CodeSpan:
+			segments = append(segments, segment{text: node.Code, style: styleCode})
+		case *markdown.SoftLineBreak, *markdown.HardLineBreak:
+			segments = append(segments, segment{text: " "})
+		case *markdown.Emoji:
+			segments = append(segments, segment{text: emojiText(node.Name)})
+		case *markdown.InlineImage, *markdown.ReferenceImage:
+			segments = append(segments, segment{text: "🖼"})
+			return false
+		case *markdown.InlineLink:
+			segments = append(segments, segment{text: inlineText(node.Children), style: styleLink, url: node.Destination()})
+			return false
+		case *markdown.ReferenceLink:
+			segments = append(segments, segment{text: inlineText(node.Children), style: styleLink, url: node.Destination()})
+			return false
+		case *markdown.Autolink:
+			segments = append(segments, segment{text: inlineText(node.Children), style: styleLink, url: node.Destination()})
+			return false
+		}
+
+		if _, ok := node.(markdown.Inline); ok {
+			paragraphStart = false
+		}
+		return true
+	})
+
+	return segments
+}
+
+// parseEmphasis splits text on the bold and strikethrough markers, which the markdown parser
+// leaves in the text. Unmatched markers are dropped.
+func parseEmphasis(text string) []segment {
+	var segments []segment
+	last := 0
+	for _, match := range emphasisPattern.FindAllStringSubmatchIndex(text, -1) {
+		segments = append(segments, segment{text: emphasisMarkerPattern.ReplaceAllString(text[last:match[0]], "")})
+
+		switch {
+		case match[2] >= 0:
+			segments = append(segments, segment{text: text[match[2]:match[3]], style: styleBold})
+		case match[4] >= 0:
+			segments = append(segments, segment{text: text[match[4]:match[5]], style: styleBold})
+		default:
+			segments = append(segments, segment{text: text[match[6]:match[7]], style: styleStrike})
+		}
+		last = match[1]
+	}
+
+	return append(segments, segment{text: emphasisMarkerPattern.ReplaceAllString(text[last:], "")})
+}
+
+// inlineText returns the text content of inlines, such as the children of a link.
+func inlineText(inlines []markdown.Inline) string {
+	var text strings.Builder
+	for _, inline := range inlines {
+		markdown.InspectInline(inline, func(inline markdown.Inline) bool {
+			switch inline := inline.(type) {
+			case *markdown.Text:
+				text.WriteString(emphasisMarkerPattern.ReplaceAllString(inline.Text, ""))
+			case *markdown.CodeSpan:
+				text.WriteString(inline.Code)
+			case *markdown.Emoji:
+				text.WriteString(emojiText(inline.Name))
+			case *markdown.SoftLineBreak, *markdown.HardLineBreak:
+				text.WriteString(" ")
+			}
+			return true
+		})
+	}
+	return text.String()
+}
+
+// normalizeSegments puts the excerpt on a single line, collapsing the whitespace between and
+// within segments and dropping empty ones.
+func normalizeSegments(segments []segment) []segment {
+	var normalized []segment
+	spacePending := false
+
+	for _, s := range segments {
+		s.text = whitespacePattern.ReplaceAllString(s.text, " ")
+		if strings.HasPrefix(s.text, " ") {
+			spacePending = true
+		}
+		trailingSpace := strings.HasSuffix(s.text, " ")
+
+		s.text = strings.TrimSpace(s.text)
+		if s.text == "" {
+			spacePending = spacePending || trailingSpace
+			continue
+		}
+
+		if spacePending && len(normalized) > 0 {
+			normalized = append(normalized, segment{text: " "})
+		}
+		normalized = append(normalized, s)
+		spacePending = trailingSpace
+	}
+
+	return normalized
+}
+
+// truncateSegments shortens the segments to at most maxLength characters in total, counted as
+// user perceived characters so that accented letters and emoji sequences are never split.
+func truncateSegments(segments []segment, maxLength int) []segment {
+	total := 0
+	for _, s := range segments {
+		total += len(clusterBoundaries(s.text))
+	}
+	if total <= maxLength {
+		return segments
+	}
+
+	// Keep room for the ellipsis
+	remaining := maxLength - 1
+	var truncated []segment
+	for _, s := range segments {
+		boundaries := clusterBoundaries(s.text)
+		if len(boundaries) > remaining {
+			if remaining > 0 {
+				s.text = strings.TrimRightFunc(s.text[:boundaries[remaining]], unicode.IsSpace)
+				truncated = append(truncated, s)
+			}
+			break
+		}
+
+		truncated = append(truncated, s)
+		remaining -= len(boundaries)
+	}
+
+	return append(truncated, segment{text: ellipsis})
+}
+
+// emojiText returns the unicode emoji for a system emoji name, or the :name: shortcode otherwise.
+func emojiText(name string) string {
+	id, ok := model.GetSystemEmojiId(name)
+	if !ok {
+		return ":" + name + ":"
+	}
+
+	var emoji strings.Builder
+	for _, codepoint := range strings.Split(id, "-") {
+		r, err := strconv.ParseInt(codepoint, 16, 32)
+		if err != nil {
+			return ":" + name + ":"
+		}
+		emoji.WriteRune(rune(r))
+	}
+	return emoji.String()
+}
+
+// resolveReferences replaces @username and ~channel-name references by display names.
+func (b *ExcerptBuilder) resolveReferences(text string) string {
+	if b.ResolveUser != nil {
+		text = userReferencePattern.ReplaceAllStringFunc(text, func(reference string) string {
+			return resolveReference(reference, b.ResolveUser)
+		})
+	}
+
+	if b.ResolveChannel != nil {
+		text = channelReferencePattern.ReplaceAllStringFunc(text, func(reference string) string {
+			return resolveReference(reference, b.ResolveChannel)
+		})
+	}
+
+	return text
+}
+
+// resolveReference resolves a reference such as @alice or ~town-square. Like the mention parser,
+// it retries without trailing punctuation, as a reference may end a sentence.
+func resolveReference(reference string, resolve func(string) (string, bool)) string {
+	prefix, name := reference[:1], reference[1:]
+	for suffix := ""; name != ""; {
+		if displayName, ok := resolve(name); ok && displayName != "" {
+			return prefix + displayName + suffix
+		}
+
+		last := name[len(name)-1]
+		if !strings.ContainsRune(".-_", rune(last)) {
+			break
+		}
+		name, suffix = name[:len(name)-1], string(last)+suffix
+	}
+	return reference
+}
+
+// clusterBoundaries returns the byte offset at which each user perceived character of text starts.
+// It approximates the unicode grapheme cluster rules for the cases found in chat messages:
+// combining marks, variation selectors, emoji modifiers, zero width joiner sequences and flags
+// made of pairs of regional indicators.
+func clusterBoundaries(text string) []int {
+	var boundaries []int
+	var previous rune
+	regionalIndicators := 0
+
+	for i, r := range text {
+		continues := false
+		switch {
+		case i == 0:
+		case unicode.Is(unicode.Mn, r), unicode.Is(unicode.Me, r):
+			continues = true
+		case r == '\u200d', previous == '\u200d':
+			continues = true
+		case r >= '\ufe00' && r <= '\ufe0f':
+			continues = true
+		case r >= 0x1f3fb && r <= 0x1f3ff:
+			continues = true
+		case isRegionalIndicator(r) && regionalIndicators%2 == 1:
+			continues = true
+		}
+
+		if isRegionalIndicator(r) {
+			regionalIndicators++
+		} else {
+			regionalIndicators = 0
+		}
+
+		if !continues {
+			boundaries = append(boundaries, i)
+		}
+		previous = r
+	}
+
+	return boundaries
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
@@ -0,0 +1,88 @@
+package notification
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripMarkdown(t *testing.T) {
+	for name, tc := range map[string]struct {
+		message  string
+		expected string
+	}{
+		"plain text":   {"hello world", "hello world"},
+		"emphasis":     {"this is **very** ~~not~~ important", "this is very not important"},
+		"heading":      {"# Release notes\nAll good", "Release notes All good"},
+		"code span":    {"run `make deploy` now", "run make deploy now"},
+		"code fence":   {"look:\n```go\nfmt.Println(\"hi\")\n```\ndone", "look: fmt.Println(\"hi\") done"},
+		"link":         {"see [the docs](https://example.com/docs)", "see the docs"},
+		"image":        {"![diagram](https://example.com/a.png) attached", "🖼 attached"},
+		"block quote":  {"> quoted\n\nreply", "quoted reply"},
+		"list":         {"- one\n- two", "one two"},
+		"system emoji": {"ship it :+1:", "ship it 👍"},
+		"custom emoji": {"ship it :party-parrot:", "ship it :party-parrot:"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, (&ExcerptBuilder{MaxLength: 1000}).Build(tc.message).String())
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	assert := assert.New(t)
+
+	truncate := func(text string, maxLength int) string {
+		return (&Excerpt{segments: truncateSegments([]segment{{text: text}}, maxLength)}).String()
+	}
+
+	assert.Equal("short", truncate("short", 10))
+	assert.Equal("exactly10!", truncate("exactly10!", 10))
+	assert.Equal("too long…", truncate("too long text", 9))
+	assert.Equal("trailing…", truncate("trailing space here", 10))
+
+	// CJK characters are multibyte and must not be split
+	cjk := truncate(strings.Repeat("日本語のテキスト", 20), 10)
+	assert.True(utf8.ValidString(cjk))
+	assert.Equal("日本語のテキスト日…", cjk)
+
+	// Emoji, including ZWJ sequences, skin tones and flags, count as a single character
+	assert.Equal("👨‍👩‍👧‍👦👍🏽…", truncate("👨‍👩‍👧‍👦👍🏽🇯🇵🇫🇷", 3))
+	assert.Equal("🇯🇵🇫🇷…", truncate("🇯🇵🇫🇷🇺🇸🇬🇧", 3))
+	assert.Equal("🇯🇵🇫🇷🇺🇸🇬🇧", truncate("🇯🇵🇫🇷🇺🇸🇬🇧", 4))
+
+	// Combining accents stay with their letter
+	assert.Equal("café…", truncate("café au lait", 5))
+}
+
+func TestExcerptBuilder(t *testing.T) {
+	assert := assert.New(t)
+
+	builder := &ExcerptBuilder{
+		MaxLength: 40,
+		ResolveUser: func(username string) (string, bool) {
+			if username == "alice" {
+				return "Alice Smith", true
+			}
+			return "", false
+		},
+		ResolveChannel: func(channelName string) (string, bool) {
+			if channelName == "town-square" {
+				return "Town Square", true
+			}
+			return "", false
+		},
+	}
+
+	assert.Equal("@Alice Smith, see ~Town Square.", builder.Build("@alice, see ~town-square.").String())
+	assert.Equal("@Alice Smith. and @unknown", builder.Build("@alice. and @unknown").String())
+	assert.Equal("@Alice Smith 東京で会いましょう 🎉 というメッセージはとても長い…", (&ExcerptBuilder{
+		MaxLength:   40,
+		ResolveUser: builder.ResolveUser,
+	}).Build("**@alice** 東京で会いましょう :tada: というメッセージはとても長いですね、本当に長い").String())
+
+	// Without a length, the default is used
+	assert.Equal(DefaultExcerptLength, utf8.RuneCountInString((&ExcerptBuilder{}).Build(strings.Repeat("あ", 500)).String()))
+}
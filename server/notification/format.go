@@ -0,0 +1,269 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/containrrr/shoutrrr/pkg/types"
+)
+
+// Format is the markup a notification message is written in.
+type Format string
+
+const (
+	// FormatPlain is unformatted text, used by push services such as ntfy, Pushover or Gotify.
+	FormatPlain Format = "plain"
+
+	// FormatMarkdown is the common markdown dialect of Discord, Mattermost, Rocket.Chat and Teams.
+	FormatMarkdown Format = "markdown"
+
+	// FormatSlack is Slack's mrkdwn.
+	FormatSlack Format = "slack"
+
+	// FormatHTML is the HTML subset supported by Telegram, also used for HTML emails.
+	FormatHTML Format = "html"
+
+	// FormatMarkdownV2 is Telegram's MarkdownV2.
+	FormatMarkdownV2 Format = "markdownv2"
+)
+
+// defaultFormats is the format used for each scheme unless the user overrides it. Matrix stays
+// plain as Shoutrrr only sends the plain body of Matrix messages, and emails stay plain unless the
+// user opts into HTML emails.
+var defaultFormats = map[string]Format{
+	"discord":    FormatMarkdown,
+	"googlechat": FormatMarkdown,
+	"mattermost": FormatMarkdown,
+	"rocketchat": FormatMarkdown,
+	"slack":      FormatSlack,
+	"teams":      FormatMarkdown,
+	"telegram":   FormatHTML,
+	"zulip":      FormatMarkdown,
+}
+
+// formatParams are the params telling a scheme how to parse messages in a format.
+var formatParams = map[string]map[Format]types.Params{
+	"telegram": {
+		FormatPlain:      {"parsemode": "None"},
+		FormatHTML:       {"parsemode": "HTML"},
+		FormatMarkdownV2: {"parsemode": "MarkdownV2"},
+	},
+	"smtp": {
+		FormatHTML: {"usehtml": "Yes"},
+	},
+}
+
+// MessageFormats maps schemes to the format their messages are sent in.
+type MessageFormats map[string]Format
+
+// ParseMessageFormats parses a JSON object such as {"telegram": "markdownv2", "smtp": "html"}.
+func ParseMessageFormats(data string) (MessageFormats, error) {
+	formats := MessageFormats{}
+	if strings.TrimSpace(data) == "" {
+		return formats, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &formats); err != nil {
+		return nil, err
+	}
+
+	for scheme, format := range formats {
+		if _, ok := formatters[format]; !ok {
+			return nil, fmt.Errorf("unknown format %q for %s", format, scheme)
+		}
+	}
+
+	return formats, nil
+}
+
+// Format returns the format of a scheme, falling back to the scheme's default.
+func (f MessageFormats) Format(scheme string) Format {
+	if format, ok := f[scheme]; ok {
+		return format
+	}
+	if format, ok := defaultFormats[scheme]; ok {
+		return format
+	}
+	return FormatPlain
+}
+
+// formatter writes styled text in a format.
+type formatter interface {
+	escape(text string) string
+	url(url string) string
+	bold(text string) string
+	strike(text string) string
+	code(text string) string
+	link(text, url string) string
+}
+
+var formatters = map[Format]formatter{
+	FormatPlain:      plainFormatter{},
+	FormatMarkdown:   markdownFormatter{},
+	FormatSlack:      slackFormatter{},
+	FormatHTML:       htmlFormatter{},
+	FormatMarkdownV2: markdownV2Formatter{},
+}
+
+func getFormatter(format Format) formatter {
+	if f, ok := formatters[format]; ok {
+		return f
+	}
+	return plainFormatter{}
+}
+
+// Format renders the excerpt in a format.
+func (e *Excerpt) Format(format Format) string {
+	if e == nil {
+		return ""
+	}
+
+	f := getFormatter(format)
+	var out strings.Builder
+	for _, s := range e.segments {
+		switch s.style {
+		case styleBold:
+			out.WriteString(f.bold(s.text))
+		case styleStrike:
+			out.WriteString(f.strike(s.text))
+		case styleCode:
+			out.WriteString(f.code(s.text))
+		case styleLink:
+			out.WriteString(f.link(s.text, s.url))
+		default:
+			out.WriteString(f.escape(s.text))
+		}
+	}
+	return out.String()
+}
+
+type plainFormatter struct{}
+
+func (plainFormatter) escape(text string) string  { return text }
+func (plainFormatter) url(url string) string      { return url }
+func (plainFormatter) bold(text string) string    { return text }
+func (plainFormatter) strike(text string) string  { return text }
+func (plainFormatter) code(text string) string    { return text }
+func (plainFormatter) link(text, _ string) string { return text }
+
+var markdownReplacer = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`",
+	"[", `\[`, "]", `\]`, "|", `\|`, "<", `\<`, ">", `\>`, "#", `\#`,
+)
+
+type markdownFormatter struct{}
+
+func (markdownFormatter) escape(text string) string { return markdownReplacer.Replace(text) }
+func (markdownFormatter) url(url string) string     { return url }
+
+func (f markdownFormatter) bold(text string) string   { return "**" + f.escape(text) + "**" }
+func (f markdownFormatter) strike(text string) string { return "~~" + f.escape(text) + "~~" }
+func (markdownFormatter) code(text string) string     { return codeSpan(text) }
+
+func (f markdownFormatter) link(text, url string) string {
+	return "[" + f.escape(text) + "](" + strings.NewReplacer("(", "%28", ")", "%29").Replace(url) + ")"
+}
+
+// codeSpan wraps text in enough backticks that the ones it contains don't end the code span.
+func codeSpan(text string) string {
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+var slackReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+type slackFormatter struct{}
+
+func (slackFormatter) escape(text string) string { return slackReplacer.Replace(text) }
+func (slackFormatter) url(url string) string     { return "<" + slackReplacer.Replace(url) + ">" }
+
+func (f slackFormatter) bold(text string) string   { return "*" + f.escape(text) + "*" }
+func (f slackFormatter) strike(text string) string { return "~" + f.escape(text) + "~" }
+
+// Slack has no way of escaping backticks within code.
+func (f slackFormatter) code(text string) string {
+	return "`" + f.escape(strings.ReplaceAll(text, "`", "'")) + "`"
+}
+
+func (f slackFormatter) link(text, url string) string {
+	return "<" + slackReplacer.Replace(url) + "|" + f.escape(strings.ReplaceAll(text, "|", "/")) + ">"
+}
+
+type htmlFormatter struct{}
+
+func (htmlFormatter) escape(text string) string { return html.EscapeString(text) }
+func (htmlFormatter) url(url string) string     { return html.EscapeString(url) }
+
+func (f htmlFormatter) bold(text string) string   { return "<b>" + f.escape(text) + "</b>" }
+func (f htmlFormatter) strike(text string) string { return "<s>" + f.escape(text) + "</s>" }
+func (f htmlFormatter) code(text string) string   { return "<code>" + f.escape(text) + "</code>" }
+
+func (f htmlFormatter) link(text, url string) string {
+	return `<a href="` + f.escape(url) + `">` + f.escape(text) + "</a>"
+}
+
+// markdownV2Replacer escapes the characters Telegram requires to be escaped outside of entities.
+var markdownV2Replacer = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`,
+	"`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`,
+	"{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+type markdownV2Formatter struct{}
+
+func (markdownV2Formatter) escape(text string) string { return markdownV2Replacer.Replace(text) }
+func (markdownV2Formatter) url(url string) string     { return markdownV2Replacer.Replace(url) }
+
+func (f markdownV2Formatter) bold(text string) string   { return "*" + f.escape(text) + "*" }
+func (f markdownV2Formatter) strike(text string) string { return "~" + f.escape(text) + "~" }
+
+// Only backticks and backslashes need escaping within code entities.
+func (markdownV2Formatter) code(text string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(text) + "`"
+}
+
+// Only parentheses and backslashes need escaping within link destinations.
+func (f markdownV2Formatter) link(text, url string) string {
+	return "[" + f.escape(text) + "](" + strings.NewReplacer(`\`, `\\`, ")", `\)`).Replace(url) + ")"
+}
+
+// Message is the content of a notification, rendered in the format of each of the user's services.
+type Message interface {
+	Render(format Format) string
+}
+
+// Text is a plain text message, escaped for the services that use markup.
+type Text string
+
+// Render escapes the text for the format.
+func (t Text) Render(format Format) string {
+	return getFormatter(format).escape(string(t))
+}
+
+// renderForScheme renders a message for a scheme. For HTML emails, it also returns the plain text
+// alternative to send alongside the HTML body.
+func renderForScheme(message Message, scheme string, format Format) (body, plain string) {
+	body = message.Render(format)
+	if scheme == "smtp" && format == FormatHTML {
+		return emailHTML(body), message.Render(FormatPlain)
+	}
+	return body, ""
+}
+
+// emailHTML turns a message rendered as HTML into an email body, keeping its line breaks.
+func emailHTML(message string) string {
+	return strings.ReplaceAll(message, "\n", "<br>\n")
+}
+
+// escapeTemplateDelimiters turns text into a Go template that outputs it unchanged.
+func escapeTemplateDelimiters(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
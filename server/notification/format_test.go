@@ -0,0 +1,77 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExcerptFormat(t *testing.T) {
+	excerpt := (&ExcerptBuilder{}).Build("**Deploy** ~~today~~ `make <all>` see [docs](https://example.com/a_(b)) & 1.5!")
+
+	for format, expected := range map[Format]string{
+		FormatPlain:      "Deploy today make <all> see docs & 1.5!",
+		FormatMarkdown:   "**Deploy** ~~today~~ `make <all>` see [docs](https://example.com/a_%28b%29) & 1.5!",
+		FormatSlack:      "*Deploy* ~today~ `make &lt;all&gt;` see <https://example.com/a_(b)|docs> &amp; 1.5!",
+		FormatHTML:       `<b>Deploy</b> <s>today</s> <code>make &lt;all&gt;</code> see <a href="https://example.com/a_(b)">docs</a> &amp; 1.5!`,
+		FormatMarkdownV2: "*Deploy* ~today~ `make <all>` see [docs](https://example.com/a_(b\\)) & 1\\.5\\!",
+	} {
+		t.Run(string(format), func(t *testing.T) {
+			assert.Equal(t, expected, excerpt.Format(format))
+		})
+	}
+
+	var missing *Excerpt
+	assert.Equal(t, "", missing.Format(FormatHTML))
+}
+
+func TestMessageFormats(t *testing.T) {
+	assert := assert.New(t)
+
+	formats, err := ParseMessageFormats(`{"telegram": "markdownv2", "smtp": "html"}`)
+	assert.Nil(err)
+	assert.Equal(FormatMarkdownV2, formats.Format("telegram"))
+	assert.Equal(FormatHTML, formats.Format("smtp"))
+	assert.Equal(FormatSlack, formats.Format("slack"))
+	assert.Equal(FormatPlain, formats.Format("ntfy"))
+
+	formats, err = ParseMessageFormats("")
+	assert.Nil(err)
+	assert.Equal(FormatHTML, formats.Format("telegram"))
+
+	_, err = ParseMessageFormats(`{"telegram": "bbcode"}`)
+	assert.NotNil(err)
+}
+
+func TestRenderForScheme(t *testing.T) {
+	assert := assert.New(t)
+
+	message := Text("Ping <everyone>\nin ~town-square.")
+
+	body, plain := renderForScheme(message, "telegram", FormatMarkdownV2)
+	assert.Equal("Ping <everyone\\>\nin \\~town\\-square\\.", body)
+	assert.Equal("", plain)
+
+	body, plain = renderForScheme(message, "smtp", FormatHTML)
+	assert.Equal("Ping &lt;everyone&gt;<br>\nin ~town-square.", body)
+	assert.Equal("Ping <everyone>\nin ~town-square.", plain)
+}
+
+func TestRenderTemplateFormats(t *testing.T) {
+	assert := assert.New(t)
+
+	data := &TemplateData{SenderUsername: "alice_b", Channel: "R&D", Permalink: "https://example.com/team/pl/post"}
+	excerpt := (&ExcerptBuilder{}).Build("ship **it**!")
+
+	message, err := RenderTemplate(DefaultMessageTemplate, formatTemplateData(data, excerpt, nil, FormatHTML), FormatHTML)
+	assert.Nil(err)
+	assert.Equal("You were mentioned by @alice_b in R&amp;D: ship <b>it</b>!\nhttps://example.com/team/pl/post", message)
+
+	message, err = RenderTemplate(DefaultMessageTemplate, formatTemplateData(data, excerpt, nil, FormatMarkdownV2), FormatMarkdownV2)
+	assert.Nil(err)
+	assert.Equal("You were mentioned by @alice\\_b in R&D: ship *it*\\!\nhttps://example\\.com/team/pl/post", message)
+
+	message, err = RenderTemplate(DefaultMessageTemplate, formatTemplateData(data, excerpt, nil, FormatSlack), FormatSlack)
+	assert.Nil(err)
+	assert.Equal("You were mentioned by @alice_b in R&amp;D: ship *it*!\n<https://example.com/team/pl/post>", message)
+}
@@ -0,0 +1,9 @@
+package notification
+
+// linkParamKeys maps the schemes that can open a URL when their notification is clicked to the
+// param holding that URL. Shoutrrr doesn't expose Pushover's url or Gotify's extras, so those
+// services only get the link in the message body.
+var linkParamKeys = map[string]string{
+	"ntfy": "click",
+	"bark": "url",
+}
@@ -0,0 +1,177 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/containrrr/shoutrrr/pkg/types"
+)
+
+// Priority is how urgently a notification should be delivered, independent of the target service.
+type Priority string
+
+const (
+	PriorityMin     Priority = "min"
+	PriorityLow     Priority = "low"
+	PriorityDefault Priority = "default"
+	PriorityHigh    Priority = "high"
+	PriorityMax     Priority = "max"
+)
+
+var priorityOrder = map[Priority]int{
+	PriorityMin:     0,
+	PriorityLow:     1,
+	PriorityDefault: 2,
+	PriorityHigh:    3,
+	PriorityMax:     4,
+}
+
+// Mention types as reported by the plugin's mention parser.
+const (
+	MentionTypeGM      = "gm"
+	MentionTypeThread  = "thread"
+	MentionTypeComment = "comment"
+	MentionTypeChannel = "channel"
+	MentionTypeDM      = "dm"
+	MentionTypeKeyword = "keyword"
+	MentionTypeGroup   = "group"
+
+	// MentionTypeSubscription is a post forwarded by one of the user's channel subscriptions.
+	MentionTypeSubscription = "subscription"
+
+	// MentionTypeWatch is a post matching one of the user's watch rules.
+	MentionTypeWatch = "watch"
+
+	// MentionTypeActivity is a post in a channel the user gets push notifications for every post of.
+	MentionTypeActivity = "activity"
+)
+
+// Post priorities as set in the Mattermost message priority metadata.
+const (
+	PostPriorityImportant = "important"
+	PostPriorityUrgent    = "urgent"
+)
+
+// priorityForMention returns the priority a notification is sent with, given how the user was
+// mentioned and the priority the sender gave the post.
+func priorityForMention(mentionType, postPriority string) Priority {
+	var priority Priority
+	switch mentionType {
+	case MentionTypeDM, MentionTypeKeyword, MentionTypeGroup:
+		priority = PriorityHigh
+	case MentionTypeGM, MentionTypeComment, MentionTypeChannel, MentionTypeWatch:
+		priority = PriorityDefault
+	case MentionTypeThread, MentionTypeSubscription, MentionTypeActivity:
+		priority = PriorityLow
+	default:
+		priority = PriorityDefault
+	}
+
+	switch postPriority {
+	case PostPriorityUrgent:
+		priority = PriorityMax
+	case PostPriorityImportant:
+		if priorityOrder[priority] < priorityOrder[PriorityHigh] {
+			priority = PriorityHigh
+		}
+	}
+
+	return priority
+}
+
+// PriorityMapping maps a Shoutrrr scheme and a priority to the params sent to that service.
+type PriorityMapping map[string]map[Priority]types.Params
+
+// defaultPriorityMapping covers the services that have some notion of priority or urgency.
+var defaultPriorityMapping = PriorityMapping{
+	"ntfy": {
+		PriorityMin:     {"priority": "1"},
+		PriorityLow:     {"priority": "2"},
+		PriorityDefault: {"priority": "3"},
+		PriorityHigh:    {"priority": "4"},
+		PriorityMax:     {"priority": "5"},
+	},
+	"pushover": {
+		PriorityMin:     {"priority": "-2"},
+		PriorityLow:     {"priority": "-1"},
+		PriorityDefault: {"priority": "0"},
+		PriorityHigh:    {"priority": "1"},
+		// Pushover's emergency priority (2) requires retry and expire params, which Shoutrrr
+		// doesn't support, so high is the most we can ask for.
+		PriorityMax: {"priority": "1"},
+	},
+	"gotify": {
+		PriorityMin:     {"priority": "0"},
+		PriorityLow:     {"priority": "2"},
+		PriorityDefault: {"priority": "5"},
+		PriorityHigh:    {"priority": "8"},
+		PriorityMax:     {"priority": "10"},
+	},
+	"opsgenie": {
+		PriorityMin:     {"priority": "P5"},
+		PriorityLow:     {"priority": "P4"},
+		PriorityDefault: {"priority": "P3"},
+		PriorityHigh:    {"priority": "P2"},
+		PriorityMax:     {"priority": "P1"},
+	},
+	"telegram": {
+		PriorityMin:     {"notification": "No"},
+		PriorityLow:     {"notification": "No"},
+		PriorityDefault: {"notification": "Yes"},
+		PriorityHigh:    {"notification": "Yes"},
+		PriorityMax:     {"notification": "Yes"},
+	},
+}
+
+// ParsePriorityMapping parses a JSON priority mapping such as {"ntfy": {"high": {"priority": "5"}}}.
+// An empty string is a valid, empty mapping.
+func ParsePriorityMapping(data string) (PriorityMapping, error) {
+	mapping := PriorityMapping{}
+	if data == "" {
+		return mapping, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &mapping); err != nil {
+		return nil, fmt.Errorf("invalid priority mapping: %w", err)
+	}
+
+	for scheme, levels := range mapping {
+		for priority := range levels {
+			if _, ok := priorityOrder[priority]; !ok {
+				return nil, fmt.Errorf("invalid priority %q for scheme %q", priority, scheme)
+			}
+		}
+	}
+
+	return mapping, nil
+}
+
+// Merge returns a new mapping with the params of overrides layered on top of m.
+func (m PriorityMapping) Merge(overrides PriorityMapping) PriorityMapping {
+	merged := PriorityMapping{}
+	for _, mapping := range []PriorityMapping{m, overrides} {
+		for scheme, levels := range mapping {
+			if merged[scheme] == nil {
+				merged[scheme] = map[Priority]types.Params{}
+			}
+			for priority, params := range levels {
+				if merged[scheme][priority] == nil {
+					merged[scheme][priority] = types.Params{}
+				}
+				for key, value := range params {
+					merged[scheme][priority][key] = value
+				}
+			}
+		}
+	}
+	return merged
+}
+
+// Params returns a copy of the params to send to the given scheme for the given priority.
+func (m PriorityMapping) Params(scheme string, priority Priority) types.Params {
+	params := types.Params{}
+	for key, value := range m[scheme][priority] {
+		params[key] = value
+	}
+	return params
+}
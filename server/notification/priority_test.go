@@ -0,0 +1,69 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/containrrr/shoutrrr/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriorityForMention(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(PriorityHigh, priorityForMention(MentionTypeDM, ""))
+	assert.Equal(PriorityHigh, priorityForMention(MentionTypeKeyword, ""))
+	assert.Equal(PriorityDefault, priorityForMention(MentionTypeChannel, ""))
+	assert.Equal(PriorityLow, priorityForMention(MentionTypeThread, ""))
+	assert.Equal(PriorityHigh, priorityForMention(MentionTypeThread, PostPriorityImportant))
+	assert.Equal(PriorityHigh, priorityForMention(MentionTypeDM, PostPriorityImportant))
+	assert.Equal(PriorityMax, priorityForMention(MentionTypeThread, PostPriorityUrgent))
+}
+
+func TestParsePriorityMapping(t *testing.T) {
+	assert := assert.New(t)
+
+	mapping, err := ParsePriorityMapping("")
+	assert.Nil(err)
+	assert.Empty(mapping)
+
+	mapping, err = ParsePriorityMapping(`{"ntfy": {"high": {"priority": "5"}}}`)
+	assert.Nil(err)
+	assert.Equal(types.Params{"priority": "5"}, mapping["ntfy"][PriorityHigh])
+
+	_, err = ParsePriorityMapping(`{"ntfy": {"critical": {"priority": "5"}}}`)
+	assert.NotNil(err)
+
+	_, err = ParsePriorityMapping(`{"ntfy": `)
+	assert.NotNil(err)
+}
+
+func TestPriorityMappingMerge(t *testing.T) {
+	assert := assert.New(t)
+
+	admin := PriorityMapping{
+		"ntfy": {PriorityHigh: {"priority": "5", "tags": "warning"}},
+	}
+	user := PriorityMapping{
+		"ntfy":    {PriorityHigh: {"priority": "3"}},
+		"generic": {PriorityMax: {"title": "Urgent"}},
+	}
+
+	merged := defaultPriorityMapping.Merge(admin).Merge(user)
+
+	assert.Equal(types.Params{"priority": "3", "tags": "warning"}, merged.Params("ntfy", PriorityHigh))
+	assert.Equal(types.Params{"priority": "1"}, merged.Params("ntfy", PriorityMin))
+	assert.Equal(types.Params{"title": "Urgent"}, merged.Params("generic", PriorityMax))
+	assert.Equal(types.Params{}, merged.Params("discord", PriorityMax))
+
+	// Merging must not modify the built-in defaults
+	assert.Equal(types.Params{"priority": "4"}, defaultPriorityMapping.Params("ntfy", PriorityHigh))
+}
+
+func TestGetScheme(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("ntfy", getScheme("ntfy://ntfy.sh/topic"))
+	assert.Equal("generic", getScheme("generic+https://example.com/hook"))
+	assert.Equal("telegram", getScheme("Telegram://token@telegram?chats=@chan"))
+	assert.Equal("", getScheme("not a url"))
+}
@@ -0,0 +1,259 @@
+package notification
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+var (
+	coalescedTitleMessage = &i18n.Message{
+		ID:    "notification.coalesced.title",
+		One:   "{{.Count}} more notification",
+		Other: "{{.Count}} more notifications",
+	}
+	coalescedChannelMessage = &i18n.Message{
+		ID:    "notification.coalesced.channel",
+		One:   "{{.Count}} more mention in ~{{.Channel}}",
+		Other: "{{.Count}} more mentions in ~{{.Channel}}",
+	}
+	coalescedDMMessage = &i18n.Message{
+		ID:    "notification.coalesced.dm",
+		One:   "{{.Count}} more message from @{{.Sender}}",
+		Other: "{{.Count}} more messages from @{{.Sender}}",
+	}
+)
+
+// RateLimits are the number of notifications per minute a user can receive, in total and on each
+// of their targets. Zero means no limit.
+type RateLimits struct {
+	User   int
+	Target int
+}
+
+// getRateLimits returns the user's rate limits, which can be lower but not higher than the admin's.
+func (s *Service) getRateLimits(userID string) RateLimits {
+	var limits RateLimits
+	if config := s.getConfig(); config != nil {
+		limits = config.RateLimits
+	}
+
+	return RateLimits{
+		User:   s.getRateLimit(userID, prefstore.UserRateLimit, limits.User),
+		Target: s.getRateLimit(userID, prefstore.TargetRateLimit, limits.Target),
+	}
+}
+
+func (s *Service) getRateLimit(userID, preference string, adminLimit int) int {
+	if adminLimit < 0 {
+		adminLimit = 0
+	}
+
+	limitStr, err := s.preferences.GetPreference(userID, preference)
+	if err != nil {
+		s.client.Log.Warn("Failed to get user rate limit", "userId", userID, "preference", preference, "error", err)
+		return adminLimit
+	}
+
+	return capRateLimit(adminLimit, limitStr)
+}
+
+// capRateLimit returns the user's limit if they set one below the admin's, the admin's otherwise.
+func capRateLimit(adminLimit int, userLimitStr string) int {
+	userLimit, err := strconv.Atoi(userLimitStr)
+	if err != nil || userLimit <= 0 {
+		return adminLimit
+	}
+	if adminLimit > 0 && userLimit > adminLimit {
+		return adminLimit
+	}
+	return userLimit
+}
+
+// targetID identifies one of a user's targets in the KV store without storing its credentials.
+func targetID(serviceURL string) string {
+	return prefstore.TargetID(serviceURL)
+}
+
+// takeToken returns whether a notification is within the rate limit of key. The limits are a
+// safeguard, so notifications are sent when the KV store fails.
+func (s *Service) takeToken(key string, perMinute int) bool {
+	if perMinute <= 0 {
+		return true
+	}
+
+	taken, err := s.kvstore.TakeRateLimitToken(key, perMinute, time.Minute)
+	if err != nil {
+		s.client.Log.Warn("Failed to check rate limit", "key", key, "error", err)
+		return true
+	}
+	return taken
+}
+
+func (s *Service) takeUserToken(userID string, limits RateLimits) bool {
+	return s.takeToken(userID, limits.User)
+}
+
+func (s *Service) takeTargetToken(userID, serviceURL string, limits RateLimits) bool {
+	return s.takeToken(userID+"-"+targetID(serviceURL), limits.Target)
+}
+
+// coalesce counts a notification held back by the rate limits on some of the user's targets, so
+// that a follow-up summarizes them once the limits allow it.
+func (s *Service) coalesce(userID string, serviceURLs []string, channel string) {
+	err := s.kvstore.UpdateCoalescedNotifications(userID, func(coalesced *kvstore.CoalescedNotifications) {
+		for _, serviceURL := range serviceURLs {
+			id := targetID(serviceURL)
+			if coalesced.Targets[id] == nil {
+				coalesced.Targets[id] = map[string]int{}
+			}
+			coalesced.Targets[id][channel]++
+		}
+	})
+	if err != nil {
+		s.client.Log.Error("Failed to coalesce rate limited notification", "userId", userID, "error", err)
+	}
+}
+
+// SendCoalescedNotifications sends a follow-up to each target that held back notifications, once
+// the user's rate limits allow it. It's called periodically by a cluster job.
+func (s *Service) SendCoalescedNotifications() {
+	list, err := s.kvstore.ListCoalescedNotifications()
+	if err != nil {
+		s.client.Log.Error("Failed to list coalesced notifications", "error", err)
+		return
+	}
+
+	for _, coalesced := range list {
+		s.sendCoalescedNotifications(coalesced)
+	}
+}
+
+func (s *Service) sendCoalescedNotifications(coalesced *kvstore.CoalescedNotifications) {
+	userID := coalesced.UserID
+
+	// The follow-ups wait for the end of the snooze or the pause rather than being dropped
+	if s.DeliveryPaused() || s.getSnooze(userID).Snoozed(model.GetMillis()) {
+		return
+	}
+
+	services, err := s.getUserServices(userID)
+	if err != nil {
+		return
+	}
+
+	limits := s.getRateLimits(userID)
+	if !s.takeUserToken(userID, limits) {
+		return
+	}
+
+	mapping := s.getPriorityMapping(userID)
+	formats := s.getMessageFormats(userID)
+
+	// Targets the user removed or disabled are forgotten, and the others once their follow-up is
+	// sent. A follow-up that fails is kept for the next run.
+	done := map[string]map[string]int{}
+	for id, channels := range coalesced.Targets {
+		done[id] = channels
+	}
+	for _, serviceURL := range services {
+		id := targetID(serviceURL)
+		channels := coalesced.Targets[id]
+		if len(channels) == 0 {
+			continue
+		}
+		delete(done, id)
+		if !s.takeTargetToken(userID, serviceURL, limits) {
+			continue
+		}
+
+		count := 0
+		for _, channelCount := range channels {
+			count += channelCount
+		}
+
+		notification := &Notification{
+			Message:  &coalescedMessage{service: s, userID: userID, channels: channels},
+			Priority: PriorityDefault,
+			Title:    s.localizer.LocalizeCount(userID, coalescedTitleMessage, count, nil),
+		}
+		err := s.sendToService(serviceURL, notification, mapping, formats)
+		s.recordDelivery(userID, serviceURL, err)
+		if err != nil {
+			s.client.Log.Error("Failed to send coalesced notifications", "userId", userID, "service", serviceURL, "error", err)
+			continue
+		}
+		done[id] = channels
+	}
+
+	// Notifications held back meanwhile are kept for the next follow-up
+	err = s.kvstore.UpdateCoalescedNotifications(userID, func(current *kvstore.CoalescedNotifications) {
+		for id, channels := range done {
+			if current.Targets[id] == nil {
+				continue
+			}
+			for channel, count := range channels {
+				if current.Targets[id][channel] -= count; current.Targets[id][channel] <= 0 {
+					delete(current.Targets[id], channel)
+				}
+			}
+			if len(current.Targets[id]) == 0 {
+				delete(current.Targets, id)
+			}
+		}
+	})
+	if err != nil {
+		s.client.Log.Error("Failed to clear coalesced notifications", "userId", userID, "error", err)
+	}
+}
+
+// coalescedChannel returns the key counting the notifications held back for a mention: the channel
+// name prefixed with "~", or the sender prefixed with "@" for direct and group messages.
+func coalescedChannel(mention *Mention) string {
+	if mention.Type == MentionTypeDM || mention.Type == MentionTypeGM {
+		return "@" + mention.MentionedBy
+	}
+	return "~" + mention.ChannelName
+}
+
+// coalescedMessage summarizes the notifications held back by the rate limits, one line per channel.
+type coalescedMessage struct {
+	service  *Service
+	userID   string
+	channels map[string]int
+}
+
+func (m *coalescedMessage) Render(format Format) string {
+	channels := make([]string, 0, len(m.channels))
+	for channel := range m.channels {
+		channels = append(channels, channel)
+	}
+
+	// The busiest channels first
+	sort.Slice(channels, func(i, j int) bool {
+		if m.channels[channels[i]] != m.channels[channels[j]] {
+			return m.channels[channels[i]] > m.channels[channels[j]]
+		}
+		return channels[i] < channels[j]
+	})
+
+	f := getFormatter(format)
+	lines := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		var line string
+		if sender, ok := strings.CutPrefix(channel, "@"); ok {
+			line = m.service.localizer.LocalizeCount(m.userID, coalescedDMMessage, m.channels[channel], map[string]any{"Sender": sender})
+		} else {
+			line = m.service.localizer.LocalizeCount(m.userID, coalescedChannelMessage, m.channels[channel], map[string]any{"Channel": strings.TrimPrefix(channel, "~")})
+		}
+		lines = append(lines, f.escape(line))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// memoryPreferences is a preference store keeping the preferences of a single user in memory.
+type memoryPreferences map[string]string
+
+func (m memoryPreferences) GetPreference(_, name string) (string, error) {
+	return m[name], nil
+}
+
+func (m memoryPreferences) SetPreferences(_ string, values map[string]string) error {
+	for name, value := range values {
+		m[name] = value
+	}
+	return nil
+}
+
+// memoryKVStore keeps the KV data the notifications of a single user read and write in memory,
+// failing on anything else.
+type memoryKVStore struct {
+	kvstore.KVStore
+	coalesced  *kvstore.CoalescedNotifications
+	deliveries map[string]string
+	digest     *kvstore.Digest
+	retries    map[string]*kvstore.Retry
+	snooze     *kvstore.Snooze
+}
+
+func (s *memoryKVStore) GetSnooze(string) (*kvstore.Snooze, error) {
+	return s.snooze, nil
+}
+
+func (s *memoryKVStore) ListDigests() ([]*kvstore.Digest, error) {
+	if s.digest == nil {
+		return nil, nil
+	}
+	return []*kvstore.Digest{s.digest}, nil
+}
+
+func (s *memoryKVStore) UpdateDigest(userID string, update func(digest *kvstore.Digest)) error {
+	if s.digest == nil {
+		s.digest = &kvstore.Digest{UserID: userID, Channels: map[string]*kvstore.DigestChannel{}}
+	}
+	update(s.digest)
+	return nil
+}
+
+func (s *memoryKVStore) TakeDigest(string) (*kvstore.Digest, error) {
+	digest := s.digest
+	s.digest = nil
+	return digest, nil
+}
+
+func (s *memoryKVStore) SaveRetry(retry *kvstore.Retry) error {
+	saved := *retry
+	s.retries[retry.ID] = &saved
+	return nil
+}
+
+func (s *memoryKVStore) ListRetries() ([]*kvstore.Retry, error) {
+	var retries []*kvstore.Retry
+	for _, retry := range s.retries {
+		retries = append(retries, retry)
+	}
+	return retries, nil
+}
+
+func (s *memoryKVStore) TakeRetry(id string) (*kvstore.Retry, error) {
+	retry := s.retries[id]
+	delete(s.retries, id)
+	return retry, nil
+}
+
+func (s *memoryKVStore) RecordDelivery(userID, targetID, deliveryErr string) (*kvstore.DeliveryStatus, error) {
+	s.deliveries[targetID] = deliveryErr
+	return &kvstore.DeliveryStatus{UserID: userID, TargetID: targetID}, nil
+}
+
+func (s *memoryKVStore) UpdateCoalescedNotifications(_ string, update func(coalesced *kvstore.CoalescedNotifications)) error {
+	update(s.coalesced)
+	return nil
+}
+
+func TestCapRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(0, capRateLimit(0, ""))
+	assert.Equal(10, capRateLimit(10, ""))
+	assert.Equal(10, capRateLimit(10, "invalid"))
+	assert.Equal(5, capRateLimit(10, "5"))
+	assert.Equal(10, capRateLimit(10, "50"))
+	assert.Equal(50, capRateLimit(0, "50"))
+}
+
+func TestCoalescedChannel(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("~town-square", coalescedChannel(&Mention{Type: MentionTypeChannel, ChannelName: "town-square", MentionedBy: "alice"}))
+	assert.Equal("@alice", coalescedChannel(&Mention{Type: MentionTypeDM, ChannelName: "id1__id2", MentionedBy: "alice"}))
+}
+
+func TestCoalescedMessageRender(t *testing.T) {
+	message := &coalescedMessage{
+		service: &Service{},
+		userID:  "user",
+		channels: map[string]int{
+			"~town-square": 1,
+			"~dev_ops":     4,
+			"@alice":       2,
+		},
+	}
+
+	assert.Equal(t, "4 more mentions in ~dev_ops\n2 more messages from @alice\n1 more mention in ~town-square", message.Render(FormatPlain))
+	assert.Equal(t, "4 more mentions in \\~dev\\_ops\n2 more messages from @alice\n1 more mention in \\~town-square", message.Render(FormatMarkdown))
+}
+
+func TestSendCoalescedNotificationsKeepsFailures(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/failing") {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	working := "generic://" + host + "/working?disabletls=yes"
+	failing := "generic://" + host + "/failing?disabletls=yes"
+
+	api := &plugintest.API{}
+	api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	store := &memoryKVStore{
+		coalesced: &kvstore.CoalescedNotifications{
+			UserID: "user",
+			Targets: map[string]map[string]int{
+				targetID(working): {"~town-square": 2},
+				targetID(failing): {"~town-square": 3},
+			},
+		},
+		deliveries: map[string]string{},
+	}
+	preferences := memoryPreferences{prefstore.NotificationServices: working + "," + failing}
+	service := NewService(pluginapi.NewClient(api, &plugintest.Driver{}), preferences, store, nil, func() *Config { return nil }, nil)
+
+	service.sendCoalescedNotifications(store.coalesced)
+
+	assert.Empty(store.deliveries[targetID(working)])
+	assert.NotEmpty(store.deliveries[targetID(failing)])
+
+	// Only the follow-up that went through is cleared, the other is retried on the next run
+	assert.Equal(map[string]map[string]int{
+		targetID(failing): {"~town-square": 3},
+	}, store.coalesced.Targets)
+}
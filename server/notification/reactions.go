@@ -0,0 +1,275 @@
+package notification
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// reactionBatchWindow is how long the reactions to a user's posts are accumulated before they are
+// notified in a single message.
+const reactionBatchWindow = time.Minute
+
+var (
+	reactionTitleMessage = &i18n.Message{
+		ID:    "notification.reaction.title",
+		Other: "@{{.Username}} reacted to your post",
+	}
+	reactionsTitleMessage = &i18n.Message{
+		ID:    "notification.reactions.title",
+		One:   "{{.Count}} new reaction to your posts",
+		Other: "{{.Count}} new reactions to your posts",
+	}
+	reactionDirectMessageLabel = &i18n.Message{
+		ID:    "notification.reactions.direct_message",
+		Other: "Direct message",
+	}
+)
+
+// Reaction describes an emoji reaction to a user's post
+type Reaction struct {
+	// UserID is the author of the post, who is notified.
+	UserID      string
+	PostID      string
+	ChannelID   string
+	Channel     string
+	ChannelName string
+	TeamName    string
+
+	// Message is an excerpt of the post.
+	Message *Excerpt
+
+	// ReactedBy is the username of the user who reacted.
+	ReactedBy string
+
+	// EmojiName is the name of the emoji, without colons.
+	EmojiName string
+}
+
+// reactionSettings are the user's preferences for the notifications of reactions to their posts.
+type reactionSettings struct {
+	enabled bool
+
+	// allow lists the only emoji notified if not empty, and deny the emoji never notified.
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+// getReactionSettings returns the user's reaction preferences. Reactions aren't notified unless the
+// user turned them on.
+func (s *Service) getReactionSettings(userID string) reactionSettings {
+	var settings reactionSettings
+
+	enabled, err := s.preferences.GetPreference(userID, prefstore.ReactionNotifications)
+	if err != nil {
+		s.client.Log.Warn("Failed to get user reaction preferences", "userId", userID, "error", err)
+		return settings
+	}
+	if settings.enabled = enabled == "true"; !settings.enabled {
+		return settings
+	}
+
+	allow, err := s.preferences.GetPreference(userID, prefstore.ReactionEmojiAllowlist)
+	if err != nil {
+		s.client.Log.Warn("Failed to get user reaction allowlist", "userId", userID, "error", err)
+	}
+	settings.allow = parseEmojiList(allow)
+
+	deny, err := s.preferences.GetPreference(userID, prefstore.ReactionEmojiDenylist)
+	if err != nil {
+		s.client.Log.Warn("Failed to get user reaction denylist", "userId", userID, "error", err)
+	}
+	settings.deny = parseEmojiList(deny)
+
+	return settings
+}
+
+// includes returns whether a reaction with the emoji is notified.
+func (settings reactionSettings) includes(emojiName string) bool {
+	if !settings.enabled {
+		return false
+	}
+
+	key := emojiKey(emojiName)
+	if settings.deny[key] {
+		return false
+	}
+	return len(settings.allow) == 0 || settings.allow[key]
+}
+
+// parseEmojiList parses a comma or space separated list of emoji names, with or without colons.
+func parseEmojiList(list string) map[string]bool {
+	emoji := map[string]bool{}
+	for _, name := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if name = strings.Trim(name, ":"); name != "" {
+			emoji[emojiKey(name)] = true
+		}
+	}
+	return emoji
+}
+
+// emojiKey identifies an emoji so that the aliases of system emoji, such as +1 and thumbsup, match.
+func emojiKey(name string) string {
+	name = strings.ToLower(name)
+	if id, ok := model.GetSystemEmojiId(name); ok {
+		return id
+	}
+	return name
+}
+
+// WantsReactionNotification returns whether the user wants to be notified of a reaction to their
+// post with the emoji.
+func (s *Service) WantsReactionNotification(userID, emojiName string) bool {
+	return s.getReactionSettings(userID).includes(emojiName)
+}
+
+// AddReactionNotification adds a reaction to the batch of the post author, which is notified once
+// the batching window elapses.
+func (s *Service) AddReactionNotification(reaction *Reaction) error {
+	if s.getSnooze(reaction.UserID).Muted(reaction.ChannelID, model.GetMillis()) {
+		return nil
+	}
+
+	permalink := s.getPermalink(reaction.UserID, reaction.TeamName, reaction.PostID)
+
+	return s.kvstore.UpdateReactionBatch(reaction.UserID, func(batch *kvstore.ReactionBatch) {
+		if batch.SendAt == 0 {
+			batch.SendAt = model.GetMillis() + reactionBatchWindow.Milliseconds()
+		}
+
+		post, ok := batch.Posts[reaction.PostID]
+		if !ok {
+			post = &kvstore.ReactedPost{
+				PostID:      reaction.PostID,
+				Channel:     reaction.Channel,
+				ChannelName: reaction.ChannelName,
+				TeamName:    reaction.TeamName,
+				Excerpt:     reaction.Message.String(),
+				Permalink:   permalink,
+			}
+			batch.Posts[reaction.PostID] = post
+		}
+
+		post.Reactions = append(post.Reactions, &kvstore.Reaction{
+			Username:  reaction.ReactedBy,
+			EmojiName: reaction.EmojiName,
+		})
+	})
+}
+
+// SendDueReactionNotifications sends the reaction batches whose window elapsed. It's called
+// periodically by a cluster job.
+func (s *Service) SendDueReactionNotifications() {
+	batches, err := s.kvstore.ListReactionBatches()
+	if err != nil {
+		s.client.Log.Error("Failed to list reaction batches", "error", err)
+		return
+	}
+
+	now := model.GetMillis()
+	for _, pending := range batches {
+		if pending.SendAt > now {
+			continue
+		}
+
+		batch, err := s.kvstore.TakeReactionBatch(pending.UserID)
+		if err != nil {
+			s.client.Log.Error("Failed to take reaction batch", "userId", pending.UserID, "error", err)
+			continue
+		}
+		if batch == nil {
+			continue
+		}
+
+		if err := s.sendReactionBatch(batch); err != nil {
+			s.client.Log.Error("Failed to send reaction notifications", "userId", batch.UserID, "error", err)
+		}
+	}
+}
+
+// sendReactionBatch sends a single message listing the reactions of a batch, grouped by post.
+func (s *Service) sendReactionBatch(batch *kvstore.ReactionBatch) error {
+	posts := make([]*kvstore.ReactedPost, 0, len(batch.Posts))
+	count := 0
+	for _, post := range batch.Posts {
+		posts = append(posts, post)
+		count += len(post.Reactions)
+	}
+	if count == 0 {
+		return nil
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		if len(posts[i].Reactions) != len(posts[j].Reactions) {
+			return len(posts[i].Reactions) > len(posts[j].Reactions)
+		}
+		return posts[i].PostID < posts[j].PostID
+	})
+
+	title := s.localizer.LocalizeCount(batch.UserID, reactionsTitleMessage, count, nil)
+	if count == 1 {
+		title = s.localizer.Localize(batch.UserID, reactionTitleMessage, map[string]any{"Username": posts[0].Reactions[0].Username})
+	}
+
+	var link string
+	if len(posts) == 1 {
+		link = posts[0].Permalink
+	}
+
+	return s.SendUserNotification(batch.UserID, &Notification{
+		Message: &reactionsMessage{
+			service: s,
+			userID:  batch.UserID,
+			posts:   posts,
+		},
+		Priority: PriorityLow,
+		Title:    title,
+		Link:     link,
+		Retry:    true,
+	})
+}
+
+// reactionsMessage lists the posts of a reaction batch, each followed by its emoji and who reacted with them.
+type reactionsMessage struct {
+	service *Service
+	userID  string
+	posts   []*kvstore.ReactedPost
+}
+
+func (m *reactionsMessage) Render(format Format) string {
+	f := getFormatter(format)
+
+	var lines []string
+	for _, post := range m.posts {
+		label := post.Channel
+		if label == "" {
+			label = m.service.localizer.Localize(m.userID, reactionDirectMessageLabel, nil)
+		}
+
+		excerpt := f.escape(post.Excerpt)
+		if post.Permalink != "" {
+			excerpt = f.link(post.Excerpt, post.Permalink)
+		}
+		lines = append(lines, f.bold(label)+f.escape(": ")+excerpt)
+
+		// Reactions with the same emoji on a single line, in the order they were first added
+		var emoji []string
+		usernames := map[string][]string{}
+		for _, reaction := range post.Reactions {
+			if _, ok := usernames[reaction.EmojiName]; !ok {
+				emoji = append(emoji, reaction.EmojiName)
+			}
+			usernames[reaction.EmojiName] = append(usernames[reaction.EmojiName], "@"+reaction.Username)
+		}
+		for _, name := range emoji {
+			lines = append(lines, f.escape(emojiText(name)+" "+strings.Join(usernames[name], ", ")))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
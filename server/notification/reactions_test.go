@@ -0,0 +1,61 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReactionSettingsIncludes(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(reactionSettings{}.includes("tada"))
+
+	all := reactionSettings{enabled: true}
+	assert.True(all.includes("tada"))
+	assert.True(all.includes("custom_emoji"))
+
+	denied := reactionSettings{enabled: true, deny: parseEmojiList(":eyes:, thumbsup")}
+	assert.False(denied.includes("eyes"))
+	assert.False(denied.includes("+1"))
+	assert.True(denied.includes("tada"))
+
+	allowed := reactionSettings{enabled: true, allow: parseEmojiList("tada +1 :Party_Parrot:"), deny: parseEmojiList("+1")}
+	assert.True(allowed.includes("tada"))
+	assert.True(allowed.includes("party_parrot"))
+	assert.False(allowed.includes("thumbsup"))
+	assert.False(allowed.includes("eyes"))
+}
+
+func TestReactionsMessageRender(t *testing.T) {
+	message := &reactionsMessage{
+		service: &Service{},
+		userID:  "user",
+		posts: []*kvstore.ReactedPost{
+			{
+				Channel:   "Town Square",
+				Excerpt:   "Release 2.0 is out!",
+				Permalink: "https://example.com/team/pl/1",
+				Reactions: []*kvstore.Reaction{
+					{Username: "alice", EmojiName: "tada"},
+					{Username: "bob", EmojiName: "+1"},
+					{Username: "carol", EmojiName: "tada"},
+				},
+			},
+			{
+				Excerpt:   "lunch?",
+				Reactions: []*kvstore.Reaction{{Username: "dave", EmojiName: "party_parrot"}},
+			},
+		},
+	}
+
+	for format, expected := range map[Format]string{
+		FormatPlain:    "Town Square: Release 2.0 is out!\n🎉 @alice, @carol\n👍 @bob\nDirect message: lunch?\n:party_parrot: @dave",
+		FormatMarkdown: "**Town Square**: [Release 2.0 is out!](https://example.com/team/pl/1)\n🎉 @alice, @carol\n👍 @bob\n**Direct message**: lunch?\n:party\\_parrot: @dave",
+	} {
+		t.Run(string(format), func(t *testing.T) {
+			assert.Equal(t, expected, message.Render(format))
+		})
+	}
+}
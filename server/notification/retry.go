@@ -0,0 +1,154 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// retryDelays are the waits before each retry of a notification that failed to be sent. The
+// notification is dropped when the last retry fails.
+var retryDelays = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+// queueRetry queues a notification that failed to be sent to one of the user's targets, and
+// returns whether it was queued.
+func (s *Service) queueRetry(userID, serviceURL string, rendered *renderedNotification) bool {
+	now := model.GetMillis()
+	err := s.kvstore.SaveRetry(&kvstore.Retry{
+		ID:         model.NewId(),
+		UserID:     userID,
+		TargetID:   targetID(serviceURL),
+		ServiceURL: rendered.serviceURL,
+		Message:    rendered.message,
+		Plain:      rendered.plain,
+		Params:     rendered.params,
+		Attempts:   1,
+		RetryAt:    now + retryDelays[0].Milliseconds(),
+		CreateAt:   now,
+	})
+	if err != nil {
+		s.client.Log.Error("Failed to queue notification for a retry", "userId", userID, "error", err)
+		return false
+	}
+	return true
+}
+
+// SendDueRetries sends again the queued notifications whose retry is due. It's called periodically
+// by a cluster job.
+func (s *Service) SendDueRetries() {
+	// Retries wait for the end of the pause, like digests
+	if s.DeliveryPaused() {
+		return
+	}
+
+	retries, err := s.kvstore.ListRetries()
+	if err != nil {
+		s.client.Log.Error("Failed to list retries", "error", err)
+		return
+	}
+
+	now := model.GetMillis()
+	for _, pending := range retries {
+		if pending.RetryAt > now || s.getSnooze(pending.UserID).Snoozed(now) {
+			continue
+		}
+		s.sendRetry(pending.ID)
+	}
+}
+
+// ReplayRetries sends again every queued notification, whether or not its retry is due, and
+// returns how many were sent and how many weren't, failing again or dropped.
+func (s *Service) ReplayRetries() (sent, failed int, err error) {
+	retries, err := s.kvstore.ListRetries()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, pending := range retries {
+		if s.sendRetry(pending.ID) {
+			sent++
+		} else {
+			failed++
+		}
+	}
+	return sent, failed, nil
+}
+
+// FlushRetries drops every queued notification, and returns how many were dropped.
+func (s *Service) FlushRetries() (int, error) {
+	retries, err := s.kvstore.ListRetries()
+	if err != nil {
+		return 0, err
+	}
+
+	flushed := 0
+	for _, pending := range retries {
+		retry, err := s.kvstore.TakeRetry(pending.ID)
+		if err != nil {
+			return flushed, err
+		}
+		if retry != nil {
+			flushed++
+		}
+	}
+	return flushed, nil
+}
+
+// sendRetry sends a queued notification again, and returns whether it was sent. It's taken from
+// the queue first, so that another server can't send it twice, and queued again if it fails
+// while retries remain. Notifications to targets the user removed or disabled meanwhile are dropped.
+func (s *Service) sendRetry(id string) bool {
+	retry, err := s.kvstore.TakeRetry(id)
+	if err != nil {
+		s.client.Log.Error("Failed to take retry", "error", err)
+		return false
+	}
+	if retry == nil {
+		return false
+	}
+
+	targetURL, ok := s.getEnabledTarget(retry.UserID, retry.TargetID)
+	if !ok {
+		s.client.Log.Debug("Retry dropped for a removed or disabled target", "userId", retry.UserID)
+		return false
+	}
+
+	rendered := &renderedNotification{
+		serviceURL: retry.ServiceURL,
+		message:    retry.Message,
+		plain:      retry.Plain,
+		params:     retry.Params,
+	}
+	err = rendered.send(s)
+	s.recordDelivery(retry.UserID, targetURL, err)
+	if err == nil {
+		return true
+	}
+
+	retry.Attempts++
+	if retry.Attempts > len(retryDelays) {
+		s.client.Log.Error("Failed to send notification, dropped after its last retry", "userId", retry.UserID, "error", RedactError(retry.ServiceURL, err))
+		return false
+	}
+
+	retry.RetryAt = model.GetMillis() + retryDelays[retry.Attempts-1].Milliseconds()
+	if err := s.kvstore.SaveRetry(retry); err != nil {
+		s.client.Log.Error("Failed to queue notification for a retry", "userId", retry.UserID, "error", err)
+	}
+	return false
+}
+
+// getEnabledTarget returns the URL of the user's enabled target with the ID, if they still have one.
+func (s *Service) getEnabledTarget(userID, id string) (string, bool) {
+	services, err := s.getUserServices(userID)
+	if err != nil {
+		return "", false
+	}
+	for _, serviceURL := range services {
+		if targetID(serviceURL) == id {
+			return serviceURL, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,103 @@
+package notification
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetries(t *testing.T) {
+	assert := assert.New(t)
+
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	serviceURL := "generic://" + strings.TrimPrefix(server.URL, "http://") + "/hook?disabletls=yes"
+
+	api := &plugintest.API{}
+	api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	store := &memoryKVStore{deliveries: map[string]string{}, retries: map[string]*kvstore.Retry{}}
+	preferences := memoryPreferences{prefstore.NotificationServices: serviceURL}
+	service := NewService(pluginapi.NewClient(api, &plugintest.Driver{}), preferences, store, nil, func() *Config { return nil }, nil)
+
+	// A notification that fails to be sent is queued rather than failing
+	require.NoError(t, service.SendUserNotification("user", &Notification{Message: Text("hello"), Retry: true}))
+	retries, _ := store.ListRetries()
+	require.Len(t, retries, 1)
+	retry := retries[0]
+	assert.Equal("user", retry.UserID)
+	assert.Equal(targetID(serviceURL), retry.TargetID)
+	assert.Equal(1, retry.Attempts)
+
+	// And kept until its retry is due
+	service.SendDueRetries()
+	assert.Len(store.retries, 1)
+
+	// Failing again pushes its retry back
+	retry.RetryAt = 0
+	service.SendDueRetries()
+	retries, _ = store.ListRetries()
+	require.Len(t, retries, 1)
+	retry = retries[0]
+	assert.Equal(2, retry.Attempts)
+	assert.Greater(retry.RetryAt, retry.CreateAt)
+
+	// Until it's sent
+	failing = false
+	retry.RetryAt = 0
+	service.SendDueRetries()
+	assert.Empty(store.retries)
+	assert.Empty(store.deliveries[targetID(serviceURL)])
+
+	// Notifications without retries still fail
+	failing = true
+	assert.Error(service.SendUserNotification("user", &Notification{Message: Text("hello")}))
+	assert.Empty(store.retries)
+}
+
+func TestRetriesAreDropped(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	serviceURL := "generic://" + strings.TrimPrefix(server.URL, "http://") + "/hook?disabletls=yes"
+
+	api := &plugintest.API{}
+	api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	store := &memoryKVStore{
+		deliveries: map[string]string{},
+		retries: map[string]*kvstore.Retry{
+			"last":    {ID: "last", UserID: "user", TargetID: targetID(serviceURL), ServiceURL: serviceURL, Attempts: len(retryDelays)},
+			"removed": {ID: "removed", UserID: "user", TargetID: "removed", ServiceURL: "generic://removed"},
+		},
+	}
+	preferences := memoryPreferences{prefstore.NotificationServices: serviceURL}
+	service := NewService(pluginapi.NewClient(api, &plugintest.Driver{}), preferences, store, nil, func() *Config { return nil }, nil)
+
+	// After its last retry, or once its target was removed
+	sent, failed, err := service.ReplayRetries()
+	require.NoError(t, err)
+	assert.Equal(0, sent)
+	assert.Equal(2, failed)
+	assert.Empty(store.retries)
+	assert.NotEmpty(store.deliveries[targetID(serviceURL)])
+	assert.NotContains(store.deliveries, "removed")
+}
@@ -4,77 +4,259 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/containrrr/shoutrrr"
 	"github.com/containrrr/shoutrrr/pkg/router"
+	"github.com/containrrr/shoutrrr/pkg/types"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
 )
 
+// Config holds the admin settings the notification service depends on.
+type Config struct {
+	// PriorityMapping overrides the built-in params sent to each scheme for each priority.
+	PriorityMapping PriorityMapping
+
+	// MessageTemplate is the default message template for users who didn't set their own.
+	MessageTemplate string
+
+	// TitleTemplate is the default title template for users who didn't set their own.
+	TitleTemplate string
+
+	// RateLimits caps the notifications per minute of each user and target, users can only lower them.
+	RateLimits RateLimits
+
+	// DeliveryPaused stops sending notifications anywhere, as a kill switch.
+	DeliveryPaused bool
+}
+
 // Service handles sending notifications to different services through Shoutrrr
 type Service struct {
-	client *pluginapi.Client
-	router router.ServiceRouter
+	client      *pluginapi.Client
+	preferences prefstore.PreferenceStore
+	kvstore     kvstore.KVStore
+	localizer   *i18n.Localizer
+	router      router.ServiceRouter
+	getConfig   func() *Config
+
+	// onDeliveryFailed is called with the updated status of a user's target each time sending to
+	// it fails, nil to ignore failures.
+	onDeliveryFailed func(userID, serviceURL string, status *kvstore.DeliveryStatus)
 }
 
 // NewService creates a new notification service
-func NewService(client *pluginapi.Client) *Service {
+func NewService(client *pluginapi.Client, preferences prefstore.PreferenceStore, kvstore kvstore.KVStore, localizer *i18n.Localizer, getConfig func() *Config, onDeliveryFailed func(userID, serviceURL string, status *kvstore.DeliveryStatus)) *Service {
 	return &Service{
-		client: client,
+		client:           client,
+		preferences:      preferences,
+		kvstore:          kvstore,
+		localizer:        localizer,
+		getConfig:        getConfig,
+		onDeliveryFailed: onDeliveryFailed,
 	}
 }
 
-// SendUserNotification sends a notification to a user based on their configured services
-func (s *Service) SendUserNotification(userID, message string) error {
-	// Get database connection
-	db, err := s.client.Store.GetMasterDB()
-	if err != nil {
-		s.client.Log.Error("Failed to get database connection", "userId", userID, "error", err)
-		return fmt.Errorf("failed to get database connection")
-	}
-
-	// Query the database directly for user preferences
-	query := `
-		SELECT Value
-		FROM Preferences
-		WHERE UserId = $1
-		AND Category = 'pp_com.mattermost.plugin-shoutrr'
-		AND Name = 'notification_services'
-	`
-	var servicesStr string
-	err = db.QueryRow(query, userID).Scan(&servicesStr)
+// Mention describes a post that mentioned a user
+type Mention struct {
+	UserID      string
+	PostID      string
+	ChannelID   string
+	Channel     string
+	ChannelName string
+	Team        string
+	TeamName    string
+	MentionedBy string
+
+	// Message is an excerpt of the post.
+	Message *Excerpt
+
+	// SenderDisplayName is the display name of the user MentionedBy refers to.
+	SenderDisplayName string
+
+	// ThreadRootMessage is an excerpt of the thread's root post when the post is a reply.
+	ThreadRootMessage *Excerpt
+
+	// Type is how the user was mentioned, one of the MentionType constants.
+	Type string
+
+	// PostPriority is the priority the sender set on the post, if any.
+	PostPriority string
+
+	// Attachments are the files attached to the post.
+	Attachments []Attachment
+
+	// ImageURL is a public link to a preview of an image attached to the post, if enabled.
+	ImageURL string
+
+	// Reminder counts the reminders of an urgent post with persistent notifications, zero for the
+	// first notification.
+	Reminder int
+}
+
+// getUserServices returns the Shoutrrr URLs the user has configured and not disabled
+func (s *Service) getUserServices(userID string) ([]string, error) {
+	targets, err := prefstore.GetTargets(s.preferences, userID)
 	if err != nil {
-		// If no rows found, it's not an error, just no services configured
-		if err.Error() == "sql: no rows in result set" {
-			s.client.Log.Debug("No notification services configured for user", "userId", userID)
-			return nil
+		s.client.Log.Error("Failed to get user preferences", "userId", userID, "error", err)
+		return nil, fmt.Errorf("failed to get user preferences: %w", err)
+	}
+
+	var services []string
+	for _, target := range targets {
+		if target.Enabled {
+			services = append(services, target.URL)
 		}
-		s.client.Log.Error("Failed to query user preferences from database", "userId", userID, "error", err)
-		return fmt.Errorf("failed to query user preferences: %w", err)
+	}
+	return services, nil
+}
+
+// DeliveryPaused returns whether a system admin paused the delivery of all notifications.
+func (s *Service) DeliveryPaused() bool {
+	config := s.getConfig()
+	return config != nil && config.DeliveryPaused
+}
+
+// getAdminPriorityMapping returns the built-in priority mapping with the admin overrides applied
+func (s *Service) getAdminPriorityMapping() PriorityMapping {
+	mapping := defaultPriorityMapping
+	if config := s.getConfig(); config != nil {
+		mapping = mapping.Merge(config.PriorityMapping)
+	}
+	return mapping
+}
+
+// getPriorityMapping returns the built-in priority mapping with the admin and user overrides applied
+func (s *Service) getPriorityMapping(userID string) PriorityMapping {
+	mapping := s.getAdminPriorityMapping()
+
+	userMappingStr, err := s.preferences.GetPreference(userID, prefstore.PriorityMapping)
+	if err != nil {
+		s.client.Log.Warn("Failed to get user priority mapping", "userId", userID, "error", err)
+		return mapping
+	}
+
+	userMapping, err := ParsePriorityMapping(userMappingStr)
+	if err != nil {
+		s.client.Log.Warn("Ignoring invalid user priority mapping", "userId", userID, "error", err)
+		return mapping
+	}
+
+	return mapping.Merge(userMapping)
+}
+
+// getMessageFormats returns the formats the user chose for some of their services
+func (s *Service) getMessageFormats(userID string) MessageFormats {
+	formatsStr, err := s.preferences.GetPreference(userID, prefstore.MessageFormats)
+	if err != nil {
+		s.client.Log.Warn("Failed to get user message formats", "userId", userID, "error", err)
+		return MessageFormats{}
 	}
 
-	if servicesStr == "" {
+	formats, err := ParseMessageFormats(formatsStr)
+	if err != nil {
+		s.client.Log.Warn("Ignoring invalid user message formats", "userId", userID, "error", err)
+		return MessageFormats{}
+	}
+
+	return formats
+}
+
+// Notification is a message ready to be delivered to a user's services
+type Notification struct {
+	Message  Message
+	Priority Priority
+
+	// Title is sent as the title or subject on the services that have one.
+	Title string
+
+	// Link is opened when the notification is clicked, on the services that support it.
+	Link string
+
+	// ImageURL is a public link to an image shown with the notification, on the services that support it.
+	ImageURL string
+
+	// CoalesceKey subjects the notification to the user's rate limits, and counts it under this key
+	// in the follow-up sent when it's held back. Notifications without a key aren't rate limited.
+	CoalesceKey string
+
+	// IdempotencyKey identifies the notification so that it's sent at most once to each target,
+	// even if several mentions or cluster nodes send it. Notifications without a key are always sent.
+	IdempotencyKey string
+
+	// Retry queues the notification to be sent again later to the targets it fails to be sent to,
+	// rather than returning an error.
+	Retry bool
+}
+
+// SendUserNotification sends a notification to a user based on their configured services
+func (s *Service) SendUserNotification(userID string, notification *Notification) error {
+	if s.DeliveryPaused() {
+		s.client.Log.Debug("Notification dropped while delivery is paused", "userId", userID)
+		return nil
+	}
+	if s.getSnooze(userID).Snoozed(model.GetMillis()) {
+		s.client.Log.Debug("Notification dropped while the user is snoozed", "userId", userID)
+		return nil
+	}
+
+	services, err := s.getUserServices(userID)
+	if err != nil {
+		return err
+	}
+
+	if len(services) == 0 {
 		s.client.Log.Debug("No notification services configured for user", "userId", userID)
 		return nil
 	}
 
-	// Split into individual services
-	services := strings.Split(servicesStr, ",")
-	for i, service := range services {
-		services[i] = strings.TrimSpace(service)
+	if notification.IdempotencyKey != "" {
+		if services = s.claimServices(userID, notification.IdempotencyKey, services); len(services) == 0 {
+			s.client.Log.Debug("Notification already sent", "userId", userID)
+			return nil
+		}
+	}
+
+	var limits RateLimits
+	if notification.CoalesceKey != "" {
+		limits = s.getRateLimits(userID)
+		if !s.takeUserToken(userID, limits) {
+			s.client.Log.Debug("Notification held back by the user rate limit", "userId", userID)
+			s.coalesce(userID, services, notification.CoalesceKey)
+			return nil
+		}
 	}
 
+	mapping := s.getPriorityMapping(userID)
+	formats := s.getMessageFormats(userID)
+
 	var errs []string
+	var held []string
 	for _, serviceURL := range services {
-		if serviceURL == "" {
+		if notification.CoalesceKey != "" && !s.takeTargetToken(userID, serviceURL, limits) {
+			held = append(held, serviceURL)
 			continue
 		}
 
-		err := shoutrrr.Send(serviceURL, message)
-		if err != nil {
+		rendered, err := s.renderForService(serviceURL, notification, mapping, formats)
+		if err == nil {
+			err = rendered.send(s)
+		}
+		s.recordDelivery(userID, serviceURL, err)
+		if err != nil && notification.Retry && rendered != nil && s.queueRetry(userID, serviceURL, rendered) {
+			s.client.Log.Warn("Failed to send notification, queued for a retry",
+				"userId", userID,
+				"service", serviceURL,
+				"error", err)
+		} else if err != nil {
 			s.client.Log.Error("Failed to send notification",
 				"userId", userID,
 				"service", serviceURL,
 				"error", err)
 			errs = append(errs, fmt.Sprintf("%s: %v", serviceURL, err))
+			if notification.IdempotencyKey != "" {
+				s.releaseNotification(userID, notification.IdempotencyKey, targetID(serviceURL))
+			}
 		} else {
 			s.client.Log.Debug("Notification sent successfully",
 				"userId", userID,
@@ -82,16 +264,258 @@ func (s *Service) SendUserNotification(userID, message string) error {
 		}
 	}
 
+	if len(held) > 0 {
+		s.client.Log.Debug("Notification held back by the target rate limit", "userId", userID, "targets", len(held))
+		s.coalesce(userID, held, notification.CoalesceKey)
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("failed to send notifications: %s", strings.Join(errs, "; "))
 	}
 	return nil
 }
 
-// SendMentionNotification sends a notification about a mention to a user
-func (s *Service) SendMentionNotification(userID, postID, channel, mentionedBy, message string) error {
-	notificationMsg := fmt.Sprintf("You were mentioned by @%s in %s: %s",
-		mentionedBy, channel, message)
+// sendToService renders a notification in the format of a service and sends it there
+func (s *Service) sendToService(serviceURL string, notification *Notification, mapping PriorityMapping, formats MessageFormats) error {
+	rendered, err := s.renderForService(serviceURL, notification, mapping, formats)
+	if err != nil {
+		return err
+	}
+	return rendered.send(s)
+}
+
+// renderedNotification is a notification rendered in the format of a service, ready to be sent.
+type renderedNotification struct {
+	serviceURL string
+	message    string
+	plain      string
+	params     types.Params
+}
+
+func (r *renderedNotification) send(s *Service) error {
+	return s.send(r.serviceURL, r.message, r.plain, r.params)
+}
+
+// renderForService renders a notification in the format of a service, with the params of its
+// priority. Images are added to the URL of the services that take them as a param.
+func (s *Service) renderForService(serviceURL string, notification *Notification, mapping PriorityMapping, formats MessageFormats) (*renderedNotification, error) {
+	scheme := getScheme(serviceURL)
+	format := formats.Format(scheme)
+
+	params := mapping.Params(scheme, notification.Priority)
+	if key, ok := linkParamKeys[scheme]; ok && notification.Link != "" {
+		params[key] = notification.Link
+	}
+	if titleSchemes[scheme] && notification.Title != "" {
+		params["title"] = notification.Title
+	}
+	for key, value := range formatParams[scheme][format] {
+		params[key] = value
+	}
+
+	message, plain := renderForScheme(notification.Message, scheme, format)
+	if notification.ImageURL != "" {
+		var err error
+		serviceURL, message, err = addImage(serviceURL, scheme, format, message, notification.Title, notification.ImageURL, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &renderedNotification{serviceURL: serviceURL, message: message, plain: plain, params: params}, nil
+}
+
+// SendMentionNotification sends a notification about a mention to a user, or adds it to their
+// digest if they chose to get a periodic summary instead
+func (s *Service) SendMentionNotification(mention *Mention) error {
+	// Mentions in muted channels or while snoozed are dropped rather than kept for a digest
+	if snooze, now := s.getSnooze(mention.UserID), model.GetMillis(); snooze.Snoozed(now) || snooze.Muted(mention.ChannelID, now) {
+		s.client.Log.Debug("Mention dropped while the user is snoozed or muted the channel", "userId", mention.UserID)
+		return nil
+	}
+
+	idempotencyKey := mentionIdempotencyKey(mention)
+
+	if settings := s.getDigestSettings(mention.UserID); settings.includes(mention) {
+		if !s.claimNotification(mention.UserID, idempotencyKey, digestTarget) {
+			return nil
+		}
+		if err := s.addToDigest(mention, settings.interval); err != nil {
+			s.releaseNotification(mention.UserID, idempotencyKey, digestTarget)
+			return err
+		}
+		return nil
+	}
+
+	data := s.getTemplateData(mention)
+
+	// Urgent posts aren't rate limited, like they aren't batched in digests
+	var coalesceKey string
+	if mention.PostPriority != PostPriorityUrgent {
+		coalesceKey = coalescedChannel(mention)
+	}
+
+	return s.SendUserNotification(mention.UserID, &Notification{
+		Message: &mentionMessage{
+			service:    s,
+			userID:     mention.UserID,
+			data:       data,
+			excerpt:    mention.Message,
+			threadRoot: mention.ThreadRootMessage,
+			rendered:   map[Format]string{},
+		},
+		Priority:       priorityForMention(mention.Type, mention.PostPriority),
+		Title:          s.renderTitle(mention.UserID, data),
+		Link:           data.Permalink,
+		ImageURL:       mention.ImageURL,
+		CoalesceKey:    coalesceKey,
+		IdempotencyKey: idempotencyKey,
+		// Reminders are sent again at their next interval anyway
+		Retry: mention.Reminder == 0,
+	})
+}
+
+// getTemplateData returns the data the message templates are rendered with for a mention
+func (s *Service) getTemplateData(mention *Mention) *TemplateData {
+	return &TemplateData{
+		SenderUsername:    mention.MentionedBy,
+		SenderDisplayName: mention.SenderDisplayName,
+		Channel:           mention.Channel,
+		ChannelName:       mention.ChannelName,
+		Team:              mention.Team,
+		TeamName:          mention.TeamName,
+		MentionType:       mention.Type,
+		Message:           mention.Message.String(),
+		Attachments:       formatAttachments(mention.Attachments),
+		Permalink:         s.getPermalink(mention.UserID, mention.TeamName, mention.PostID),
+		ThreadRootExcerpt: mention.ThreadRootMessage.String(),
+	}
+}
+
+// getPermalink returns a link to the post for the user, or an empty string if it can't be built.
+// Direct and group messages don't belong to a team, so their links go through one of the user's teams.
+func (s *Service) getPermalink(userID, teamName, postID string) string {
+	siteURL := s.client.Configuration.GetConfig().ServiceSettings.SiteURL
+	if siteURL == nil || *siteURL == "" {
+		return ""
+	}
+
+	if teamName == "" {
+		teams, err := s.client.Team.List(pluginapi.FilterTeamsByUser(userID))
+		if err != nil || len(teams) == 0 {
+			s.client.Log.Debug("Unable to find a team for the permalink", "userId", userID, "error", err)
+			return ""
+		}
+		teamName = teams[0].Name
+	}
+
+	return fmt.Sprintf("%s/%s/pl/%s", strings.TrimSuffix(*siteURL, "/"), teamName, postID)
+}
+
+// mentionMessage renders the recipient's message template for a mention, once per format.
+type mentionMessage struct {
+	service    *Service
+	userID     string
+	data       *TemplateData
+	excerpt    *Excerpt
+	threadRoot *Excerpt
+	rendered   map[Format]string
+}
+
+func (m *mentionMessage) Render(format Format) string {
+	if message, ok := m.rendered[format]; ok {
+		return message
+	}
+
+	data := formatTemplateData(m.data, m.excerpt, m.threadRoot, format)
+	message := m.service.renderMessage(m.userID, data, format)
+	m.rendered[format] = message
+	return message
+}
+
+// renderMessage renders the user's message template, falling back to the admin default and then
+// to the built-in template when a template is missing or fails to render.
+func (s *Service) renderMessage(userID string, data *TemplateData, format Format) string {
+	var adminTemplate string
+	if config := s.getConfig(); config != nil {
+		adminTemplate = config.MessageTemplate
+	}
+
+	return s.renderUserTemplate(userID, prefstore.MessageTemplate, adminTemplate, defaultMessageTemplateMessage, data, format)
+}
+
+// renderTitle renders the user's title template like renderMessage. Titles are plain text on a
+// single line, the services escape them as needed.
+func (s *Service) renderTitle(userID string, data *TemplateData) string {
+	var adminTemplate string
+	if config := s.getConfig(); config != nil {
+		adminTemplate = config.TitleTemplate
+	}
+
+	title := s.renderUserTemplate(userID, prefstore.TitleTemplate, adminTemplate, defaultTitleTemplateMessage, data, FormatPlain)
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// renderUserTemplate renders the template saved in the user's preference, falling back to the
+// admin template and then to the built-in one, in the user's language.
+func (s *Service) renderUserTemplate(userID, preference, adminTemplate string, defaultTemplate *i18n.Message, data *TemplateData, format Format) string {
+	userTemplate, err := s.preferences.GetPreference(userID, preference)
+	if err != nil {
+		s.client.Log.Warn("Failed to get user template", "userId", userID, "preference", preference, "error", err)
+	}
+
+	for _, text := range []string{userTemplate, adminTemplate} {
+		if text == "" {
+			continue
+		}
+
+		message, err := RenderTemplate(text, data, format)
+		if err == nil {
+			return message
+		}
+		s.client.Log.Warn("Failed to render template", "userId", userID, "preference", preference, "error", err)
+	}
+
+	message, err := RenderTemplate(s.localizer.Template(userID, defaultTemplate), data, format)
+	if err == nil {
+		return message
+	}
+	s.client.Log.Warn("Failed to render translated default template", "userId", userID, "preference", preference, "error", err)
+
+	message, err = RenderTemplate(defaultTemplate.Other, data, format)
+	if err != nil {
+		s.client.Log.Error("Failed to render default template", "preference", preference, "error", err)
+	}
+	return message
+}
+
+// send delivers a single message to a Shoutrrr URL, along with its plain text alternative for
+// the services that support one
+func (s *Service) send(serviceURL, message, plain string, params types.Params) error {
+	service, err := s.router.Locate(serviceURL)
+	if err != nil {
+		return err
+	}
+
+	// Shoutrrr writes the same message in both parts of multipart emails unless the service has a
+	// template for the plain part.
+	if templater, ok := service.(types.Templater); ok && plain != "" {
+		if err := templater.SetTemplateString("plain", escapeTemplateDelimiters(plain)); err != nil {
+			return err
+		}
+	}
+
+	return service.Send(message, &params)
+}
+
+// getScheme returns the Shoutrrr service name of a URL, e.g. "ntfy" for ntfy://ntfy.sh/topic
+// or "generic" for generic+https://example.com
+func getScheme(serviceURL string) string {
+	scheme, _, found := strings.Cut(serviceURL, "://")
+	if !found {
+		return ""
+	}
 
-	return s.SendUserNotification(userID, notificationMsg)
+	scheme, _, _ = strings.Cut(scheme, "+")
+	return strings.ToLower(scheme)
 }
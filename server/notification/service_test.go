@@ -0,0 +1,47 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type env struct {
+	service *Service
+	api     *plugintest.API
+}
+
+func setupTest() *env {
+	api := &plugintest.API{}
+	driver := &plugintest.Driver{}
+	client := pluginapi.NewClient(api, driver)
+
+	return &env{
+		service: NewService(client, nil, nil, nil, func() *Config { return nil }, nil),
+		api:     api,
+	}
+}
+
+func TestGetPermalink(t *testing.T) {
+	assert := assert.New(t)
+	env := setupTest()
+
+	config := &model.Config{}
+	config.SetDefaults()
+	config.ServiceSettings.SiteURL = model.NewPointer("https://mattermost.example.com/")
+	env.api.On("GetConfig").Return(config)
+	env.api.On("GetTeamsForUser", "user-id").Return([]*model.Team{{Name: "first-team"}}, nil)
+	env.api.On("GetTeamsForUser", "teamless-user-id").Return([]*model.Team{}, nil)
+	env.api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	assert.Equal("https://mattermost.example.com/engineering/pl/post-id", env.service.getPermalink("user-id", "engineering", "post-id"))
+	assert.Equal("https://mattermost.example.com/first-team/pl/post-id", env.service.getPermalink("user-id", "", "post-id"))
+	assert.Equal("", env.service.getPermalink("teamless-user-id", "", "post-id"))
+
+	config.ServiceSettings.SiteURL = model.NewPointer("")
+	assert.Equal("", env.service.getPermalink("user-id", "engineering", "post-id"))
+}
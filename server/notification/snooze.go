@@ -0,0 +1,16 @@
+package notification
+
+import (
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+)
+
+// getSnooze returns when the user's notifications resume, nil if they aren't snoozed or muted
+// anywhere. Failing to read it shouldn't lose notifications, so it's only logged.
+func (s *Service) getSnooze(userID string) *kvstore.Snooze {
+	snooze, err := s.kvstore.GetSnooze(userID)
+	if err != nil {
+		s.client.Log.Warn("Failed to get snooze", "userId", userID, "error", err)
+		return nil
+	}
+	return snooze
+}
@@ -0,0 +1,242 @@
+package notification
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+)
+
+// DefaultMessageTemplate is used when neither the user nor the admin configured a message template.
+const DefaultMessageTemplate = "{{if or (eq .MentionType \"subscription\") (eq .MentionType \"watch\") (eq .MentionType \"activity\")}}@{{.SenderUsername}} posted in {{.Channel}}{{else}}You were mentioned by @{{.SenderUsername}} in {{.Channel}}{{end}}: {{.Message}}{{if .Attachments}}\nAttached: {{.Attachments}}{{end}}{{if .Permalink}}\n{{.Permalink}}{{end}}"
+
+// DefaultTitleTemplate is used when neither the user nor the admin configured a title template.
+const DefaultTitleTemplate = `@{{.SenderUsername}}{{if eq .MentionType "dm"}} sent you a direct message{{else if eq .MentionType "gm"}} in a group message{{else}} in ~{{.ChannelName}}{{end}}`
+
+// defaultMessageTemplateMessage and defaultTitleTemplateMessage translate the default templates.
+// Their translations are Go templates with the same fields, and fall back to the English template
+// when they fail to render.
+var (
+	defaultMessageTemplateMessage = &i18n.Message{
+		ID:          "notification.default_message_template",
+		Description: "Go template of the notification body, keep the {{...}} actions",
+		Other:       DefaultMessageTemplate,
+	}
+	defaultTitleTemplateMessage = &i18n.Message{
+		ID:          "notification.default_title_template",
+		Description: "Go template of the notification title and email subject, keep the {{...}} actions",
+		Other:       DefaultTitleTemplate,
+	}
+)
+
+// DefaultTemplate returns the default message or title template in the user's language.
+func DefaultTemplate(localizer *i18n.Localizer, userID string, title bool) string {
+	if title {
+		return localizer.Template(userID, defaultTitleTemplateMessage)
+	}
+	return localizer.Template(userID, defaultMessageTemplateMessage)
+}
+
+// maxTemplateOutput bounds the size of a rendered template, as notifications are short by nature.
+const maxTemplateOutput = 4096
+
+// TemplateData is the data available to notification templates, e.g. {{.SenderUsername}}.
+type TemplateData struct {
+	// SenderUsername is the username of the author of the post, without the leading @.
+	SenderUsername string
+
+	// SenderDisplayName is the author's name as configured by the server's teammate name display setting.
+	SenderDisplayName string
+
+	// Channel is the display name of the channel the post was made in.
+	Channel string
+
+	// ChannelName is the name of the channel as used in its URL.
+	ChannelName string
+
+	// Team is the display name of the channel's team, empty for direct and group messages.
+	Team string
+
+	// TeamName is the name of the channel's team as used in its URL.
+	TeamName string
+
+	// MentionType is how the recipient was mentioned: dm, gm, keyword, group, channel, comment or
+	// thread, subscription for a post forwarded by one of their channel subscriptions, watch for a
+	// post matching one of their watch rules, or activity for a post in a channel they get push
+	// notifications for every post of.
+	MentionType string
+
+	// Message is an excerpt of the post.
+	Message string
+
+	// Attachments lists the names, sizes and types of the files attached to the post.
+	Attachments string
+
+	// Permalink is a link to the post in Mattermost.
+	Permalink string
+
+	// ThreadRootExcerpt is an excerpt of the first post of the thread, empty if the post isn't a reply.
+	ThreadRootExcerpt string
+}
+
+// SampleTemplateData is used to validate and preview templates.
+var SampleTemplateData = TemplateData{
+	SenderUsername:    "alice",
+	SenderDisplayName: "Alice Smith",
+	Channel:           "Town Square",
+	ChannelName:       "town-square",
+	Team:              "Engineering",
+	TeamName:          "engineering",
+	MentionType:       MentionTypeKeyword,
+	Message:           "@bob can you take a look at the release notes?",
+	Attachments:       "release-notes.pdf (240.0 KB, application/pdf)",
+	Permalink:         "https://mattermost.example.com/engineering/pl/8kgmsw8o1fbj8rkqfr4dqzmafe",
+	ThreadRootExcerpt: "Release notes for v2.0",
+}
+
+// unsafeVerbPattern matches printf verbs with a width or precision large enough to allocate huge strings.
+var unsafeVerbPattern = regexp.MustCompile(`%[-+# 0]*(\*|\d{4,}|\d*\.(\*|\d{4,}))`)
+
+var templateFuncs = template.FuncMap{
+	"printf": func(format string, args ...any) (string, error) {
+		if unsafeVerbPattern.MatchString(format) {
+			return "", errors.New("printf widths and precisions are limited to three digits")
+		}
+		return fmt.Sprintf(format, args...), nil
+	},
+}
+
+// ParseTemplate parses a notification template, rejecting constructs that could make rendering
+// arbitrarily expensive, and checks that it renders against the sample data.
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("notification").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tmpl.Templates()) > 1 {
+		return nil, errors.New("templates can't define other templates")
+	}
+
+	if err := checkTemplateNodes(tmpl.Root); err != nil {
+		return nil, err
+	}
+
+	if _, err := executeTemplate(tmpl, &SampleTemplateData); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// RenderTemplate parses and executes a notification template, escaping its text for the format.
+// The data is expected to be escaped already, see formatTemplateData.
+func RenderTemplate(text string, data *TemplateData, format Format) (string, error) {
+	tmpl, err := ParseTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	escapeTemplateText(tmpl.Root, getFormatter(format))
+	return executeTemplate(tmpl, data)
+}
+
+// formatTemplateData returns a copy of data escaped for a format, with the post and thread root
+// excerpts rendered in it.
+func formatTemplateData(data *TemplateData, message, threadRoot *Excerpt, format Format) *TemplateData {
+	f := getFormatter(format)
+
+	formatted := &TemplateData{
+		SenderUsername:    f.escape(data.SenderUsername),
+		SenderDisplayName: f.escape(data.SenderDisplayName),
+		Channel:           f.escape(data.Channel),
+		ChannelName:       f.escape(data.ChannelName),
+		Team:              f.escape(data.Team),
+		TeamName:          f.escape(data.TeamName),
+		MentionType:       f.escape(data.MentionType),
+		Message:           message.Format(format),
+		Attachments:       f.escape(data.Attachments),
+		ThreadRootExcerpt: threadRoot.Format(format),
+	}
+	if data.Permalink != "" {
+		formatted.Permalink = f.url(data.Permalink)
+	}
+	return formatted
+}
+
+func executeTemplate(tmpl *template.Template, data *TemplateData) (string, error) {
+	out := &limitedBuffer{limit: maxTemplateOutput}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// escapeTemplateText escapes the literal text of a parsed template, so that templates are written
+// in plain text whatever the format of the service.
+func escapeTemplateText(node parse.Node, f formatter) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, child := range node.Nodes {
+			escapeTemplateText(child, f)
+		}
+	case *parse.TextNode:
+		node.Text = []byte(f.escape(string(node.Text)))
+	case *parse.IfNode:
+		escapeTemplateText(node.List, f)
+		escapeTemplateText(node.ElseList, f)
+	case *parse.WithNode:
+		escapeTemplateText(node.List, f)
+		escapeTemplateText(node.ElseList, f)
+	}
+}
+
+func checkTemplateNodes(node parse.Node) error {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return nil
+		}
+		for _, child := range node.Nodes {
+			if err := checkTemplateNodes(child); err != nil {
+				return err
+			}
+		}
+	case *parse.RangeNode:
+		return errors.New("range is not allowed in templates")
+	case *parse.TemplateNode:
+		return errors.New("templates can't include other templates")
+	case *parse.IfNode:
+		return checkBranchNodes(&node.BranchNode)
+	case *parse.WithNode:
+		return checkBranchNodes(&node.BranchNode)
+	}
+	return nil
+}
+
+func checkBranchNodes(node *parse.BranchNode) error {
+	if err := checkTemplateNodes(node.List); err != nil {
+		return err
+	}
+	return checkTemplateNodes(node.ElseList)
+}
+
+// limitedBuffer fails writes past its limit so that a template can't produce unbounded output.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, fmt.Errorf("the rendered template is longer than %d bytes", b.limit)
+	}
+	return b.Buffer.Write(p)
+}
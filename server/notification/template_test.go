@@ -0,0 +1,108 @@
+package notification
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	message, err := RenderTemplate(DefaultMessageTemplate, &SampleTemplateData, FormatPlain)
+	assert.Nil(err)
+	assert.Equal("You were mentioned by @alice in Town Square: @bob can you take a look at the release notes?\nAttached: release-notes.pdf (240.0 KB, application/pdf)\nhttps://mattermost.example.com/engineering/pl/8kgmsw8o1fbj8rkqfr4dqzmafe", message)
+
+	message, err = RenderTemplate(DefaultMessageTemplate, &TemplateData{SenderUsername: "alice", Channel: "Town Square", Message: "hi"}, FormatPlain)
+	assert.Nil(err)
+	assert.Equal("You were mentioned by @alice in Town Square: hi", message)
+
+	message, err = RenderTemplate(DefaultMessageTemplate, &TemplateData{SenderUsername: "alice", Channel: "Incidents", MentionType: MentionTypeSubscription, Message: "db is down"}, FormatPlain)
+	assert.Nil(err)
+	assert.Equal("@alice posted in Incidents: db is down", message)
+
+	message, err = RenderTemplate(DefaultMessageTemplate, &TemplateData{SenderUsername: "alice", Channel: "Incidents", MentionType: MentionTypeWatch, Message: "sev1 declared"}, FormatPlain)
+	assert.NoError(err)
+	assert.Equal("@alice posted in Incidents: sev1 declared", message)
+
+	message, err = RenderTemplate(`{{.SenderDisplayName}} ({{.MentionType}}){{if .ThreadRootExcerpt}} re: {{.ThreadRootExcerpt}}{{end}} {{.Permalink}}`, &TemplateData{
+		SenderDisplayName: "Bob",
+		MentionType:       MentionTypeDM,
+		Permalink:         "https://example.com/team/pl/post",
+	}, FormatPlain)
+	assert.Nil(err)
+	assert.Equal("Bob (dm) https://example.com/team/pl/post", message)
+
+	message, err = RenderTemplate(`{{printf "%s in ~%s" .SenderUsername .ChannelName}}`, &SampleTemplateData, FormatPlain)
+	assert.Nil(err)
+	assert.Equal("alice in ~town-square", message)
+}
+
+func TestRenderTitleTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	title, err := RenderTemplate(DefaultTitleTemplate, &SampleTemplateData, FormatPlain)
+	assert.Nil(err)
+	assert.Equal("@alice in ~town-square", title)
+
+	title, err = RenderTemplate(DefaultTitleTemplate, &TemplateData{SenderUsername: "alice", MentionType: MentionTypeDM}, FormatPlain)
+	assert.Nil(err)
+	assert.Equal("@alice sent you a direct message", title)
+
+	title, err = RenderTemplate(DefaultTitleTemplate, &TemplateData{SenderUsername: "alice", MentionType: MentionTypeGM}, FormatPlain)
+	assert.Nil(err)
+	assert.Equal("@alice in a group message", title)
+}
+
+func TestParseTemplateRejectsInvalidTemplates(t *testing.T) {
+	for name, text := range map[string]string{
+		"syntax error":      "{{.Message",
+		"unknown field":     "{{.Recipient}}",
+		"range":             "{{range 1000000000}}x{{end}}",
+		"nested range":      "{{if .Message}}{{range 10}}x{{end}}{{end}}",
+		"define":            `{{define "x"}}x{{end}}{{.Message}}`,
+		"huge printf width": `{{printf "%999999999d" 1}}`,
+		"printf star width": `{{printf "%*d" 999999999 1}}`,
+		"huge output":       strings.Repeat("{{.Message}}", 100),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseTemplate(text)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestDefaultTemplateTranslations(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetBundlePath").Return("../..", nil)
+	api.On("GetUser", "german-user").Return(&model.User{Locale: "de"}, nil)
+	api.On("GetUser", "spanish-user").Return(&model.User{Locale: "es"}, nil)
+	localizer, err := i18n.NewLocalizer(api)
+	require.NoError(t, err)
+
+	for userID, expected := range map[string][2]string{
+		"german-user": {
+			"@alice hat dich in Town Square erwähnt: @bob can you take a look at the release notes?\nAngehängt: release-notes.pdf (240.0 KB, application/pdf)\nhttps://mattermost.example.com/engineering/pl/8kgmsw8o1fbj8rkqfr4dqzmafe",
+			"@alice in ~town-square",
+		},
+		"spanish-user": {
+			"@alice te mencionó en Town Square: @bob can you take a look at the release notes?\nAdjuntos: release-notes.pdf (240.0 KB, application/pdf)\nhttps://mattermost.example.com/engineering/pl/8kgmsw8o1fbj8rkqfr4dqzmafe",
+			"@alice en ~town-square",
+		},
+	} {
+		t.Run(userID, func(t *testing.T) {
+			message, err := RenderTemplate(DefaultTemplate(localizer, userID, false), &SampleTemplateData, FormatPlain)
+			assert.NoError(t, err)
+			assert.Equal(t, expected[0], message)
+
+			title, err := RenderTemplate(DefaultTemplate(localizer, userID, true), &SampleTemplateData, FormatPlain)
+			assert.NoError(t, err)
+			assert.Equal(t, expected[1], title)
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package notification
+
+// titleSchemes are the schemes with a title or subject param. Shoutrrr's smtp and zulip services
+// accept title as an alias of their subject and topic. Telegram only shows titles in HTML and
+// plain text messages, and the Mattermost and Matrix services ignore them.
+var titleSchemes = map[string]bool{
+	"bark":       true,
+	"discord":    true,
+	"generic":    true,
+	"gotify":     true,
+	"ifttt":      true,
+	"join":       true,
+	"ntfy":       true,
+	"opsgenie":   true,
+	"pushbullet": true,
+	"pushover":   true,
+	"slack":      true,
+	"smtp":       true,
+	"teams":      true,
+	"telegram":   true,
+	"zulip":      true,
+}
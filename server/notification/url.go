@@ -0,0 +1,76 @@
+package notification
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/containrrr/shoutrrr/pkg/router"
+)
+
+// ValidateURL returns the error of Shoutrrr if it doesn't support the URL's service or can't parse its config.
+func ValidateURL(serviceURL string) error {
+	var serviceRouter router.ServiceRouter
+	_, err := serviceRouter.Locate(serviceURL)
+	return err
+}
+
+// RedactURL returns a Shoutrrr URL with its credentials, path and query hidden, e.g.
+// telegram://***@telegram/*** for a Telegram URL, so that it can be displayed.
+func RedactURL(serviceURL string) string {
+	parsed, err := url.Parse(serviceURL)
+	if err != nil || parsed.Scheme == "" {
+		return "***"
+	}
+
+	redacted := &url.URL{Scheme: parsed.Scheme, Host: parsed.Host}
+	if parsed.User != nil {
+		redacted.User = url.User("***")
+	}
+	if parsed.Path != "" && parsed.Path != "/" || parsed.RawQuery != "" {
+		redacted.Path = "/***"
+	}
+
+	// url.URL escapes the asterisks of the user info
+	redactedURL, _ := url.PathUnescape(redacted.String())
+	return redactedURL
+}
+
+// minSecretLength is the length under which the parts of a URL aren't considered secrets, as
+// hiding every short word of an error would make it unreadable.
+const minSecretLength = 4
+
+// RedactError returns the message of an error sending to a Shoutrrr URL with the URL's
+// credentials, path and query hidden, as errors can quote the URL or the API endpoints built
+// from its parts.
+func RedactError(serviceURL string, err error) string {
+	message := strings.ReplaceAll(err.Error(), serviceURL, RedactURL(serviceURL))
+
+	parsed, parseErr := url.Parse(serviceURL)
+	if parseErr != nil {
+		return message
+	}
+
+	var secrets []string
+	if parsed.User != nil {
+		secrets = append(secrets, parsed.User.Username())
+		if password, ok := parsed.User.Password(); ok {
+			secrets = append(secrets, password)
+		}
+	}
+	secrets = append(secrets, strings.Split(parsed.Path, "/")...)
+	for _, values := range parsed.Query() {
+		secrets = append(secrets, values...)
+	}
+
+	// The longest first, so that a secret containing another one is hidden whole
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	for _, secret := range secrets {
+		if len(secret) < minSecretLength {
+			continue
+		}
+		message = strings.ReplaceAll(message, secret, "***")
+		message = strings.ReplaceAll(message, url.QueryEscape(secret), "***")
+	}
+	return message
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// persistentMentionTypes are the mentions that keep being notified for urgent posts. Like Mattermost
+// itself, channel-wide and thread mentions only get the first notification.
+var persistentMentionTypes = map[string]bool{
+	notification.MentionTypeDM:      true,
+	notification.MentionTypeGM:      true,
+	notification.MentionTypeKeyword: true,
+	notification.MentionTypeGroup:   true,
+}
+
+// isPersistentNotification returns whether the post is urgent and asks for persistent notifications
+func isPersistentNotification(post *model.Post) bool {
+	persistent := post.GetPersistentNotification()
+	return post.IsUrgent() && persistent != nil && *persistent
+}
+
+// trackPersistentNotification stores the users mentioned by an urgent post so that the persistent
+// notifications job keeps notifying them until they acknowledge it.
+func (p *Plugin) trackPersistentNotification(post *model.Post, mentionTypes map[string]string) {
+	if !*p.API.GetConfig().ServiceSettings.AllowPersistentNotifications {
+		return
+	}
+
+	mentions := make(map[string]string)
+	for userID, mentionType := range mentionTypes {
+		if userID != post.UserId && persistentMentionTypes[mentionType] {
+			mentions[userID] = mentionType
+		}
+	}
+	if len(mentions) == 0 {
+		return
+	}
+
+	now := model.GetMillis()
+	err := p.kvstore.SavePersistentNotification(&kvstore.PersistentNotification{
+		PostID:     post.Id,
+		Mentions:   mentions,
+		CreateAt:   now,
+		LastSentAt: now,
+		SentCount:  1,
+	})
+	if err != nil {
+		p.API.LogError("Failed to save persistent notification", "error", err.Error(), "postId", post.Id)
+	}
+}
+
+// runPersistentNotificationsJob re-sends the notifications of urgent posts on the server's
+// persistent notification interval, until every user acknowledged them or the maximum count is reached.
+func (p *Plugin) runPersistentNotificationsJob() {
+	notifications, err := p.kvstore.ListPersistentNotifications()
+	if err != nil {
+		p.API.LogError("Failed to list persistent notifications", "error", err.Error())
+		return
+	}
+
+	serviceSettings := p.API.GetConfig().ServiceSettings
+	interval := time.Duration(*serviceSettings.PersistentNotificationIntervalMinutes) * time.Minute
+	maxCount := *serviceSettings.PersistentNotificationMaxCount
+
+	for _, persistentNotification := range notifications {
+		p.processPersistentNotification(persistentNotification, interval, maxCount)
+	}
+}
+
+func (p *Plugin) processPersistentNotification(persistentNotification *kvstore.PersistentNotification, interval time.Duration, maxCount int) {
+	if model.GetMillis()-persistentNotification.LastSentAt < interval.Milliseconds() {
+		return
+	}
+
+	post, appErr := p.API.GetPost(persistentNotification.PostID)
+	if appErr != nil && appErr.StatusCode != http.StatusNotFound {
+		p.API.LogError("Failed to get post for persistent notification", "error", appErr.Error(), "postId", persistentNotification.PostID)
+		return
+	}
+
+	if post == nil || post.DeleteAt != 0 || persistentNotification.SentCount >= maxCount {
+		p.stopPersistentNotification(persistentNotification.PostID)
+		return
+	}
+
+	acknowledged, err := p.getAcknowledgedUserIDs(post)
+	if err != nil {
+		p.API.LogError("Failed to get acknowledgements for persistent notification", "error", err.Error(), "postId", post.Id)
+		return
+	}
+
+	for userID := range acknowledged {
+		delete(persistentNotification.Mentions, userID)
+	}
+	if len(persistentNotification.Mentions) == 0 {
+		p.stopPersistentNotification(persistentNotification.PostID)
+		return
+	}
+
+	// Make sure the reminders are still delivered as urgent, whether or not the API included the
+	// post's priority metadata.
+	if post.Metadata == nil {
+		post.Metadata = &model.PostMetadata{}
+	}
+	post.Metadata.Priority = &model.PostPriority{
+		Priority:                model.NewPointer(model.PostPriorityUrgent),
+		PersistentNotifications: model.NewPointer(true),
+	}
+
+	p.notifyMentionedUsers(post, persistentNotification.Mentions, persistentNotification.SentCount)
+
+	persistentNotification.LastSentAt = model.GetMillis()
+	persistentNotification.SentCount++
+	if err := p.kvstore.SavePersistentNotification(persistentNotification); err != nil {
+		p.API.LogError("Failed to save persistent notification", "error", err.Error(), "postId", post.Id)
+	}
+}
+
+func (p *Plugin) stopPersistentNotification(postID string) {
+	if err := p.kvstore.DeletePersistentNotification(postID); err != nil {
+		p.API.LogError("Failed to delete persistent notification", "error", err.Error(), "postId", postID)
+	}
+}
+
+// getAcknowledgedUserIDs returns the IDs of the users who acknowledged the post. They are read from
+// the post metadata when present, but the plugin API neither prepares that metadata nor exposes
+// acknowledgements otherwise, so they are also read from the core PostAcknowledgements table. This
+// depends on its schema (PostId, UserId and AcknowledgedAt, zero once an acknowledgement is
+// removed), and reads from a replica since a reminder acknowledged moments ago can wait for the
+// next interval.
+func (p *Plugin) getAcknowledgedUserIDs(post *model.Post) (map[string]bool, error) {
+	acknowledged := make(map[string]bool)
+	if post.Metadata != nil {
+		for _, acknowledgement := range post.Metadata.Acknowledgements {
+			if acknowledgement.AcknowledgedAt > 0 {
+				acknowledged[acknowledgement.UserId] = true
+			}
+		}
+	}
+
+	db, err := p.client.Store.GetReplicaDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT UserId FROM PostAcknowledgements WHERE PostId = $1 AND AcknowledgedAt > 0"
+	if p.client.Store.DriverName() == model.DatabaseDriverMysql {
+		query = "SELECT UserId FROM PostAcknowledgements WHERE PostId = ? AND AcknowledgedAt > 0"
+	}
+
+	rows, err := db.Query(query, post.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		acknowledged[userID] = true
+	}
+
+	return acknowledged, rows.Err()
+}
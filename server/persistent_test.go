@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPersistentNotification(t *testing.T) {
+	assert := assert.New(t)
+
+	newPost := func(priority string, persistent bool) *model.Post {
+		return &model.Post{
+			Metadata: &model.PostMetadata{
+				Priority: &model.PostPriority{
+					Priority:                model.NewPointer(priority),
+					PersistentNotifications: model.NewPointer(persistent),
+				},
+			},
+		}
+	}
+
+	assert.False(isPersistentNotification(&model.Post{}))
+	assert.False(isPersistentNotification(newPost(model.PostPriorityUrgent, false)))
+	assert.False(isPersistentNotification(newPost("important", true)))
+	assert.True(isPersistentNotification(newPost(model.PostPriorityUrgent, true)))
+}
@@ -5,9 +5,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/bridge"
 	"github.com/mattermost/mattermost-plugin-shoutrrr/server/command"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
 	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
 	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
@@ -15,6 +18,9 @@ import (
 	"github.com/pkg/errors"
 )
 
+// pluginID is the id of the plugin in plugin.json, used to build the plugin's public URLs.
+const pluginID = "com.mattermost.plugin-shoutrrr"
+
 // Plugin implements the interface expected by the Mattermost server to communicate between the server and plugin processes.
 type Plugin struct {
 	plugin.MattermostPlugin
@@ -22,17 +28,47 @@ type Plugin struct {
 	// kvstore is the client used to read/write KV records for this plugin.
 	kvstore kvstore.KVStore
 
+	// prefstore is the client used to read/write the user preferences for this plugin.
+	prefstore prefstore.PreferenceStore
+
 	// client is the Mattermost server API client.
 	client *pluginapi.Client
 
+	// localizer renders the plugin's strings in each user's language.
+	localizer *i18n.Localizer
+
 	// commandClient is the client used to register and execute slash commands.
 	commandClient command.Command
 
 	// notificationService is the client used to send notifications using Shoutrrr
 	notificationService *notification.Service
 
+	// botUserID is the user ID of the bot the plugin sends direct messages from.
+	botUserID string
+
+	// bridgeService manages the bridges forwarding the posts of channels to shared Shoutrrr URLs.
+	bridgeService *bridge.Service
+
 	backgroundJob *cluster.Job
 
+	// persistentNotificationsJob re-sends the notifications of urgent posts until they are acknowledged.
+	persistentNotificationsJob *cluster.Job
+
+	// digestJob sends the digests of the users who batch their notifications.
+	digestJob *cluster.Job
+
+	// reactionNotificationsJob sends the batched notifications of reactions to users' posts.
+	reactionNotificationsJob *cluster.Job
+
+	// snoozeJob clears the snoozes and channel mutes that expired.
+	snoozeJob *cluster.Job
+
+	// coalescedNotificationsJob sends the follow-ups of the notifications held back by the rate limits.
+	coalescedNotificationsJob *cluster.Job
+
+	// retryJob sends again the notifications that failed to be sent, once their retry is due.
+	retryJob *cluster.Job
+
 	// configurationLock synchronizes access to the configuration.
 	configurationLock sync.RWMutex
 
@@ -46,11 +82,36 @@ func (p *Plugin) OnActivate() error {
 	p.client = pluginapi.NewClient(p.API, p.Driver)
 
 	p.kvstore = kvstore.NewKVStore(p.client)
+	if err := p.kvstore.BuildIndexes(); err != nil {
+		return err
+	}
+
+	p.prefstore = prefstore.NewPreferenceStore(p.API)
 
-	p.commandClient = command.NewCommandHandler(p.client)
+	localizer, err := i18n.NewLocalizer(p.API)
+	if err != nil {
+		return err
+	}
+	p.localizer = localizer
+
+	botUserID, err := p.client.Bot.EnsureBot(&model.Bot{
+		Username:    "shoutrrr",
+		DisplayName: "Shoutrrr",
+		Description: "Sends you messages about your Shoutrrr notifications.",
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to ensure bot")
+	}
+	p.botUserID = botUserID
 
 	// Initialize notification service
-	p.notificationService = notification.NewService(p.client)
+	p.notificationService = notification.NewService(p.client, p.prefstore, p.kvstore, p.localizer, p.getNotificationConfig, p.onDeliveryFailed)
+
+	p.bridgeService = bridge.NewService(p.client, p.kvstore)
+
+	p.commandClient = command.NewCommandHandler(p.client, p.bridgeService, p.notificationService, p.kvstore, p.prefstore, p.localizer)
+
+	p.refreshPushFollowers()
 
 	job, err := cluster.Schedule(
 		p.API,
@@ -64,6 +125,78 @@ func (p *Plugin) OnActivate() error {
 
 	p.backgroundJob = job
 
+	persistentNotificationsJob, err := cluster.Schedule(
+		p.API,
+		"PersistentNotificationsJob",
+		cluster.MakeWaitForInterval(1*time.Minute),
+		p.runPersistentNotificationsJob,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule persistent notifications job")
+	}
+
+	p.persistentNotificationsJob = persistentNotificationsJob
+
+	digestJob, err := cluster.Schedule(
+		p.API,
+		"DigestJob",
+		cluster.MakeWaitForInterval(1*time.Minute),
+		p.notificationService.SendDueDigests,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule digest job")
+	}
+
+	p.digestJob = digestJob
+
+	coalescedNotificationsJob, err := cluster.Schedule(
+		p.API,
+		"CoalescedNotificationsJob",
+		cluster.MakeWaitForInterval(1*time.Minute),
+		p.notificationService.SendCoalescedNotifications,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule coalesced notifications job")
+	}
+
+	p.coalescedNotificationsJob = coalescedNotificationsJob
+
+	reactionNotificationsJob, err := cluster.Schedule(
+		p.API,
+		"ReactionNotificationsJob",
+		cluster.MakeWaitForInterval(15*time.Second),
+		p.notificationService.SendDueReactionNotifications,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule reaction notifications job")
+	}
+
+	p.reactionNotificationsJob = reactionNotificationsJob
+
+	snoozeJob, err := cluster.Schedule(
+		p.API,
+		"SnoozeJob",
+		cluster.MakeWaitForInterval(1*time.Minute),
+		p.runSnoozeJob,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule snooze job")
+	}
+
+	p.snoozeJob = snoozeJob
+
+	retryJob, err := cluster.Schedule(
+		p.API,
+		"RetryJob",
+		cluster.MakeWaitForInterval(1*time.Minute),
+		p.notificationService.SendDueRetries,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule retry job")
+	}
+
+	p.retryJob = retryJob
+
 	return nil
 }
 
@@ -74,12 +207,47 @@ func (p *Plugin) OnDeactivate() error {
 			p.API.LogError("Failed to close background job", "err", err)
 		}
 	}
+	if p.persistentNotificationsJob != nil {
+		if err := p.persistentNotificationsJob.Close(); err != nil {
+			p.API.LogError("Failed to close persistent notifications job", "err", err)
+		}
+	}
+	if p.digestJob != nil {
+		if err := p.digestJob.Close(); err != nil {
+			p.API.LogError("Failed to close digest job", "err", err)
+		}
+	}
+	if p.coalescedNotificationsJob != nil {
+		if err := p.coalescedNotificationsJob.Close(); err != nil {
+			p.API.LogError("Failed to close coalesced notifications job", "err", err)
+		}
+	}
+	if p.reactionNotificationsJob != nil {
+		if err := p.reactionNotificationsJob.Close(); err != nil {
+			p.API.LogError("Failed to close reaction notifications job", "err", err)
+		}
+	}
+	if p.retryJob != nil {
+		if err := p.retryJob.Close(); err != nil {
+			p.API.LogError("Failed to close retry job", "err", err)
+		}
+	}
+	if p.snoozeJob != nil {
+		if err := p.snoozeJob.Close(); err != nil {
+			p.API.LogError("Failed to close snooze job", "err", err)
+		}
+	}
 	return nil
 }
 
 // MessageHasBeenPosted is called after a message has been posted.
-// This hook extracts all mentions from the post and logs them.
+// This hook extracts all mentions from the post and notifies the mentioned users.
 func (p *Plugin) MessageHasBeenPosted(c *plugin.Context, post *model.Post) {
+	// The bot's own direct messages, such as failure alerts, never notify anyone
+	if post.UserId == p.botUserID {
+		return
+	}
+
 	mentions, err := p.GetAllMentions(post)
 	if err != nil {
 		p.API.LogError("Failed to get mentions from post", "error", err.Error())
@@ -96,33 +264,95 @@ func (p *Plugin) MessageHasBeenPosted(c *plugin.Context, post *model.Post) {
 		"group_mentions", formatMentionsForLog(mentions.GroupMentions),
 		"other_potential_mentions", mentions.OtherPotentialMentions)
 
-	// Send notifications to mentioned users
+	// Send notifications to all mentioned users
+	mentionTypes := make(map[string]string, len(mentions.Mentions))
+	for userID, mentionType := range mentions.Mentions {
+		mentionTypes[userID] = formatMentionType(mentionType)
+	}
+	p.notifyMentionedUsers(post, mentionTypes, 0)
+
+	if isPersistentNotification(post) {
+		p.trackPersistentNotification(post, mentionTypes)
+	}
+
+	p.notifyChannelActivity(post, mentionTypes)
+
+	p.notifySubscribers(post, mentions.Mentions)
+	p.notifyWatchers(post, mentions.Mentions)
+	p.forwardToBridges(post)
+}
+
+// notifyMentionedUsers sends a notification about the post to each user in mentionTypes, which maps
+// the ID of each user to how they were mentioned. reminder counts the reminders of urgent posts,
+// zero for the first notification.
+func (p *Plugin) notifyMentionedUsers(post *model.Post, mentionTypes map[string]string, reminder int) {
 	sender, err := p.API.GetUser(post.UserId)
 	if err != nil {
 		p.API.LogError("Failed to get sender for notification", "error", err.Error())
 		return
 	}
 
-	// Extract post message to use in notification
-	message := post.Message
-	if len(message) > 100 {
-		message = message[:97] + "..."
+	channel, err := p.API.GetChannel(post.ChannelId)
+	if err != nil {
+		p.API.LogError("Failed to get channel for notification", "error", err.Error())
+		return
 	}
 
-	// Send notifications to all mentioned users
-	for userID := range mentions.Mentions {
+	// Direct and group messages don't belong to a team
+	team := &model.Team{}
+	if channel.TeamId != "" {
+		team, err = p.API.GetTeam(channel.TeamId)
+		if err != nil {
+			p.API.LogError("Failed to get team for notification", "error", err.Error())
+			return
+		}
+	}
+
+	excerptBuilder := p.newExcerptBuilder(channel)
+
+	var threadRootMessage *notification.Excerpt
+	if post.RootId != "" {
+		rootPost, err := p.API.GetPost(post.RootId)
+		if err != nil {
+			p.API.LogWarn("Failed to get thread root for notification", "error", err.Error())
+		} else {
+			threadRootMessage = excerptBuilder.Build(rootPost.Message)
+		}
+	}
+
+	// Extract post message to use in notification
+	message := excerptBuilder.Build(post.Message)
+	attachments, imageURL := p.getAttachments(post)
+	senderDisplayName := sender.GetDisplayName(*p.API.GetConfig().TeamSettings.TeammateNameDisplay)
+
+	for userID, mentionType := range mentionTypes {
 		// Don't send notifications to the post author
 		if userID == post.UserId {
 			continue
 		}
 
-		appErr := p.notificationService.SendMentionNotification(
-			userID,
-			post.Id,
-			channel.DisplayName,
-			sender.Username,
-			message,
-		)
+		if !p.allowedByPushSettings(userID, channel, mentionType) {
+			continue
+		}
+
+		appErr := p.notificationService.SendMentionNotification(&notification.Mention{
+			UserID:            userID,
+			PostID:            post.Id,
+			ChannelID:         channel.Id,
+			Channel:           channel.DisplayName,
+			ChannelName:       channel.Name,
+			Team:              team.DisplayName,
+			TeamName:          team.Name,
+			MentionedBy:       sender.Username,
+			SenderDisplayName: senderDisplayName,
+			Message:           message,
+			ThreadRootMessage: threadRootMessage,
+			Type:              mentionType,
+			PostPriority:      getPostPriority(post),
+			Attachments:       attachments,
+			ImageURL:          imageURL,
+			Reminder:          reminder,
+		})
 		if appErr != nil {
 			p.API.LogError("Failed to send mention notification",
 				"error", appErr.Error(),
@@ -131,6 +361,42 @@ func (p *Plugin) MessageHasBeenPosted(c *plugin.Context, post *model.Post) {
 	}
 }
 
+// newExcerptBuilder returns a builder for the excerpts of the posts of a channel, resolving the
+// users and the public channels of the channel's team that the posts reference.
+func (p *Plugin) newExcerptBuilder(channel *model.Channel) *notification.ExcerptBuilder {
+	teammateNameDisplay := *p.API.GetConfig().TeamSettings.TeammateNameDisplay
+
+	return &notification.ExcerptBuilder{
+		MaxLength: p.getConfiguration().ExcerptLength,
+		ResolveUser: func(username string) (string, bool) {
+			user, err := p.API.GetUserByUsername(username)
+			if err != nil {
+				return "", false
+			}
+			return user.GetDisplayName(teammateNameDisplay), true
+		},
+		ResolveChannel: func(channelName string) (string, bool) {
+			if channel.TeamId == "" {
+				return "", false
+			}
+			referenced, err := p.API.GetChannelByName(channel.TeamId, channelName, false)
+			if err != nil || referenced.Type != model.ChannelTypeOpen {
+				return "", false
+			}
+			return referenced.DisplayName, true
+		},
+	}
+}
+
+// getPostPriority returns the message priority set on the post, or an empty string if it has none
+func getPostPriority(post *model.Post) string {
+	priority := post.GetPriority()
+	if priority == nil || priority.Priority == nil {
+		return ""
+	}
+	return *priority.Priority
+}
+
 // formatMentionsForLog converts a map of mentions to a comma-separated string for logging
 func formatMentionsForLog(mentions map[string]MentionType) string {
 	if len(mentions) == 0 {
@@ -154,6 +420,8 @@ func formatMentionType(mentionType MentionType) string {
 	switch mentionType {
 	case NoMention:
 		return "none"
+	case WatchMention:
+		return "watch"
 	case GMMention:
 		return "gm"
 	case ThreadMention:
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/golang/mock/gomock"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/command/mocks"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,3 +33,52 @@ func TestServeHTTP(t *testing.T) {
 
 	assert.Equal("Hello, world!", bodyString)
 }
+
+func TestPreviewTemplate(t *testing.T) {
+	assert := assert.New(t)
+	plugin := Plugin{}
+
+	preview := func(body string) (int, map[string]string) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/templates/preview", strings.NewReader(body))
+		r.Header.Set("Mattermost-User-ID", "test-user-id")
+
+		plugin.ServeHTTP(nil, w, r)
+
+		result := w.Result()
+		defer result.Body.Close()
+		var response map[string]string
+		assert.Nil(json.NewDecoder(result.Body).Decode(&response))
+		return result.StatusCode, response
+	}
+
+	statusCode, response := preview(`{"template": "@{{.SenderUsername}}: {{.Message}}"}`)
+	assert.Equal(http.StatusOK, statusCode)
+	assert.Equal("@alice: @bob can you take a look at the release notes?", response["preview"])
+
+	statusCode, response = preview(`{"template": "", "title": true}`)
+	assert.Equal(http.StatusOK, statusCode)
+	assert.Equal("@alice in ~town-square", response["preview"])
+
+	statusCode, response = preview(`{"template": "{{.Unknown}}"}`)
+	assert.Equal(http.StatusBadRequest, statusCode)
+	assert.NotEmpty(response["error"])
+}
+
+func TestExecuteCommand(t *testing.T) {
+	assert := assert.New(t)
+	ctrl := gomock.NewController(t)
+	commandClient := mocks.NewMockCommand(ctrl)
+	plugin := Plugin{commandClient: commandClient}
+
+	args := &model.CommandArgs{Command: "/shoutrrr list"}
+	commandClient.EXPECT().Handle(args).Return(&model.CommandResponse{Text: "Your targets:"}, nil)
+	response, appErr := plugin.ExecuteCommand(nil, args)
+	assert.Nil(appErr)
+	assert.Equal("Your targets:", response.Text)
+
+	commandClient.EXPECT().Handle(args).Return(nil, errors.New("boom"))
+	response, appErr = plugin.ExecuteCommand(nil, args)
+	assert.Nil(response)
+	assert.Equal(http.StatusInternalServerError, appErr.StatusCode)
+}
@@ -0,0 +1,62 @@
+// Package postfilter selects the channel posts forwarded to subscriptions and bridges.
+package postfilter
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// Filter selects which posts of a channel are forwarded.
+type Filter struct {
+	// Senders lists the usernames whose posts are forwarded, empty for everyone.
+	Senders []string `json:"senders,omitempty"`
+
+	// Keywords lists the words of which a post must contain one to be forwarded, empty for every post.
+	Keywords []string `json:"keywords,omitempty"`
+
+	// RootOnly forwards only the posts starting a thread, not the replies.
+	RootOnly bool `json:"root_only,omitempty"`
+}
+
+// Matches returns whether a post passes the filter.
+func (f *Filter) Matches(post *model.Post, senderUsername string) bool {
+	if f.RootOnly && post.RootId != "" {
+		return false
+	}
+
+	if len(f.Senders) > 0 && !slices.Contains(f.Senders, strings.ToLower(senderUsername)) {
+		return false
+	}
+
+	if len(f.Keywords) == 0 {
+		return true
+	}
+	message := strings.ToLower(post.Message)
+	for _, keyword := range f.Keywords {
+		if strings.Contains(message, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize returns the filter with its entries lowercased, without the empty ones and the @ of usernames.
+func (f Filter) Normalize() Filter {
+	return Filter{
+		Senders:  normalizeFilterEntries(f.Senders),
+		Keywords: normalizeFilterEntries(f.Keywords),
+		RootOnly: f.RootOnly,
+	}
+}
+
+func normalizeFilterEntries(entries []string) []string {
+	var normalized []string
+	for _, entry := range entries {
+		if entry = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(entry), "@")); entry != "" {
+			normalized = append(normalized, entry)
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,31 @@
+package postfilter
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterMatches(t *testing.T) {
+	assert := assert.New(t)
+
+	rootPost := &model.Post{Message: "Database is DOWN in eu-west"}
+	reply := &model.Post{Message: "Looking into it", RootId: "root"}
+
+	all := &Filter{}
+	assert.True(all.Matches(rootPost, "alice"))
+	assert.True(all.Matches(reply, "bob"))
+
+	rootOnly := &Filter{RootOnly: true}
+	assert.True(rootOnly.Matches(rootPost, "alice"))
+	assert.False(rootOnly.Matches(reply, "bob"))
+
+	senders := Filter{Senders: []string{"@Alice", " "}}.Normalize()
+	assert.True(senders.Matches(rootPost, "alice"))
+	assert.False(senders.Matches(reply, "bob"))
+
+	keywords := Filter{Keywords: []string{"Down", "outage"}}.Normalize()
+	assert.True(keywords.Matches(rootPost, "alice"))
+	assert.False(keywords.Matches(reply, "bob"))
+}
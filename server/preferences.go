@@ -0,0 +1,73 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+)
+
+// PreferencesHaveChanged reacts to the users' settings, whether they were saved from the settings
+// or by a slash command: it welcomes the users who configure their first targets, forgets the
+// delivery statuses of removed targets and keeps the index of the users following their push
+// preferences up to date.
+func (p *Plugin) PreferencesHaveChanged(c *plugin.Context, preferences []model.Preference) {
+	for _, preference := range preferences {
+		if preference.Category != prefstore.Category {
+			continue
+		}
+
+		switch preference.Name {
+		case prefstore.NotificationServices:
+			p.updateTargets(preference.UserId, preference.Value)
+		case prefstore.FollowPushSettings:
+			p.updatePushFollower(preference.UserId, preference.Value == "true")
+		}
+	}
+}
+
+// updateTargets reacts to a new list of the user's services, unless its targets didn't change,
+// as the settings save the list along with every other setting.
+func (p *Plugin) updateTargets(userID, services string) {
+	var targetIDs []string
+	for _, serviceURL := range prefstore.SplitList(services) {
+		targetIDs = append(targetIDs, prefstore.TargetID(serviceURL))
+	}
+
+	previous, changed, err := p.kvstore.SaveTargetIDs(userID, targetIDs)
+	if err != nil {
+		p.API.LogError("Failed to save target IDs", "error", err.Error(), "userId", userID)
+		return
+	}
+	if !changed {
+		return
+	}
+
+	if len(targetIDs) > 0 {
+		p.sendWelcomeMessage(userID)
+	}
+	p.resetRemovedTargets(userID, previous, targetIDs)
+}
+
+// resetRemovedTargets forgets the delivery statuses of the user's previous targets that aren't in
+// their current ones, so that they're neither counted as failing nor kept forever.
+func (p *Plugin) resetRemovedTargets(userID string, previous, current []string) {
+	if previous == nil {
+		// The targets saved before their IDs were recorded are told by their delivery statuses
+		var err error
+		if previous, err = p.kvstore.ListDeliveryTargetIDs(userID); err != nil {
+			p.API.LogError("Failed to list delivery statuses", "error", err.Error(), "userId", userID)
+			return
+		}
+	}
+
+	for _, targetID := range previous {
+		if slices.Contains(current, targetID) {
+			continue
+		}
+		if err := p.kvstore.ResetDeliveryStatus(userID, targetID); err != nil {
+			p.API.LogError("Failed to reset delivery status", "error", err.Error(), "userId", userID)
+		}
+	}
+}
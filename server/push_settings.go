@@ -0,0 +1,291 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// channelMembersPerPage is the page size used when going through a user's channel memberships.
+const channelMembersPerPage = 200
+
+// followsPushSettings returns whether the user wants their notifications to follow their Mattermost
+// push notification preferences, as a drop-in replacement for mobile push.
+func (p *Plugin) followsPushSettings(userID string) bool {
+	follow, err := p.prefstore.GetPreference(userID, prefstore.FollowPushSettings)
+	if err != nil {
+		p.API.LogWarn("Failed to get user push settings preference", "userId", userID, "error", err.Error())
+		return false
+	}
+	return follow == "true"
+}
+
+// allowedByPushSettings returns whether the user would get a Mattermost push notification for a
+// post in the channel, if they chose to follow their push preferences. Users who didn't are always
+// notified.
+func (p *Plugin) allowedByPushSettings(userID string, channel *model.Channel, mentionType string) bool {
+	if !p.followsPushSettings(userID) {
+		return true
+	}
+
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		p.API.LogWarn("Failed to get user for push settings", "userId", userID, "error", appErr.Error())
+		return true
+	}
+
+	member, appErr := p.API.GetChannelMember(channel.Id, userID)
+	if appErr != nil {
+		// Subscriptions and watch rules can match channels the user isn't a member of
+		member = &model.ChannelMember{NotifyProps: model.GetDefaultChannelNotifyProps()}
+	}
+
+	status, appErr := p.API.GetUserStatus(userID)
+	if appErr != nil {
+		status = &model.Status{UserId: userID, Status: model.StatusOffline}
+	}
+
+	return pushAllows(user.NotifyProps, member.NotifyProps, status, channel, mentionType, p.IsCRTEnabledForUser(userID))
+}
+
+// getPushLevel returns the push level of a channel member: the channel's own setting, or the
+// user's global setting if the channel uses the default.
+func getPushLevel(userProps, channelProps model.StringMap) string {
+	if level := channelProps[model.PushNotifyProp]; level != "" && level != model.ChannelNotifyDefault {
+		return level
+	}
+	if level := userProps[model.PushNotifyProp]; level != "" {
+		return level
+	}
+	return model.UserNotifyMention
+}
+
+// pushAllows mirrors how the Mattermost server decides to send a push notification, given the
+// user's and the channel member's notify props, the user's status and how the user was notified
+// of the post.
+func pushAllows(userProps, channelProps model.StringMap, status *model.Status, channel *model.Channel, mentionType string, crtEnabled bool) bool {
+	// Muted channels never push
+	if channelProps[model.MarkUnreadNotifyProp] == model.ChannelMarkUnreadMention {
+		return false
+	}
+
+	switch getPushLevel(userProps, channelProps) {
+	case model.UserNotifyNone:
+		return false
+	case model.UserNotifyMention:
+		switch mentionType {
+		case notification.MentionTypeActivity:
+			return false
+		case notification.MentionTypeThread, notification.MentionTypeComment:
+			// Replies to followed threads push only with the "all" thread setting once threads are collapsed
+			if crtEnabled && userProps[model.PushThreadsNotifyProp] != model.UserNotifyAll {
+				return false
+			}
+		}
+	}
+
+	return pushStatusAllows(userProps, status, channel.Id, model.GetMillis())
+}
+
+// pushStatusAllows returns whether the user's status allows push notifications: never in do not
+// disturb or out of office, otherwise when the user is at most as active as their push_status
+// preference and isn't looking at the channel.
+func pushStatusAllows(userProps model.StringMap, status *model.Status, channelID string, now int64) bool {
+	if status.Status == model.StatusDnd || status.Status == model.StatusOutOfOffice {
+		return false
+	}
+
+	pushStatus, ok := userProps[model.PushStatusNotifyProp]
+	switch {
+	case pushStatus == model.StatusOnline || !ok:
+		return status.ActiveChannel != channelID || now-status.LastActivityAt > model.StatusChannelTimeout
+	case pushStatus == model.StatusAway:
+		return status.Status == model.StatusAway || status.Status == model.StatusOffline
+	case pushStatus == model.StatusOffline:
+		return status.Status == model.StatusOffline
+	default:
+		return false
+	}
+}
+
+// notifyChannelActivity notifies the members of the channel who follow their push preferences and
+// set them to push every post of the channel, except the users already notified of the post.
+func (p *Plugin) notifyChannelActivity(post *model.Post, mentionTypes map[string]string) {
+	if post.IsSystemMessage() {
+		return
+	}
+
+	activity := p.getActivityRecipients(post, mentionTypes)
+	if len(activity) == 0 {
+		return
+	}
+
+	p.notifyMentionedUsers(post, activity, 0)
+}
+
+// getActivityRecipients returns the users to notify of the activity of the post's channel. Only the
+// users in the index of push followers are checked, rather than every member of the channel.
+func (p *Plugin) getActivityRecipients(post *model.Post, mentionTypes map[string]string) map[string]string {
+	followers, err := p.kvstore.GetPushFollowers()
+	if err != nil {
+		p.API.LogError("Failed to get push followers for activity notifications", "error", err.Error())
+		return nil
+	}
+
+	var candidates []string
+	for _, userID := range followers {
+		if _, notified := mentionTypes[userID]; !notified && userID != post.UserId {
+			candidates = append(candidates, userID)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// The followers who aren't members of the channel are left out
+	members, appErr := p.API.GetChannelMembersByIds(post.ChannelId, candidates)
+	if appErr != nil {
+		p.API.LogError("Failed to get channel members for activity notifications", "channelId", post.ChannelId, "error", appErr.Error())
+		return nil
+	}
+
+	activity := make(map[string]string)
+	var defaultLevel []string
+	for _, member := range members {
+		switch member.NotifyProps[model.PushNotifyProp] {
+		case model.UserNotifyAll:
+			activity[member.UserId] = notification.MentionTypeActivity
+		case "", model.ChannelNotifyDefault:
+			defaultLevel = append(defaultLevel, member.UserId)
+		}
+	}
+	if len(defaultLevel) == 0 {
+		return activity
+	}
+
+	users, appErr := p.API.GetUsersByIds(defaultLevel)
+	if appErr != nil {
+		p.API.LogError("Failed to get users for activity notifications", "error", appErr.Error())
+		return activity
+	}
+	for _, user := range users {
+		if user.NotifyProps[model.PushNotifyProp] == model.UserNotifyAll {
+			activity[user.Id] = notification.MentionTypeActivity
+		}
+	}
+	return activity
+}
+
+// updatePushFollower indexes the user as notified of channel activity if they follow their push
+// preferences and their push level is "all" in some channel, globally or for the channel.
+func (p *Plugin) updatePushFollower(userID string, follows bool) {
+	if follows {
+		pushesAll, err := p.pushesAllPosts(userID)
+		if err != nil {
+			p.API.LogWarn("Failed to get user push level", "userId", userID, "error", err.Error())
+			return
+		}
+		follows = pushesAll
+	}
+
+	if err := p.kvstore.SetPushFollower(userID, follows); err != nil {
+		p.API.LogError("Failed to update push followers", "error", err.Error(), "userId", userID)
+	}
+}
+
+// pushesAllPosts returns whether the user's push level is "all", globally or in one of their
+// channels. Direct and group messages are left out, as each of their posts is already a mention.
+func (p *Plugin) pushesAllPosts(userID string) (bool, error) {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return false, appErr
+	}
+	if user.NotifyProps[model.PushNotifyProp] == model.UserNotifyAll {
+		return true, nil
+	}
+
+	teams, appErr := p.API.GetTeamsForUser(userID)
+	if appErr != nil {
+		return false, appErr
+	}
+	for _, team := range teams {
+		for page := 0; ; page++ {
+			members, appErr := p.API.GetChannelMembersForUser(team.Id, userID, page, channelMembersPerPage)
+			if appErr != nil {
+				return false, appErr
+			}
+			for _, member := range members {
+				if member.NotifyProps[model.PushNotifyProp] == model.UserNotifyAll {
+					return true, nil
+				}
+			}
+			if len(members) < channelMembersPerPage {
+				break
+			}
+		}
+	}
+	return false, nil
+}
+
+// refreshPushFollowers rebuilds the index of the users notified of channel activity from the users
+// following their push preferences. Push levels change without any hook, so it runs hourly as well
+// as on activation, which also indexes the users who followed them before the index existed.
+func (p *Plugin) refreshPushFollowers() {
+	followers, err := p.getPushSettingsFollowers()
+	if err != nil {
+		p.API.LogError("Failed to get the users following their push preferences", "error", err.Error())
+		return
+	}
+	p.indexPushFollowers(followers)
+}
+
+// indexPushFollowers updates the index of the users notified of channel activity from the users
+// following their push preferences.
+func (p *Plugin) indexPushFollowers(followers []string) {
+	indexed, err := p.kvstore.GetPushFollowers()
+	if err != nil {
+		p.API.LogError("Failed to get push followers", "error", err.Error())
+		return
+	}
+
+	following := make(map[string]bool, len(followers))
+	for _, userID := range followers {
+		following[userID] = true
+		p.updatePushFollower(userID, true)
+	}
+	for _, userID := range indexed {
+		if !following[userID] {
+			p.updatePushFollower(userID, false)
+		}
+	}
+}
+
+// getPushSettingsFollowers returns the users who chose to follow their push preferences. The API
+// can only get the preferences of a given user, so they are read from the database.
+func (p *Plugin) getPushSettingsFollowers() ([]string, error) {
+	db, err := p.client.Store.GetReplicaDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT UserId FROM Preferences WHERE Category = $1 AND Name = $2 AND Value = 'true'"
+	if p.client.Store.DriverName() == model.DatabaseDriverMysql {
+		query = "SELECT UserId FROM Preferences WHERE Category = ? AND Name = ? AND Value = 'true'"
+	}
+
+	rows, err := db.Query(query, prefstore.Category, prefstore.FollowPushSettings)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
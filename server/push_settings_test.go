@@ -0,0 +1,249 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// memoryKVStore keeps the KV data the plugin's hooks and jobs read and write in memory, failing on
+// anything else.
+type memoryKVStore struct {
+	kvstore.KVStore
+	pushFollowers []string
+	deliveries    map[string]*kvstore.DeliveryStatus
+	targetIDs     []string
+}
+
+func (s *memoryKVStore) ListDeliveryStatuses() ([]*kvstore.DeliveryStatus, error) {
+	var statuses []*kvstore.DeliveryStatus
+	for _, status := range s.deliveries {
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (s *memoryKVStore) ListDeliveryTargetIDs(string) ([]string, error) {
+	var targetIDs []string
+	for targetID := range s.deliveries {
+		targetIDs = append(targetIDs, targetID)
+	}
+	return targetIDs, nil
+}
+
+func (s *memoryKVStore) ResetDeliveryStatus(_, targetID string) error {
+	delete(s.deliveries, targetID)
+	return nil
+}
+
+func (s *memoryKVStore) SaveTargetIDs(_ string, targetIDs []string) ([]string, bool, error) {
+	previous := s.targetIDs
+	s.targetIDs = targetIDs
+	return previous, !slices.Equal(previous, targetIDs), nil
+}
+
+func (s *memoryKVStore) SetPushFollower(userID string, follows bool) error {
+	var followers []string
+	for _, follower := range s.pushFollowers {
+		if follower != userID {
+			followers = append(followers, follower)
+		}
+	}
+	if follows {
+		followers = append(followers, userID)
+	}
+	s.pushFollowers = followers
+	return nil
+}
+
+func (s *memoryKVStore) GetPushFollowers() ([]string, error) {
+	return s.pushFollowers, nil
+}
+
+func TestPushAllows(t *testing.T) {
+	channel := &model.Channel{Id: "channel"}
+	offline := &model.Status{Status: model.StatusOffline}
+	defaultChannel := model.GetDefaultChannelNotifyProps()
+
+	for _, tc := range []struct {
+		name         string
+		userProps    model.StringMap
+		channelProps model.StringMap
+		mentionType  string
+		crt          bool
+		allowed      bool
+	}{
+		{
+			name:         "mentions notify mentions",
+			userProps:    model.StringMap{model.PushNotifyProp: model.UserNotifyMention},
+			channelProps: defaultChannel,
+			mentionType:  notification.MentionTypeKeyword,
+			allowed:      true,
+		},
+		{
+			name:         "mentions don't notify activity",
+			userProps:    model.StringMap{model.PushNotifyProp: model.UserNotifyMention},
+			channelProps: defaultChannel,
+			mentionType:  notification.MentionTypeActivity,
+		},
+		{
+			name:         "all notifies activity",
+			userProps:    model.StringMap{model.PushNotifyProp: model.UserNotifyAll},
+			channelProps: defaultChannel,
+			mentionType:  notification.MentionTypeActivity,
+			allowed:      true,
+		},
+		{
+			name:         "none notifies nothing",
+			userProps:    model.StringMap{model.PushNotifyProp: model.UserNotifyNone},
+			channelProps: defaultChannel,
+			mentionType:  notification.MentionTypeDM,
+		},
+		{
+			name:         "channel overrides user",
+			userProps:    model.StringMap{model.PushNotifyProp: model.UserNotifyNone},
+			channelProps: model.StringMap{model.PushNotifyProp: model.UserNotifyMention},
+			mentionType:  notification.MentionTypeKeyword,
+			allowed:      true,
+		},
+		{
+			name:         "muted channel",
+			userProps:    model.StringMap{model.PushNotifyProp: model.UserNotifyAll},
+			channelProps: model.StringMap{model.MarkUnreadNotifyProp: model.ChannelMarkUnreadMention},
+			mentionType:  notification.MentionTypeKeyword,
+		},
+		{
+			name:         "threads with collapsed threads",
+			userProps:    model.StringMap{model.PushNotifyProp: model.UserNotifyMention, model.PushThreadsNotifyProp: model.UserNotifyMention},
+			channelProps: defaultChannel,
+			mentionType:  notification.MentionTypeThread,
+			crt:          true,
+		},
+		{
+			name:         "threads without collapsed threads",
+			userProps:    model.StringMap{model.PushNotifyProp: model.UserNotifyMention, model.PushThreadsNotifyProp: model.UserNotifyMention},
+			channelProps: defaultChannel,
+			mentionType:  notification.MentionTypeThread,
+			allowed:      true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.allowed, pushAllows(tc.userProps, tc.channelProps, offline, channel, tc.mentionType, tc.crt))
+		})
+	}
+}
+
+func TestPushStatusAllows(t *testing.T) {
+	assert := assert.New(t)
+
+	status := func(s string) *model.Status {
+		return &model.Status{Status: s, ActiveChannel: "other", LastActivityAt: 1000}
+	}
+	pushStatus := func(s string) model.StringMap {
+		return model.StringMap{model.PushStatusNotifyProp: s}
+	}
+
+	assert.True(pushStatusAllows(pushStatus(model.StatusOnline), status(model.StatusOnline), "channel", 2000))
+	assert.True(pushStatusAllows(model.StringMap{}, status(model.StatusOnline), "channel", 2000))
+	assert.False(pushStatusAllows(pushStatus(model.StatusOnline), status(model.StatusDnd), "channel", 2000))
+	assert.False(pushStatusAllows(pushStatus(model.StatusOnline), status(model.StatusOutOfOffice), "channel", 2000))
+
+	// Users looking at the channel aren't notified until they go idle
+	viewing := &model.Status{Status: model.StatusOnline, ActiveChannel: "channel", LastActivityAt: 1000}
+	assert.False(pushStatusAllows(pushStatus(model.StatusOnline), viewing, "channel", 2000))
+	assert.True(pushStatusAllows(pushStatus(model.StatusOnline), viewing, "channel", 1000+model.StatusChannelTimeout+1))
+
+	assert.False(pushStatusAllows(pushStatus(model.StatusAway), status(model.StatusOnline), "channel", 2000))
+	assert.True(pushStatusAllows(pushStatus(model.StatusAway), status(model.StatusAway), "channel", 2000))
+	assert.True(pushStatusAllows(pushStatus(model.StatusAway), status(model.StatusOffline), "channel", 2000))
+
+	assert.False(pushStatusAllows(pushStatus(model.StatusOffline), status(model.StatusAway), "channel", 2000))
+	assert.True(pushStatusAllows(pushStatus(model.StatusOffline), status(model.StatusOffline), "channel", 2000))
+}
+
+func TestPushFollowersIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &plugintest.API{}
+	store := &memoryKVStore{}
+	plugin := &Plugin{kvstore: store}
+	plugin.SetAPI(api)
+
+	user := func(level string) *model.User {
+		return &model.User{NotifyProps: model.StringMap{model.PushNotifyProp: level}}
+	}
+	api.On("GetUser", "alice").Return(user(model.UserNotifyAll), nil)
+	api.On("GetUser", "bob").Return(user(model.UserNotifyAll), nil)
+	api.On("GetUser", "carol").Return(user(model.UserNotifyMention), nil)
+	api.On("GetUser", "dave").Return(user(model.UserNotifyMention), nil)
+	api.On("GetTeamsForUser", mock.Anything).Return([]*model.Team{{Id: "team"}}, nil)
+	api.On("GetChannelMembersForUser", "team", "carol", 0, channelMembersPerPage).Return([]*model.ChannelMember{
+		{NotifyProps: model.StringMap{model.PushNotifyProp: model.ChannelNotifyDefault}},
+		{NotifyProps: model.StringMap{model.PushNotifyProp: model.UserNotifyAll}},
+	}, nil)
+	api.On("GetChannelMembersForUser", "team", "dave", 0, channelMembersPerPage).Return([]*model.ChannelMember{
+		{NotifyProps: model.StringMap{model.PushNotifyProp: model.ChannelNotifyDefault}},
+	}, nil)
+
+	setFollow := func(userID, value string) {
+		plugin.PreferencesHaveChanged(nil, []model.Preference{{UserId: userID, Category: prefstore.Category, Name: prefstore.FollowPushSettings, Value: value}})
+	}
+	setFollow("alice", "true")
+	setFollow("bob", "true")
+	setFollow("alice", "true")
+	assert.Equal([]string{"bob", "alice"}, store.pushFollowers)
+
+	setFollow("bob", "false")
+	assert.Equal([]string{"alice"}, store.pushFollowers)
+
+	// Only the users pushing every post of some channel are indexed
+	setFollow("carol", "true")
+	setFollow("dave", "true")
+	assert.Equal([]string{"alice", "carol"}, store.pushFollowers)
+
+	// Other preferences don't change the index
+	plugin.PreferencesHaveChanged(nil, []model.Preference{{UserId: "erin", Category: "other", Name: prefstore.FollowPushSettings, Value: "true"}})
+	assert.Equal([]string{"alice", "carol"}, store.pushFollowers)
+
+	// The index is rebuilt from the users following their push preferences
+	plugin.indexPushFollowers([]string{"bob", "carol", "dave"})
+	assert.ElementsMatch([]string{"bob", "carol"}, store.pushFollowers)
+}
+
+func TestGetActivityRecipients(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &plugintest.API{}
+	plugin := &Plugin{kvstore: &memoryKVStore{pushFollowers: []string{"all", "default-all", "default-mention", "mention", "outsider", "mentioned", "sender"}}}
+	plugin.SetAPI(api)
+
+	member := func(userID, level string) model.ChannelMember {
+		return model.ChannelMember{UserId: userID, NotifyProps: model.StringMap{model.PushNotifyProp: level}}
+	}
+	api.On("GetChannelMembersByIds", "channel", []string{"all", "default-all", "default-mention", "mention", "outsider"}).Return(model.ChannelMembers{
+		member("all", model.UserNotifyAll),
+		member("default-all", model.ChannelNotifyDefault),
+		member("default-mention", model.ChannelNotifyDefault),
+		member("mention", model.UserNotifyMention),
+	}, nil)
+	api.On("GetUsersByIds", []string{"default-all", "default-mention"}).Return([]*model.User{
+		{Id: "default-all", NotifyProps: model.StringMap{model.PushNotifyProp: model.UserNotifyAll}},
+		{Id: "default-mention", NotifyProps: model.StringMap{model.PushNotifyProp: model.UserNotifyMention}},
+	}, nil)
+
+	post := &model.Post{UserId: "sender", ChannelId: "channel"}
+	recipients := plugin.getActivityRecipients(post, map[string]string{"mentioned": notification.MentionTypeChannel})
+
+	assert.Equal(map[string]string{
+		"all":         notification.MentionTypeActivity,
+		"default-all": notification.MentionTypeActivity,
+	}, recipients)
+	api.AssertExpectations(t)
+}
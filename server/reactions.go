@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+)
+
+// ReactionHasBeenAdded is called after a reaction has been added to a post. The post author is
+// notified if they turned reaction notifications on and the emoji passes their filters.
+func (p *Plugin) ReactionHasBeenAdded(c *plugin.Context, reaction *model.Reaction) {
+	post, appErr := p.API.GetPost(reaction.PostId)
+	if appErr != nil {
+		p.API.LogError("Failed to get post for reaction notification", "error", appErr.Error(), "postId", reaction.PostId)
+		return
+	}
+
+	// Don't notify users of their own reactions, or of reactions to system messages
+	if post.UserId == reaction.UserId || post.IsSystemMessage() {
+		return
+	}
+
+	if !p.notificationService.WantsReactionNotification(post.UserId, reaction.EmojiName) {
+		return
+	}
+
+	user, appErr := p.API.GetUser(reaction.UserId)
+	if appErr != nil {
+		p.API.LogError("Failed to get user for reaction notification", "error", appErr.Error(), "userId", reaction.UserId)
+		return
+	}
+
+	channel, appErr := p.API.GetChannel(post.ChannelId)
+	if appErr != nil {
+		p.API.LogError("Failed to get channel for reaction notification", "error", appErr.Error(), "channelId", post.ChannelId)
+		return
+	}
+
+	// Direct and group messages don't belong to a team
+	team := &model.Team{}
+	if channel.TeamId != "" {
+		team, appErr = p.API.GetTeam(channel.TeamId)
+		if appErr != nil {
+			p.API.LogError("Failed to get team for reaction notification", "error", appErr.Error(), "teamId", channel.TeamId)
+			return
+		}
+	}
+
+	err := p.notificationService.AddReactionNotification(&notification.Reaction{
+		UserID:      post.UserId,
+		PostID:      post.Id,
+		ChannelID:   channel.Id,
+		Channel:     channel.DisplayName,
+		ChannelName: channel.Name,
+		TeamName:    team.Name,
+		Message:     p.newExcerptBuilder(channel).Build(post.Message),
+		ReactedBy:   user.Username,
+		EmojiName:   reaction.EmojiName,
+	})
+	if err != nil {
+		p.API.LogError("Failed to add reaction notification", "error", err.Error(), "postId", post.Id)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+var snoozeEndedMessage = &i18n.Message{
+	ID:    "snooze.ended",
+	Other: "Your snooze ended, your Shoutrrr notifications are back on.",
+}
+
+// runSnoozeJob clears the snoozes and channel mutes that expired, and lets the users who asked for
+// it know that their notifications resumed. It's called periodically by a cluster job.
+func (p *Plugin) runSnoozeJob() {
+	snoozes, err := p.kvstore.ListSnoozes()
+	if err != nil {
+		p.API.LogError("Failed to list snoozes", "error", err.Error())
+		return
+	}
+
+	now := model.GetMillis()
+	for _, pending := range snoozes {
+		if !pending.HasExpired(now) {
+			continue
+		}
+
+		var ended bool
+		_, err := p.kvstore.UpdateSnooze(pending.UserID, func(snooze *kvstore.Snooze) {
+			ended = snooze.RemoveExpired(now)
+		})
+		if err != nil {
+			p.API.LogError("Failed to clear expired snooze", "error", err.Error(), "userId", pending.UserID)
+			continue
+		}
+
+		if ended {
+			p.sendSnoozeEndedMessage(pending.UserID)
+		}
+	}
+}
+
+// sendSnoozeEndedMessage sends a direct message from the bot to a user whose snooze ended, if they
+// asked for it.
+func (p *Plugin) sendSnoozeEndedMessage(userID string) {
+	value, err := p.prefstore.GetPreference(userID, prefstore.SnoozeEndMessage)
+	if err != nil {
+		p.API.LogWarn("Failed to get snooze end message preference", "error", err.Error(), "userId", userID)
+		return
+	}
+	if value != "true" {
+		return
+	}
+
+	post := &model.Post{Message: p.localizer.Localize(userID, snoozeEndedMessage, nil)}
+	if err := p.client.Post.DM(p.botUserID, userID, post); err != nil {
+		p.API.LogError("Failed to send snooze end message", "error", err.Error(), "userId", userID)
+	}
+}
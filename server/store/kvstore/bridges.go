@@ -0,0 +1,168 @@
+package kvstore
+
+import (
+	"encoding/json"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/postfilter"
+	"github.com/pkg/errors"
+)
+
+const (
+	bridgesPrefix     = "bridges-"
+	bridgeChannelsKey = "bridge_channels"
+)
+
+// Bridge forwards the posts of a channel to a Shoutrrr URL shared by the channel, such as a team
+// room on another chat service.
+type Bridge struct {
+	ID string `json:"id"`
+
+	// URL is encrypted in the store, as it holds the credentials of the service.
+	URL string `json:"url"`
+
+	postfilter.Filter
+	CreatorID string `json:"creator_id"`
+	CreateAt  int64  `json:"create_at"`
+}
+
+func (kv Client) GetBridges(channelID string) ([]*Bridge, error) {
+	var bridges []*Bridge
+	if err := kv.client.KV.Get(bridgesPrefix+channelID, &bridges); err != nil {
+		return nil, errors.Wrap(err, "failed to get bridges")
+	}
+	if len(bridges) == 0 {
+		return nil, nil
+	}
+
+	keys, err := kv.getCredentialsKeys()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get credentials keys")
+	}
+	for _, bridge := range bridges {
+		if bridge.URL, err = decryptCredentials(keys, bridge.URL); err != nil {
+			return nil, errors.Wrap(err, "failed to decrypt bridge URL")
+		}
+	}
+	return bridges, nil
+}
+
+func (kv Client) AddBridge(channelID string, bridge *Bridge) error {
+	keys, err := kv.getCredentialsKeys()
+	if err != nil {
+		return errors.Wrap(err, "failed to get credentials keys")
+	}
+	encrypted := *bridge
+	if encrypted.URL, err = encryptCredentials(keys, bridge.URL); err != nil {
+		return errors.Wrap(err, "failed to encrypt bridge URL")
+	}
+
+	err = kv.client.KV.SetAtomicWithRetries(bridgesPrefix+channelID, func(oldValue []byte) (any, error) {
+		var bridges []*Bridge
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &bridges); err != nil {
+				return nil, err
+			}
+		}
+		return append(bridges, &encrypted), nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to save bridge")
+	}
+	if err := kv.addToIndex(bridgeChannelsKey, channelID); err != nil {
+		return errors.Wrap(err, "failed to index bridges")
+	}
+	return nil
+}
+
+// errBridgeNotFound stops DeleteBridge from writing when the bridge doesn't exist.
+var errBridgeNotFound = errors.New("bridge not found")
+
+func (kv Client) DeleteBridge(channelID, bridgeID string) (*Bridge, error) {
+	var deleted *Bridge
+	var empty bool
+	err := kv.client.KV.SetAtomicWithRetries(bridgesPrefix+channelID, func(oldValue []byte) (any, error) {
+		var bridges []*Bridge
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &bridges); err != nil {
+				return nil, err
+			}
+		}
+
+		deleted = nil
+		var remaining []*Bridge
+		for _, bridge := range bridges {
+			if bridge.ID == bridgeID {
+				deleted = bridge
+			} else {
+				remaining = append(remaining, bridge)
+			}
+		}
+		if deleted == nil {
+			return nil, errBridgeNotFound
+		}
+
+		empty = len(remaining) == 0
+		if empty {
+			return nil, nil
+		}
+		return remaining, nil
+	})
+	if errors.Is(err, errBridgeNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to delete bridge")
+	}
+
+	if empty {
+		if err := kv.unindex(bridgeChannelsKey, channelID, bridgesPrefix+channelID); err != nil {
+			return nil, errors.Wrap(err, "failed to unindex bridges")
+		}
+	}
+
+	keys, err := kv.getCredentialsKeys()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get credentials keys")
+	}
+	if deleted.URL, err = decryptCredentials(keys, deleted.URL); err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt bridge URL")
+	}
+	return deleted, nil
+}
+
+// reencryptBridges encrypts the URLs of every bridge with the current key, and returns how many
+// were re-encrypted.
+func (kv Client) reencryptBridges(keys *credentialsKeys) (int, error) {
+	channelIDs, err := kv.getIndex(bridgeChannelsKey)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, channelID := range channelIDs {
+		var reencrypted int
+		err := kv.client.KV.SetAtomicWithRetries(bridgesPrefix+channelID, func(oldValue []byte) (any, error) {
+			reencrypted = 0
+			if len(oldValue) == 0 {
+				return nil, errBridgeNotFound
+			}
+
+			var bridges []*Bridge
+			if err := json.Unmarshal(oldValue, &bridges); err != nil {
+				return nil, err
+			}
+			for _, bridge := range bridges {
+				var err error
+				if bridge.URL, err = reencryptCredentials(keys, bridge.URL); err != nil {
+					return nil, err
+				}
+				reencrypted++
+			}
+			return bridges, nil
+		})
+		if err != nil && !errors.Is(err, errBridgeNotFound) {
+			return count, err
+		}
+		count += reencrypted
+	}
+	return count, nil
+}
@@ -0,0 +1,178 @@
+package kvstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// credentialsKeysKey holds the keys encrypting the Shoutrrr URLs saved in the KV store, which hold
+// the credentials of their service.
+const credentialsKeysKey = "credentials_keys"
+
+// encryptedPrefix starts the encrypted values, followed by the ID of their key. Values without it
+// were saved before they were encrypted.
+const encryptedPrefix = "enc:"
+
+// credentialsKeyLength is the length in bytes of the generated AES-256 keys.
+const credentialsKeyLength = 32
+
+// credentialsKeys are the keys encrypting credentials, by ID. New values are encrypted with the
+// current key, the previous one is kept to read the values saved while re-encrypting.
+type credentialsKeys struct {
+	Current  string            `json:"current"`
+	Previous string            `json:"previous,omitempty"`
+	Keys     map[string][]byte `json:"keys"`
+}
+
+// rotate generates a new current key, keeping the current one as the previous key.
+func (k *credentialsKeys) rotate() error {
+	key := make([]byte, credentialsKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+
+	id := model.NewId()
+	keys := map[string][]byte{id: key}
+	if current, ok := k.Keys[k.Current]; ok {
+		keys[k.Current] = current
+	}
+	k.Previous, k.Current, k.Keys = k.Current, id, keys
+	return nil
+}
+
+// updateCredentialsKeys applies an update to the keys, and returns them.
+func (kv Client) updateCredentialsKeys(update func(keys *credentialsKeys) error) (*credentialsKeys, error) {
+	var keys *credentialsKeys
+	err := kv.client.KV.SetAtomicWithRetries(credentialsKeysKey, func(oldValue []byte) (any, error) {
+		keys = &credentialsKeys{}
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, keys); err != nil {
+				return nil, err
+			}
+		}
+		if err := update(keys); err != nil {
+			return nil, err
+		}
+		return keys, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// getCredentialsKeys returns the keys encrypting credentials, generating the first one on first use.
+func (kv Client) getCredentialsKeys() (*credentialsKeys, error) {
+	var keys *credentialsKeys
+	if err := kv.client.KV.Get(credentialsKeysKey, &keys); err != nil {
+		return nil, err
+	}
+	if keys != nil && keys.Keys[keys.Current] != nil {
+		return keys, nil
+	}
+
+	// Another server of the cluster may be generating a key at the same time, keep the first one saved.
+	return kv.updateCredentialsKeys(func(keys *credentialsKeys) error {
+		if keys.Keys[keys.Current] != nil {
+			return nil
+		}
+		return keys.rotate()
+	})
+}
+
+func newCredentialsCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptCredentials(keys *credentialsKeys, value string) (string, error) {
+	aead, err := newCredentialsCipher(keys.Keys[keys.Current])
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + keys.Current + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptCredentials(keys *credentialsKeys, value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+
+	keyID, encoded, ok := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted credentials")
+	}
+	key, ok := keys.Keys[keyID]
+	if !ok {
+		return "", errors.New("unknown credentials key")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newCredentialsCipher(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted credentials")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// reencryptCredentials decrypts a value with any key and encrypts it with the current one.
+func reencryptCredentials(keys *credentialsKeys, value string) (string, error) {
+	plain, err := decryptCredentials(keys, value)
+	if err != nil {
+		return "", err
+	}
+	return encryptCredentials(keys, plain)
+}
+
+func (kv Client) ReencryptCredentials() (int, error) {
+	keys, err := kv.getCredentialsKeys()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get credentials keys")
+	}
+	keys, err = kv.updateCredentialsKeys(func(latest *credentialsKeys) error {
+		if latest.Current != keys.Current {
+			// Another server rotated the key meanwhile
+			return nil
+		}
+		return latest.rotate()
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to rotate credentials key")
+	}
+
+	bridges, err := kv.reencryptBridges(keys)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to re-encrypt bridges")
+	}
+	retries, err := kv.reencryptRetries(keys)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to re-encrypt retries")
+	}
+	return bridges + retries, nil
+}
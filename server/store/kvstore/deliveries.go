@@ -0,0 +1,203 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	deliveryPrefix = "delivery-"
+
+	// deliveryUsersKey indexes the users with a delivery status, and the keys starting with
+	// deliveryTargetsPrefix the targets of each of them.
+	deliveryUsersKey      = "delivery_users"
+	deliveryTargetsPrefix = "delivery_targets-"
+)
+
+// recentDeliveries is the number of latest outcomes kept to tell a target's health.
+const recentDeliveries = 10
+
+// brokenFailures is the number of failures in a row after which a target is considered broken.
+const brokenFailures = 3
+
+// The health of a target, as told by its latest deliveries.
+const (
+	HealthUnknown  = "unknown"
+	HealthHealthy  = "healthy"
+	HealthDegraded = "degraded"
+	HealthBroken   = "broken"
+)
+
+// DeliveryStatus records the outcome of the recent notifications sent to one of a user's targets.
+type DeliveryStatus struct {
+	UserID   string
+	TargetID string
+
+	// LastAttemptAt and LastSuccessAt are in milliseconds since the epoch, zero if it never happened.
+	LastAttemptAt int64
+	LastSuccessAt int64
+
+	// LastError is the error of the last failed attempt, with the target's credentials redacted.
+	LastError string
+
+	// ConsecutiveFailures counts the attempts that failed since the last success.
+	ConsecutiveFailures int
+
+	// Recent holds whether each of the latest attempts succeeded, the oldest first.
+	Recent []bool
+}
+
+// Failing returns whether the last attempt failed.
+func (d *DeliveryStatus) Failing() bool {
+	return d.ConsecutiveFailures > 0
+}
+
+// Health returns whether the target is broken, failing every attempt lately, degraded, failing
+// some of its latest attempts, or healthy. A nil status is of a target never notified yet.
+func (d *DeliveryStatus) Health() string {
+	switch {
+	case d == nil:
+		return HealthUnknown
+	case d.ConsecutiveFailures >= brokenFailures:
+		return HealthBroken
+	case d.ConsecutiveFailures > 0:
+		return HealthDegraded
+	}
+
+	for _, succeeded := range d.Recent {
+		if !succeeded {
+			return HealthDegraded
+		}
+	}
+	return HealthHealthy
+}
+
+func deliveryKey(userID, targetID string) string {
+	return deliveryPrefix + userID + "-" + targetID
+}
+
+// parseDeliveryKey returns the user and target IDs of a delivery status key.
+func parseDeliveryKey(key string) (userID, targetID string, ok bool) {
+	if !strings.HasPrefix(key, deliveryPrefix) {
+		return "", "", false
+	}
+	userID, targetID, ok = strings.Cut(strings.TrimPrefix(key, deliveryPrefix), "-")
+	return userID, targetID, ok
+}
+
+func deliveryUserIndexer(key string) (string, string, bool) {
+	userID, _, ok := parseDeliveryKey(key)
+	return deliveryUsersKey, userID, ok
+}
+
+func deliveryTargetIndexer(key string) (string, string, bool) {
+	userID, targetID, ok := parseDeliveryKey(key)
+	return deliveryTargetsPrefix + userID, targetID, ok
+}
+
+func (kv Client) RecordDelivery(userID, targetID, deliveryErr string) (*DeliveryStatus, error) {
+	status := &DeliveryStatus{}
+	var created bool
+	err := kv.client.KV.SetAtomicWithRetries(deliveryKey(userID, targetID), func(oldValue []byte) (any, error) {
+		created = len(oldValue) == 0
+		status = &DeliveryStatus{UserID: userID, TargetID: targetID}
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, status); err != nil {
+				return nil, err
+			}
+		}
+
+		status.LastAttemptAt = model.GetMillis()
+		if deliveryErr == "" {
+			status.LastSuccessAt = status.LastAttemptAt
+			status.ConsecutiveFailures = 0
+		} else {
+			status.LastError = deliveryErr
+			status.ConsecutiveFailures++
+		}
+
+		status.Recent = append(status.Recent, deliveryErr == "")
+		if len(status.Recent) > recentDeliveries {
+			status.Recent = status.Recent[len(status.Recent)-recentDeliveries:]
+		}
+		return status, nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to record delivery")
+	}
+	if created {
+		if err := kv.addToIndex(deliveryTargetsPrefix+userID, targetID); err != nil {
+			return nil, errors.Wrap(err, "failed to index delivery status")
+		}
+		if err := kv.addToIndex(deliveryUsersKey, userID); err != nil {
+			return nil, errors.Wrap(err, "failed to index delivery status")
+		}
+	}
+	return status, nil
+}
+
+func (kv Client) GetDeliveryStatus(userID, targetID string) (*DeliveryStatus, error) {
+	var status *DeliveryStatus
+	if err := kv.client.KV.Get(deliveryKey(userID, targetID), &status); err != nil {
+		return nil, errors.Wrap(err, "failed to get delivery status")
+	}
+	return status, nil
+}
+
+func (kv Client) ListDeliveryStatuses() ([]*DeliveryStatus, error) {
+	userIDs, err := kv.getIndex(deliveryUsersKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list delivery statuses")
+	}
+
+	var statuses []*DeliveryStatus
+	for _, userID := range userIDs {
+		targetIDs, err := kv.getIndex(deliveryTargetsPrefix + userID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list delivery statuses")
+		}
+
+		for _, targetID := range targetIDs {
+			var status *DeliveryStatus
+			if err := kv.client.KV.Get(deliveryKey(userID, targetID), &status); err != nil {
+				return nil, errors.Wrap(err, "failed to get delivery status")
+			}
+			if status != nil {
+				statuses = append(statuses, status)
+			}
+		}
+	}
+	return statuses, nil
+}
+
+func (kv Client) ResetDeliveryStatus(userID, targetID string) error {
+	if err := kv.client.KV.Delete(deliveryKey(userID, targetID)); err != nil {
+		return errors.Wrap(err, "failed to reset delivery status")
+	}
+	if err := kv.unindex(deliveryTargetsPrefix+userID, targetID, deliveryKey(userID, targetID)); err != nil {
+		return errors.Wrap(err, "failed to unindex delivery status")
+	}
+
+	// The user is unindexed with their last target
+	targetIDs, err := kv.getIndex(deliveryTargetsPrefix + userID)
+	if err != nil {
+		return errors.Wrap(err, "failed to unindex delivery status")
+	}
+	if len(targetIDs) == 0 {
+		if err := kv.unindex(deliveryUsersKey, userID, deliveryTargetsPrefix+userID); err != nil {
+			return errors.Wrap(err, "failed to unindex delivery status")
+		}
+	}
+	return nil
+}
+
+func (kv Client) ListDeliveryTargetIDs(userID string) ([]string, error) {
+	targetIDs, err := kv.getIndex(deliveryTargetsPrefix + userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list delivery statuses")
+	}
+	return targetIDs, nil
+}
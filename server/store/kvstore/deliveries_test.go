@@ -0,0 +1,22 @@
+package kvstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliveryStatusHealth(t *testing.T) {
+	assert := assert.New(t)
+
+	var never *DeliveryStatus
+	assert.Equal(HealthUnknown, never.Health())
+
+	assert.Equal(HealthHealthy, (&DeliveryStatus{Recent: []bool{true, true}}).Health())
+	assert.Equal(HealthDegraded, (&DeliveryStatus{Recent: []bool{true, false, true}}).Health())
+	assert.Equal(HealthDegraded, (&DeliveryStatus{ConsecutiveFailures: 2, Recent: []bool{true, false, false}}).Health())
+	assert.Equal(HealthBroken, (&DeliveryStatus{ConsecutiveFailures: 3, Recent: []bool{false, false, false}}).Health())
+
+	// Statuses recorded before outcomes were kept
+	assert.Equal(HealthHealthy, (&DeliveryStatus{}).Health())
+}
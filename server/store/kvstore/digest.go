@@ -0,0 +1,114 @@
+package kvstore
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	digestPrefix = "digest-"
+	digestsKey   = "digests"
+)
+
+// Digest accumulates the mentions of a user who prefers a periodic summary to a notification per mention.
+type Digest struct {
+	UserID string
+
+	// SendAt is when the digest is due, set from the user's interval when the first mention is added.
+	SendAt int64
+
+	// Channels groups the mentions by channel, keyed by team and channel name.
+	Channels map[string]*DigestChannel
+}
+
+// DigestChannel counts the mentions of a digest in a channel and keeps a few of their excerpts.
+type DigestChannel struct {
+	Channel     string
+	ChannelName string
+	TeamName    string
+	Count       int
+	Mentions    []*DigestMention
+}
+
+// DigestMention is one of the mentions kept to be shown in a digest.
+type DigestMention struct {
+	PostID         string
+	SenderUsername string
+	Excerpt        string
+	Permalink      string
+	Priority       string
+	CreateAt       int64
+}
+
+func (kv Client) UpdateDigest(userID string, update func(digest *Digest)) error {
+	var created bool
+	err := kv.client.KV.SetAtomicWithRetries(digestPrefix+userID, func(oldValue []byte) (any, error) {
+		created = len(oldValue) == 0
+		digest := &Digest{UserID: userID, Channels: map[string]*DigestChannel{}}
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, digest); err != nil {
+				return nil, err
+			}
+		}
+
+		update(digest)
+		return digest, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to update digest")
+	}
+	if created {
+		if err := kv.addToIndex(digestsKey, userID); err != nil {
+			return errors.Wrap(err, "failed to index digest")
+		}
+	}
+	return nil
+}
+
+func (kv Client) ListDigests() ([]*Digest, error) {
+	userIDs, err := kv.getIndex(digestsKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list digests")
+	}
+
+	var digests []*Digest
+	for _, userID := range userIDs {
+		var digest *Digest
+		if err := kv.client.KV.Get(digestPrefix+userID, &digest); err != nil {
+			return nil, errors.Wrap(err, "failed to get digest")
+		}
+		if digest != nil {
+			digests = append(digests, digest)
+		}
+	}
+	return digests, nil
+}
+
+// errNoDigest stops TakeDigest when there is nothing to delete, as an atomic delete of a missing
+// key never succeeds.
+var errNoDigest = errors.New("no pending digest")
+
+func (kv Client) TakeDigest(userID string) (*Digest, error) {
+	var digest *Digest
+	err := kv.client.KV.SetAtomicWithRetries(digestPrefix+userID, func(oldValue []byte) (any, error) {
+		if len(oldValue) == 0 {
+			return nil, errNoDigest
+		}
+
+		digest = nil
+		if err := json.Unmarshal(oldValue, &digest); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if errors.Is(err, errNoDigest) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to take digest")
+	}
+	if err := kv.unindex(digestsKey, userID, digestPrefix+userID); err != nil {
+		return nil, errors.Wrap(err, "failed to unindex digest")
+	}
+	return digest, nil
+}
@@ -0,0 +1,163 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// An index is a key holding the IDs of the keys saved by a feature, so that listing them reads a
+// single key rather than paging through every key of the plugin. Keys are indexed after being
+// saved and unindexed after being deleted, with unindex, so that a key saved by another server
+// meanwhile is never left out. An index may then briefly hold the ID of a deleted key, which
+// readers skip.
+
+const (
+	// indexVersionKey holds the version of the indexes last built from the existing keys.
+	indexVersionKey = "index_version"
+
+	// indexVersion is bumped when an index is added, to index the keys saved before it existed.
+	indexVersion = 2
+
+	// listKeysPerPage is the page size used when building the indexes from every key.
+	listKeysPerPage = 100
+)
+
+// indexers return the index and ID of each key saved before its index existed.
+var indexers = []func(key string) (indexKey, id string, ok bool){
+	prefixIndexer(persistentNotificationPrefix, persistentNotificationsKey),
+	prefixIndexer(digestPrefix, digestsKey),
+	prefixIndexer(reactionBatchPrefix, reactionBatchesKey),
+	prefixIndexer(coalescedPrefix, coalescedNotificationsKey),
+	prefixIndexer(snoozePrefix, snoozesKey),
+	prefixIndexer(bridgesPrefix, bridgeChannelsKey),
+	deliveryUserIndexer,
+	deliveryTargetIndexer,
+}
+
+// prefixIndexer indexes the keys starting with prefix by the rest of the key.
+func prefixIndexer(prefix, indexKey string) func(key string) (string, string, bool) {
+	return func(key string) (string, string, bool) {
+		if !strings.HasPrefix(key, prefix) {
+			return "", "", false
+		}
+		return indexKey, strings.TrimPrefix(key, prefix), true
+	}
+}
+
+// errIndexUnchanged stops updateIndex when the update leaves the index as it was, which saves a
+// write and avoids the atomic delete of a missing key, which never succeeds.
+var errIndexUnchanged = errors.New("index unchanged")
+
+func (kv Client) updateIndex(indexKey string, ids []string, indexed bool) error {
+	err := kv.client.KV.SetAtomicWithRetries(indexKey, func(oldValue []byte) (any, error) {
+		var index []string
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &index); err != nil {
+				return nil, err
+			}
+		}
+
+		updated := make([]string, 0, len(index)+len(ids))
+		for _, id := range index {
+			if indexed || !containsString(ids, id) {
+				updated = append(updated, id)
+			}
+		}
+		if indexed {
+			for _, id := range ids {
+				if !containsString(updated, id) {
+					updated = append(updated, id)
+				}
+			}
+		}
+
+		if len(updated) == len(index) {
+			return nil, errIndexUnchanged
+		} else if len(updated) > 0 {
+			return updated, nil
+		}
+		return nil, nil
+	})
+	if err != nil && !errors.Is(err, errIndexUnchanged) {
+		return err
+	}
+	return nil
+}
+
+func (kv Client) addToIndex(indexKey string, ids ...string) error {
+	return kv.updateIndex(indexKey, ids, true)
+}
+
+func (kv Client) removeFromIndex(indexKey string, ids ...string) error {
+	return kv.updateIndex(indexKey, ids, false)
+}
+
+// unindex removes id from an index once key was deleted. The key is checked again afterwards, and
+// indexed again if another server saved it while its ID was still indexed.
+func (kv Client) unindex(indexKey, id, key string) error {
+	if err := kv.removeFromIndex(indexKey, id); err != nil {
+		return err
+	}
+
+	var value []byte
+	if err := kv.client.KV.Get(key, &value); err != nil {
+		return err
+	}
+	if len(value) > 0 {
+		return kv.addToIndex(indexKey, id)
+	}
+	return nil
+}
+
+func (kv Client) getIndex(indexKey string) ([]string, error) {
+	var ids []string
+	if err := kv.client.KV.Get(indexKey, &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// BuildIndexes indexes the keys saved before their index existed. It pages through every key of
+// the plugin, so it only runs once per index version.
+func (kv Client) BuildIndexes() error {
+	var version int
+	if err := kv.client.KV.Get(indexVersionKey, &version); err != nil {
+		return errors.Wrap(err, "failed to get index version")
+	}
+	if version >= indexVersion {
+		return nil
+	}
+
+	ids := map[string][]string{}
+	for page := 0; ; page++ {
+		keys, err := kv.client.KV.ListKeys(page, listKeysPerPage)
+		if err != nil {
+			return errors.Wrap(err, "failed to list keys")
+		}
+
+		for _, key := range keys {
+			for _, indexer := range indexers {
+				if indexKey, id, ok := indexer(key); ok {
+					ids[indexKey] = append(ids[indexKey], id)
+				}
+			}
+		}
+
+		if len(keys) < listKeysPerPage {
+			break
+		}
+	}
+
+	for indexKey, indexIDs := range ids {
+		if err := kv.addToIndex(indexKey, indexIDs...); err != nil {
+			return errors.Wrap(err, "failed to build index")
+		}
+	}
+
+	if _, err := kv.client.KV.Set(indexVersionKey, indexVersion); err != nil {
+		return errors.Wrap(err, "failed to save index version")
+	}
+	return nil
+}
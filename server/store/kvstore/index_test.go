@@ -0,0 +1,140 @@
+package kvstore
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newMemoryClient returns a client whose KV store is a map, counting the writes to each key.
+func newMemoryClient(t *testing.T) (Client, map[string][]byte, map[string]int) {
+	values := map[string][]byte{}
+	writes := map[string]int{}
+
+	api := &plugintest.API{}
+	t.Cleanup(func() { api.AssertExpectations(t) })
+	api.On("KVGet", mock.Anything).Return(func(key string) []byte {
+		return values[key]
+	}, nil).Maybe()
+	api.On("KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(func(key string, value []byte, _ model.PluginKVSetOptions) bool {
+		writes[key]++
+		if value == nil {
+			delete(values, key)
+		} else {
+			values[key] = value
+		}
+		return true
+	}, nil).Maybe()
+	api.On("KVList", mock.Anything, mock.Anything).Return(func(page, perPage int) []string {
+		var keys []string
+		for key := range values {
+			keys = append(keys, key)
+		}
+		if page > 0 {
+			return nil
+		}
+		return keys
+	}, nil).Maybe()
+
+	return Client{client: pluginapi.NewClient(api, nil)}, values, writes
+}
+
+func TestIndex(t *testing.T) {
+	kv, values, writes := newMemoryClient(t)
+
+	require.NoError(t, kv.addToIndex("index", "a", "b"))
+	require.NoError(t, kv.addToIndex("index", "b", "c"))
+	ids, err := kv.getIndex("index")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, ids)
+
+	// Indexing an indexed ID or removing a missing one doesn't write
+	require.NoError(t, kv.addToIndex("index", "a"))
+	require.NoError(t, kv.removeFromIndex("index", "d"))
+	assert.Equal(t, 2, writes["index"])
+
+	require.NoError(t, kv.removeFromIndex("index", "a", "c"))
+	ids, err = kv.getIndex("index")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, ids)
+
+	// The index is deleted with its last ID
+	require.NoError(t, kv.removeFromIndex("index", "b"))
+	assert.NotContains(t, values, "index")
+	require.NoError(t, kv.removeFromIndex("index", "b"))
+}
+
+func TestBuildIndexes(t *testing.T) {
+	kv, values, writes := newMemoryClient(t)
+
+	values[persistentNotificationPrefix+"post1"] = []byte(`{"PostID":"post1"}`)
+	values[persistentNotificationPrefix+"post2"] = []byte(`{"PostID":"post2"}`)
+	values["other"] = []byte(`{}`)
+
+	require.NoError(t, kv.BuildIndexes())
+	notifications, err := kv.ListPersistentNotifications()
+	require.NoError(t, err)
+	assert.Len(t, notifications, 2)
+
+	// The indexes are built once
+	require.NoError(t, kv.BuildIndexes())
+	assert.Equal(t, 1, writes[persistentNotificationsKey])
+}
+
+func TestUnindexKeepsSavedKeys(t *testing.T) {
+	kv, values, _ := newMemoryClient(t)
+
+	require.NoError(t, kv.addToIndex("index", "a"))
+
+	// Another server saved the key again before it was unindexed
+	values["key-a"] = []byte(`{}`)
+	require.NoError(t, kv.unindex("index", "a", "key-a"))
+	ids, err := kv.getIndex("index")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, ids)
+
+	delete(values, "key-a")
+	require.NoError(t, kv.unindex("index", "a", "key-a"))
+	assert.NotContains(t, values, "index")
+}
+
+func TestDeliveryStatusIndex(t *testing.T) {
+	kv, values, _ := newMemoryClient(t)
+
+	_, err := kv.RecordDelivery("user1", "target1", "")
+	require.NoError(t, err)
+	_, err = kv.RecordDelivery("user1", "target2", "failed")
+	require.NoError(t, err)
+	_, err = kv.RecordDelivery("user2", "target1", "")
+	require.NoError(t, err)
+
+	statuses, err := kv.ListDeliveryStatuses()
+	require.NoError(t, err)
+	assert.Len(t, statuses, 3)
+	targetIDs, err := kv.ListDeliveryTargetIDs("user1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"target1", "target2"}, targetIDs)
+
+	// Users are unindexed with their last target
+	require.NoError(t, kv.ResetDeliveryStatus("user2", "target1"))
+	userIDs, err := kv.getIndex(deliveryUsersKey)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user1"}, userIDs)
+	assert.NotContains(t, values, deliveryTargetsPrefix+"user2")
+
+	// The statuses recorded before the index existed are indexed once
+	delete(values, deliveryUsersKey)
+	delete(values, deliveryTargetsPrefix+"user1")
+	require.NoError(t, kv.BuildIndexes())
+	targetIDs, err = kv.ListDeliveryTargetIDs("user1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"target1", "target2"}, targetIDs)
+	statuses, err = kv.ListDeliveryStatuses()
+	require.NoError(t, err)
+	assert.Len(t, statuses, 2)
+}
@@ -1,6 +1,150 @@
 package kvstore
 
+import "time"
+
 type KVStore interface {
 	// Define your methods here. This package is used to access the KVStore pluginapi methods.
 	GetTemplateData(userID string) (string, error)
+
+	// SavePersistentNotification stores an urgent post whose notifications are re-sent until acknowledged.
+	SavePersistentNotification(notification *PersistentNotification) error
+
+	// ListPersistentNotifications returns every persistent notification that is still pending.
+	ListPersistentNotifications() ([]*PersistentNotification, error)
+
+	// DeletePersistentNotification stops re-sending the notifications for a post.
+	DeletePersistentNotification(postID string) error
+
+	// UpdateDigest atomically applies update to the user's pending digest, creating it if needed.
+	UpdateDigest(userID string, update func(digest *Digest)) error
+
+	// ListDigests returns the pending digest of every user.
+	ListDigests() ([]*Digest, error)
+
+	// TakeDigest atomically removes and returns the user's pending digest, nil if there is none.
+	TakeDigest(userID string) (*Digest, error)
+
+	// UpdateReactionBatch atomically applies update to the reactions pending for a user's posts,
+	// creating the batch if needed.
+	UpdateReactionBatch(userID string, update func(batch *ReactionBatch)) error
+
+	// ListReactionBatches returns the pending reaction batch of every user.
+	ListReactionBatches() ([]*ReactionBatch, error)
+
+	// TakeReactionBatch atomically removes and returns a user's pending reaction batch, nil if there is none.
+	TakeReactionBatch(userID string) (*ReactionBatch, error)
+
+	// TakeRateLimitToken takes a token from the rate limit bucket of key, which refills at limit
+	// tokens per period, and returns false if the bucket is empty.
+	TakeRateLimitToken(key string, limit int, period time.Duration) (bool, error)
+
+	// UpdateCoalescedNotifications atomically applies update to the notifications of a user held
+	// back by the rate limits, deleting them once update leaves no target.
+	UpdateCoalescedNotifications(userID string, update func(coalesced *CoalescedNotifications)) error
+
+	// ListCoalescedNotifications returns the notifications held back by the rate limits of every user.
+	ListCoalescedNotifications() ([]*CoalescedNotifications, error)
+
+	// GetSubscriptions returns the channel subscriptions of a user.
+	GetSubscriptions(userID string) ([]*Subscription, error)
+
+	// AddSubscription saves a new channel subscription of a user.
+	AddSubscription(userID string, subscription *Subscription) error
+
+	// DeleteSubscription deletes a channel subscription of a user and returns it, nil if the user
+	// has no subscription with that ID.
+	DeleteSubscription(userID, subscriptionID string) (*Subscription, error)
+
+	// GetChannelSubscribers returns the IDs of the users with a subscription to a channel.
+	GetChannelSubscribers(channelID string) ([]string, error)
+
+	// GetWatchRules returns the watch rules of a user.
+	GetWatchRules(userID string) ([]*WatchRule, error)
+
+	// AddWatchRule saves a new watch rule of a user.
+	AddWatchRule(userID string, rule *WatchRule) error
+
+	// DeleteWatchRule deletes a watch rule of a user and returns it, nil if the user has no rule with that ID.
+	DeleteWatchRule(userID, ruleID string) (*WatchRule, error)
+
+	// GetWatchers returns the IDs of the users with at least one watch rule.
+	GetWatchers() ([]string, error)
+
+	// SetPushFollower records whether a user is notified of channel activity, following their
+	// Mattermost push notification preferences, in the index of push followers.
+	SetPushFollower(userID string, follows bool) error
+
+	// GetPushFollowers returns the IDs of the users notified of channel activity, following their
+	// Mattermost push notification preferences.
+	GetPushFollowers() ([]string, error)
+
+	// GetBridges returns the bridges of a channel.
+	GetBridges(channelID string) ([]*Bridge, error)
+
+	// AddBridge saves a new bridge of a channel.
+	AddBridge(channelID string, bridge *Bridge) error
+
+	// DeleteBridge deletes a bridge of a channel and returns it, nil if the channel has no bridge with that ID.
+	DeleteBridge(channelID, bridgeID string) (*Bridge, error)
+
+	// ClaimNotification records that the notification identified by key is being sent, and returns
+	// false if it was already claimed within ttl, so that it's sent only once across the cluster.
+	ClaimNotification(key string, ttl time.Duration) (bool, error)
+
+	// ReleaseNotification forgets a claimed notification, so that it can be sent again.
+	ReleaseNotification(key string) error
+
+	// RecordDelivery records the outcome of a notification sent to one of a user's targets, with
+	// an empty deliveryErr for a success, and returns the target's updated status.
+	RecordDelivery(userID, targetID, deliveryErr string) (*DeliveryStatus, error)
+
+	// GetDeliveryStatus returns the outcome of the recent notifications sent to one of a user's
+	// targets, nil if none was sent yet.
+	GetDeliveryStatus(userID, targetID string) (*DeliveryStatus, error)
+
+	// ListDeliveryStatuses returns the delivery statuses of the targets of all users.
+	ListDeliveryStatuses() ([]*DeliveryStatus, error)
+
+	// ResetDeliveryStatus forgets the outcome of the notifications sent to one of a user's targets,
+	// once it's enabled again or removed.
+	ResetDeliveryStatus(userID, targetID string) error
+
+	// ListDeliveryTargetIDs returns the IDs of the user's targets that have a delivery status.
+	ListDeliveryTargetIDs(userID string) ([]string, error)
+
+	// SaveTargetIDs saves the IDs of a user's targets, and returns the IDs saved before, nil if none
+	// were, and whether they changed.
+	SaveTargetIDs(userID string, targetIDs []string) ([]string, bool, error)
+
+	// GetSnooze returns when a user's notifications resume, nil if they aren't snoozed or muted anywhere.
+	GetSnooze(userID string) (*Snooze, error)
+
+	// UpdateSnooze applies update to a user's snooze atomically, deleting it once nothing is
+	// snoozed or muted anymore, and returns the updated snooze.
+	UpdateSnooze(userID string, update func(snooze *Snooze)) (*Snooze, error)
+
+	// ListSnoozes returns the snoozes of all users, so that the expired ones can be cleared.
+	ListSnoozes() ([]*Snooze, error)
+
+	// MarkWelcomed records that a user got the welcome message, and returns false if they already had.
+	MarkWelcomed(userID string) (bool, error)
+
+	// GetSigningKey returns the key used to sign public links, generating it on first use.
+	GetSigningKey() ([]byte, error)
+
+	// SaveRetry queues a notification that failed to be sent to one of a user's targets.
+	SaveRetry(retry *Retry) error
+
+	// ListRetries returns every queued retry.
+	ListRetries() ([]*Retry, error)
+
+	// TakeRetry atomically removes and returns a queued retry, nil if it was already taken.
+	TakeRetry(id string) (*Retry, error)
+
+	// ReencryptCredentials encrypts the credentials saved in the store with a new key, and returns
+	// how many were re-encrypted.
+	ReencryptCredentials() (int, error)
+
+	// BuildIndexes indexes the keys saved before their index existed.
+	BuildIndexes() error
 }
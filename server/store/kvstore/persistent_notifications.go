@@ -0,0 +1,61 @@
+package kvstore
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	persistentNotificationPrefix = "persistent_notification-"
+	persistentNotificationsKey   = "persistent_notifications"
+)
+
+// PersistentNotification tracks the users who still have to acknowledge an urgent post.
+type PersistentNotification struct {
+	PostID string
+
+	// Mentions maps each user still to be notified to how they were mentioned.
+	Mentions map[string]string
+
+	CreateAt   int64
+	LastSentAt int64
+	SentCount  int
+}
+
+func (kv Client) SavePersistentNotification(notification *PersistentNotification) error {
+	if _, err := kv.client.KV.Set(persistentNotificationPrefix+notification.PostID, notification); err != nil {
+		return errors.Wrap(err, "failed to save persistent notification")
+	}
+	if err := kv.addToIndex(persistentNotificationsKey, notification.PostID); err != nil {
+		return errors.Wrap(err, "failed to index persistent notification")
+	}
+	return nil
+}
+
+func (kv Client) ListPersistentNotifications() ([]*PersistentNotification, error) {
+	postIDs, err := kv.getIndex(persistentNotificationsKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list persistent notifications")
+	}
+
+	var notifications []*PersistentNotification
+	for _, postID := range postIDs {
+		var notification *PersistentNotification
+		if err := kv.client.KV.Get(persistentNotificationPrefix+postID, &notification); err != nil {
+			return nil, errors.Wrap(err, "failed to get persistent notification")
+		}
+		if notification != nil {
+			notifications = append(notifications, notification)
+		}
+	}
+	return notifications, nil
+}
+
+func (kv Client) DeletePersistentNotification(postID string) error {
+	if err := kv.client.KV.Delete(persistentNotificationPrefix + postID); err != nil {
+		return errors.Wrap(err, "failed to delete persistent notification")
+	}
+	if err := kv.unindex(persistentNotificationsKey, postID, persistentNotificationPrefix+postID); err != nil {
+		return errors.Wrap(err, "failed to unindex persistent notification")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package kvstore
+
+import (
+	"github.com/pkg/errors"
+)
+
+const pushFollowersKey = "push_followers"
+
+func (kv Client) SetPushFollower(userID string, follows bool) error {
+	var err error
+	if follows {
+		err = kv.addToIndex(pushFollowersKey, userID)
+	} else {
+		err = kv.removeFromIndex(pushFollowersKey, userID)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to update push followers")
+	}
+	return nil
+}
+
+func (kv Client) GetPushFollowers() ([]string, error) {
+	followers, err := kv.getIndex(pushFollowersKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get push followers")
+	}
+	return followers, nil
+}
@@ -0,0 +1,149 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	rateLimitPrefix = "ratelimit-"
+	coalescedPrefix = "coalesced-"
+
+	coalescedNotificationsKey = "coalesced_notifications"
+)
+
+// TokenBucket rate limits notifications: it holds up to a period worth of tokens and refills
+// continuously, each notification taking a token.
+type TokenBucket struct {
+	Tokens   float64
+	UpdateAt int64
+}
+
+// Take refills the bucket for the time elapsed since its last update at limit tokens per period,
+// and takes a token if one is available. New buckets start full.
+func (b *TokenBucket) Take(limit int, period time.Duration, now int64) bool {
+	capacity := float64(limit)
+	if b.UpdateAt == 0 {
+		b.Tokens = capacity
+	} else if elapsed := now - b.UpdateAt; elapsed > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+float64(elapsed)*capacity/float64(period.Milliseconds()))
+	}
+	b.UpdateAt = now
+
+	if b.Tokens < 1 {
+		return false
+	}
+	b.Tokens--
+	return true
+}
+
+// rateLimitRetries is the number of times taking a token is attempted when other servers update
+// the bucket concurrently.
+const rateLimitRetries = 5
+
+func (kv Client) TakeRateLimitToken(key string, limit int, period time.Duration) (bool, error) {
+	// The bucket is full again a period after its last update, as a missing bucket starts, so it
+	// expires then. SetAtomicWithRetries can't set an expiry, hence the retries here.
+	for i := 0; i < rateLimitRetries; i++ {
+		var oldValue []byte
+		if err := kv.client.KV.Get(rateLimitPrefix+key, &oldValue); err != nil {
+			return false, errors.Wrap(err, "failed to get rate limit")
+		}
+
+		bucket := &TokenBucket{}
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, bucket); err != nil {
+				return false, errors.Wrap(err, "failed to decode rate limit")
+			}
+		}
+
+		taken := bucket.Take(limit, period, model.GetMillis())
+		saved, err := kv.client.KV.Set(rateLimitPrefix+key, bucket, pluginapi.SetAtomic(oldValue), pluginapi.SetExpiry(period))
+		if err != nil {
+			return false, errors.Wrap(err, "failed to take rate limit token")
+		} else if saved {
+			return taken, nil
+		}
+	}
+	return false, errors.New("failed to take rate limit token: too many concurrent updates")
+}
+
+// CoalescedNotifications counts the notifications of a user held back by the rate limits, to be
+// summarized in a follow-up once the limits allow it.
+type CoalescedNotifications struct {
+	UserID string
+
+	// Targets counts the notifications held back for each of the user's targets, keyed by target
+	// ID and then by channel.
+	Targets map[string]map[string]int
+}
+
+// errNoCoalescedNotifications stops UpdateCoalescedNotifications from deleting a missing key, as
+// an atomic delete of a missing key never succeeds.
+var errNoCoalescedNotifications = errors.New("no coalesced notifications")
+
+func (kv Client) UpdateCoalescedNotifications(userID string, update func(coalesced *CoalescedNotifications)) error {
+	var created, deleted bool
+	err := kv.client.KV.SetAtomicWithRetries(coalescedPrefix+userID, func(oldValue []byte) (any, error) {
+		created, deleted = false, false
+		coalesced := &CoalescedNotifications{UserID: userID}
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, coalesced); err != nil {
+				return nil, err
+			}
+		}
+		if coalesced.Targets == nil {
+			coalesced.Targets = map[string]map[string]int{}
+		}
+
+		update(coalesced)
+		if len(coalesced.Targets) > 0 {
+			created = len(oldValue) == 0
+			return coalesced, nil
+		} else if len(oldValue) == 0 {
+			return nil, errNoCoalescedNotifications
+		}
+		deleted = true
+		return nil, nil
+	})
+	if errors.Is(err, errNoCoalescedNotifications) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "failed to update coalesced notifications")
+	}
+
+	if created {
+		if err := kv.addToIndex(coalescedNotificationsKey, userID); err != nil {
+			return errors.Wrap(err, "failed to index coalesced notifications")
+		}
+	} else if deleted {
+		if err := kv.unindex(coalescedNotificationsKey, userID, coalescedPrefix+userID); err != nil {
+			return errors.Wrap(err, "failed to unindex coalesced notifications")
+		}
+	}
+	return nil
+}
+
+func (kv Client) ListCoalescedNotifications() ([]*CoalescedNotifications, error) {
+	userIDs, err := kv.getIndex(coalescedNotificationsKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list coalesced notifications")
+	}
+
+	var list []*CoalescedNotifications
+	for _, userID := range userIDs {
+		var coalesced *CoalescedNotifications
+		if err := kv.client.KV.Get(coalescedPrefix+userID, &coalesced); err != nil {
+			return nil, errors.Wrap(err, "failed to get coalesced notifications")
+		}
+		if coalesced != nil {
+			list = append(list, coalesced)
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,52 @@
+package kvstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucketTake(t *testing.T) {
+	assert := assert.New(t)
+
+	bucket := &TokenBucket{}
+	assert.True(bucket.Take(2, time.Minute, 1000))
+	assert.True(bucket.Take(2, time.Minute, 1000))
+	assert.False(bucket.Take(2, time.Minute, 1000))
+
+	// Half a minute refills one of two tokens per minute
+	assert.True(bucket.Take(2, time.Minute, 31000))
+	assert.False(bucket.Take(2, time.Minute, 31000))
+
+	// The bucket never holds more than a minute worth of tokens
+	assert.True(bucket.Take(2, time.Minute, 10*60*1000))
+	assert.True(bucket.Take(2, time.Minute, 10*60*1000))
+	assert.False(bucket.Take(2, time.Minute, 10*60*1000))
+
+	// A token per hour refills in an hour, not a minute
+	hourly := &TokenBucket{}
+	assert.True(hourly.Take(1, time.Hour, 1000))
+	assert.False(hourly.Take(1, time.Hour, 61000))
+	assert.True(hourly.Take(1, time.Hour, 60*60*1000+1000))
+}
+
+func TestTakeRateLimitTokenExpires(t *testing.T) {
+	api := &plugintest.API{}
+	defer api.AssertExpectations(t)
+	api.On("KVGet", rateLimitPrefix+"user").Return(nil, nil)
+	api.On("KVSetWithOptions", rateLimitPrefix+"user", mock.Anything, model.PluginKVSetOptions{
+		Atomic:          true,
+		ExpireInSeconds: 3600,
+	}).Return(true, nil)
+
+	kv := Client{client: pluginapi.NewClient(api, nil)}
+	taken, err := kv.TakeRateLimitToken("user", 10, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, taken)
+}
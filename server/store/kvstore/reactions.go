@@ -0,0 +1,116 @@
+package kvstore
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	reactionBatchPrefix = "reactions-"
+	reactionBatchesKey  = "reaction_batches"
+)
+
+// ReactionBatch accumulates the reactions to a user's posts during a short window, to notify
+// them in a single message.
+type ReactionBatch struct {
+	UserID string
+
+	// SendAt is when the batch is due, set when the first reaction is added.
+	SendAt int64
+
+	// Posts groups the reactions by post, keyed by post ID.
+	Posts map[string]*ReactedPost
+}
+
+// ReactedPost is one of the posts of a reaction batch with the reactions it received.
+type ReactedPost struct {
+	PostID      string
+	Channel     string
+	ChannelName string
+	TeamName    string
+	Excerpt     string
+	Permalink   string
+	Reactions   []*Reaction
+}
+
+// Reaction is an emoji reaction added by a user.
+type Reaction struct {
+	Username  string
+	EmojiName string
+}
+
+func (kv Client) UpdateReactionBatch(userID string, update func(batch *ReactionBatch)) error {
+	var created bool
+	err := kv.client.KV.SetAtomicWithRetries(reactionBatchPrefix+userID, func(oldValue []byte) (any, error) {
+		created = len(oldValue) == 0
+		batch := &ReactionBatch{UserID: userID}
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, batch); err != nil {
+				return nil, err
+			}
+		}
+		if batch.Posts == nil {
+			batch.Posts = map[string]*ReactedPost{}
+		}
+
+		update(batch)
+		return batch, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to update reaction batch")
+	}
+	if created {
+		if err := kv.addToIndex(reactionBatchesKey, userID); err != nil {
+			return errors.Wrap(err, "failed to index reaction batch")
+		}
+	}
+	return nil
+}
+
+func (kv Client) ListReactionBatches() ([]*ReactionBatch, error) {
+	userIDs, err := kv.getIndex(reactionBatchesKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list reaction batches")
+	}
+
+	var batches []*ReactionBatch
+	for _, userID := range userIDs {
+		var batch *ReactionBatch
+		if err := kv.client.KV.Get(reactionBatchPrefix+userID, &batch); err != nil {
+			return nil, errors.Wrap(err, "failed to get reaction batch")
+		}
+		if batch != nil {
+			batches = append(batches, batch)
+		}
+	}
+	return batches, nil
+}
+
+// errNoReactionBatch stops TakeReactionBatch when there is nothing to delete, as an atomic delete
+// of a missing key never succeeds.
+var errNoReactionBatch = errors.New("no pending reaction batch")
+
+func (kv Client) TakeReactionBatch(userID string) (*ReactionBatch, error) {
+	var batch *ReactionBatch
+	err := kv.client.KV.SetAtomicWithRetries(reactionBatchPrefix+userID, func(oldValue []byte) (any, error) {
+		if len(oldValue) == 0 {
+			return nil, errNoReactionBatch
+		}
+
+		batch = nil
+		if err := json.Unmarshal(oldValue, &batch); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if errors.Is(err, errNoReactionBatch) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to take reaction batch")
+	}
+	if err := kv.unindex(reactionBatchesKey, userID, reactionBatchPrefix+userID); err != nil {
+		return nil, errors.Wrap(err, "failed to unindex reaction batch")
+	}
+	return batch, nil
+}
@@ -0,0 +1,156 @@
+package kvstore
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	retryPrefix = "retry-"
+	retriesKey  = "retries"
+)
+
+// Retry is a notification that failed to be sent to one of a user's targets, rendered for the
+// target and queued to be sent again later.
+type Retry struct {
+	ID       string
+	UserID   string
+	TargetID string
+
+	// ServiceURL is the URL the notification is sent to, encrypted in the store as it holds the
+	// credentials of the service.
+	ServiceURL string
+
+	Message string
+	Plain   string
+	Params  map[string]string
+
+	// Attempts counts the failed attempts to send the notification, the first one included.
+	Attempts int
+
+	// RetryAt is when the notification is due to be sent again, in milliseconds since the epoch.
+	RetryAt  int64
+	CreateAt int64
+}
+
+func (kv Client) SaveRetry(retry *Retry) error {
+	keys, err := kv.getCredentialsKeys()
+	if err != nil {
+		return errors.Wrap(err, "failed to get credentials keys")
+	}
+	encrypted := *retry
+	if encrypted.ServiceURL, err = encryptCredentials(keys, retry.ServiceURL); err != nil {
+		return errors.Wrap(err, "failed to encrypt retry URL")
+	}
+
+	if _, err := kv.client.KV.Set(retryPrefix+retry.ID, &encrypted); err != nil {
+		return errors.Wrap(err, "failed to save retry")
+	}
+	if err := kv.addToIndex(retriesKey, retry.ID); err != nil {
+		return errors.Wrap(err, "failed to index retry")
+	}
+	return nil
+}
+
+func (kv Client) ListRetries() ([]*Retry, error) {
+	ids, err := kv.getIndex(retriesKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list retries")
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	keys, err := kv.getCredentialsKeys()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get credentials keys")
+	}
+
+	var retries []*Retry
+	for _, id := range ids {
+		var retry *Retry
+		if err := kv.client.KV.Get(retryPrefix+id, &retry); err != nil {
+			return nil, errors.Wrap(err, "failed to get retry")
+		}
+		if retry == nil {
+			continue
+		}
+		if retry.ServiceURL, err = decryptCredentials(keys, retry.ServiceURL); err != nil {
+			return nil, errors.Wrap(err, "failed to decrypt retry URL")
+		}
+		retries = append(retries, retry)
+	}
+	return retries, nil
+}
+
+// errNoRetry stops TakeRetry when there is nothing to delete, as an atomic delete of a missing key
+// never succeeds.
+var errNoRetry = errors.New("no retry")
+
+func (kv Client) TakeRetry(id string) (*Retry, error) {
+	var retry *Retry
+	err := kv.client.KV.SetAtomicWithRetries(retryPrefix+id, func(oldValue []byte) (any, error) {
+		if len(oldValue) == 0 {
+			return nil, errNoRetry
+		}
+
+		retry = nil
+		if err := json.Unmarshal(oldValue, &retry); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if errors.Is(err, errNoRetry) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to take retry")
+	}
+	if err := kv.unindex(retriesKey, id, retryPrefix+id); err != nil {
+		return nil, errors.Wrap(err, "failed to unindex retry")
+	}
+
+	keys, err := kv.getCredentialsKeys()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get credentials keys")
+	}
+	if retry.ServiceURL, err = decryptCredentials(keys, retry.ServiceURL); err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt retry URL")
+	}
+	return retry, nil
+}
+
+// reencryptRetries encrypts the URLs of every queued retry with the current key, and returns how
+// many were re-encrypted.
+func (kv Client) reencryptRetries(keys *credentialsKeys) (int, error) {
+	ids, err := kv.getIndex(retriesKey)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, id := range ids {
+		err := kv.client.KV.SetAtomicWithRetries(retryPrefix+id, func(oldValue []byte) (any, error) {
+			if len(oldValue) == 0 {
+				return nil, errNoRetry
+			}
+
+			var retry *Retry
+			if err := json.Unmarshal(oldValue, &retry); err != nil {
+				return nil, err
+			}
+			var err error
+			if retry.ServiceURL, err = reencryptCredentials(keys, retry.ServiceURL); err != nil {
+				return nil, err
+			}
+			return retry, nil
+		})
+		if errors.Is(err, errNoRetry) {
+			continue
+		} else if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,25 @@
+package kvstore
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const sentNotificationPrefix = "sent-"
+
+func (kv Client) ClaimNotification(key string, ttl time.Duration) (bool, error) {
+	claimed, err := kv.client.KV.Set(sentNotificationPrefix+key, true, pluginapi.SetAtomic(nil), pluginapi.SetExpiry(ttl))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to claim notification")
+	}
+	return claimed, nil
+}
+
+func (kv Client) ReleaseNotification(key string) error {
+	if err := kv.client.KV.Delete(sentNotificationPrefix + key); err != nil {
+		return errors.Wrap(err, "failed to release notification")
+	}
+	return nil
+}
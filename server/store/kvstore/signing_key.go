@@ -0,0 +1,38 @@
+package kvstore
+
+import (
+	"crypto/rand"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const signingKeyKey = "signing_key"
+
+// signingKeyLength is the length in bytes of the generated HMAC key.
+const signingKeyLength = 32
+
+func (kv Client) GetSigningKey() ([]byte, error) {
+	var key []byte
+	if err := kv.client.KV.Get(signingKeyKey, &key); err != nil {
+		return nil, errors.Wrap(err, "failed to get signing key")
+	}
+	if len(key) > 0 {
+		return key, nil
+	}
+
+	key = make([]byte, signingKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errors.Wrap(err, "failed to generate signing key")
+	}
+
+	// Another server of the cluster may be generating a key at the same time, keep the first one saved.
+	if _, err := kv.client.KV.Set(signingKeyKey, key, pluginapi.SetAtomic(nil)); err != nil {
+		return nil, errors.Wrap(err, "failed to save signing key")
+	}
+
+	if err := kv.client.KV.Get(signingKeyKey, &key); err != nil {
+		return nil, errors.Wrap(err, "failed to get signing key")
+	}
+	return key, nil
+}
@@ -0,0 +1,145 @@
+package kvstore
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	snoozePrefix = "snooze-"
+	snoozesKey   = "snoozes"
+)
+
+// Snooze holds when a user's notifications resume, in general and in the channels they muted.
+type Snooze struct {
+	UserID string
+
+	// Until is when the user's notifications resume, in milliseconds since the epoch, zero if
+	// they aren't snoozed.
+	Until int64
+
+	// MutedChannels maps the IDs of the channels the user muted to when their mute expires, zero
+	// for a mute lasting until the user unmutes the channel.
+	MutedChannels map[string]int64
+}
+
+// Snoozed returns whether the user's notifications are snoozed at the time, in milliseconds.
+func (s *Snooze) Snoozed(now int64) bool {
+	return s != nil && s.Until > now
+}
+
+// Muted returns whether the user muted the channel at the time, in milliseconds.
+func (s *Snooze) Muted(channelID string, now int64) bool {
+	if s == nil {
+		return false
+	}
+	until, ok := s.MutedChannels[channelID]
+	return ok && (until == 0 || until > now)
+}
+
+// HasExpired returns whether the snooze or one of the channel mutes expired at the time, in milliseconds.
+func (s *Snooze) HasExpired(now int64) bool {
+	for _, until := range s.MutedChannels {
+		if until != 0 && until <= now {
+			return true
+		}
+	}
+	return s.Until != 0 && s.Until <= now
+}
+
+// RemoveExpired forgets the snooze and the channel mutes that expired at the time, in
+// milliseconds, and returns whether the snooze itself expired.
+func (s *Snooze) RemoveExpired(now int64) bool {
+	for channelID, until := range s.MutedChannels {
+		if until != 0 && until <= now {
+			delete(s.MutedChannels, channelID)
+		}
+	}
+
+	if s.Until != 0 && s.Until <= now {
+		s.Until = 0
+		return true
+	}
+	return false
+}
+
+// IsEmpty returns whether the user's notifications are neither snoozed nor muted anywhere.
+func (s *Snooze) IsEmpty() bool {
+	return s.Until == 0 && len(s.MutedChannels) == 0
+}
+
+func (kv Client) GetSnooze(userID string) (*Snooze, error) {
+	var snooze *Snooze
+	if err := kv.client.KV.Get(snoozePrefix+userID, &snooze); err != nil {
+		return nil, errors.Wrap(err, "failed to get snooze")
+	}
+	return snooze, nil
+}
+
+// errNoSnooze stops UpdateSnooze when an update leaves nothing to save or delete, as an atomic
+// delete of a missing key never succeeds.
+var errNoSnooze = errors.New("no snooze")
+
+func (kv Client) UpdateSnooze(userID string, update func(snooze *Snooze)) (*Snooze, error) {
+	var snooze *Snooze
+	var created, deleted bool
+	err := kv.client.KV.SetAtomicWithRetries(snoozePrefix+userID, func(oldValue []byte) (any, error) {
+		created, deleted = false, false
+		snooze = &Snooze{UserID: userID}
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, snooze); err != nil {
+				return nil, err
+			}
+		}
+		if snooze.MutedChannels == nil {
+			snooze.MutedChannels = map[string]int64{}
+		}
+
+		update(snooze)
+		if !snooze.IsEmpty() {
+			created = len(oldValue) == 0
+			return snooze, nil
+		}
+		if len(oldValue) == 0 {
+			return nil, errNoSnooze
+		}
+		deleted = true
+		return nil, nil
+	})
+	if errors.Is(err, errNoSnooze) {
+		return snooze, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to update snooze")
+	}
+
+	if created {
+		if err := kv.addToIndex(snoozesKey, userID); err != nil {
+			return nil, errors.Wrap(err, "failed to index snooze")
+		}
+	} else if deleted {
+		if err := kv.unindex(snoozesKey, userID, snoozePrefix+userID); err != nil {
+			return nil, errors.Wrap(err, "failed to unindex snooze")
+		}
+	}
+	return snooze, nil
+}
+
+func (kv Client) ListSnoozes() ([]*Snooze, error) {
+	userIDs, err := kv.getIndex(snoozesKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list snoozes")
+	}
+
+	var snoozes []*Snooze
+	for _, userID := range userIDs {
+		var snooze *Snooze
+		if err := kv.client.KV.Get(snoozePrefix+userID, &snooze); err != nil {
+			return nil, errors.Wrap(err, "failed to get snooze")
+		}
+		if snooze != nil {
+			snoozes = append(snoozes, snooze)
+		}
+	}
+	return snoozes, nil
+}
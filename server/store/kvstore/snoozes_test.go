@@ -0,0 +1,41 @@
+package kvstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnooze(t *testing.T) {
+	assert := assert.New(t)
+
+	var none *Snooze
+	assert.False(none.Snoozed(1000))
+	assert.False(none.Muted("channel", 1000))
+
+	snooze := &Snooze{
+		Until: 2000,
+		MutedChannels: map[string]int64{
+			"forever": 0,
+			"briefly": 1500,
+		},
+	}
+	assert.True(snooze.Snoozed(1000))
+	assert.True(snooze.Muted("forever", 1000))
+	assert.True(snooze.Muted("briefly", 1000))
+	assert.False(snooze.Muted("other", 1000))
+
+	assert.False(snooze.HasExpired(1000))
+	assert.True(snooze.HasExpired(1500))
+	assert.False(snooze.RemoveExpired(1500))
+	assert.False(snooze.HasExpired(1500))
+	assert.Equal(map[string]int64{"forever": 0}, snooze.MutedChannels)
+	assert.True(snooze.Snoozed(1500))
+
+	assert.True(snooze.RemoveExpired(2000))
+	assert.False(snooze.Snoozed(2000))
+	assert.False(snooze.IsEmpty())
+
+	delete(snooze.MutedChannels, "forever")
+	assert.True(snooze.IsEmpty())
+}
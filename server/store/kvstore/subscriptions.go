@@ -0,0 +1,167 @@
+package kvstore
+
+import (
+	"encoding/json"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/postfilter"
+	"github.com/pkg/errors"
+)
+
+const (
+	subscriptionsPrefix      = "subscriptions-"
+	channelSubscribersPrefix = "channel_subscribers-"
+)
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription forwards the posts of a channel to a user's services, optionally filtered.
+type Subscription struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	postfilter.Filter
+	CreateAt int64 `json:"create_at"`
+}
+
+func (kv Client) GetSubscriptions(userID string) ([]*Subscription, error) {
+	var subscriptions []*Subscription
+	if err := kv.client.KV.Get(subscriptionsPrefix+userID, &subscriptions); err != nil {
+		return nil, errors.Wrap(err, "failed to get subscriptions")
+	}
+	return subscriptions, nil
+}
+
+func (kv Client) AddSubscription(userID string, subscription *Subscription) error {
+	err := kv.client.KV.SetAtomicWithRetries(subscriptionsPrefix+userID, func(oldValue []byte) (any, error) {
+		var subscriptions []*Subscription
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &subscriptions); err != nil {
+				return nil, err
+			}
+		}
+		return append(subscriptions, subscription), nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to save subscription")
+	}
+
+	err = kv.updateChannelSubscribers(subscription.ChannelID, func(subscribers []string) []string {
+		for _, subscriber := range subscribers {
+			if subscriber == userID {
+				return subscribers
+			}
+		}
+		return append(subscribers, userID)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to add channel subscriber")
+	}
+	return nil
+}
+
+// errSubscriptionNotFound stops DeleteSubscription from writing when the subscription doesn't exist.
+var errSubscriptionNotFound = errors.New("subscription not found")
+
+func (kv Client) DeleteSubscription(userID, subscriptionID string) (*Subscription, error) {
+	var deleted *Subscription
+	var channelSubscribed bool
+	err := kv.client.KV.SetAtomicWithRetries(subscriptionsPrefix+userID, func(oldValue []byte) (any, error) {
+		var subscriptions []*Subscription
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &subscriptions); err != nil {
+				return nil, err
+			}
+		}
+
+		deleted = nil
+		var remaining []*Subscription
+		for _, subscription := range subscriptions {
+			if subscription.ID == subscriptionID {
+				deleted = subscription
+			} else {
+				remaining = append(remaining, subscription)
+			}
+		}
+		if deleted == nil {
+			return nil, errSubscriptionNotFound
+		}
+
+		channelSubscribed = false
+		for _, subscription := range remaining {
+			if subscription.ChannelID == deleted.ChannelID {
+				channelSubscribed = true
+			}
+		}
+
+		if len(remaining) == 0 {
+			return nil, nil
+		}
+		return remaining, nil
+	})
+	if errors.Is(err, errSubscriptionNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to delete subscription")
+	}
+
+	// The user stays a subscriber of the channel while another of their subscriptions uses it
+	if channelSubscribed {
+		return deleted, nil
+	}
+
+	err = kv.updateChannelSubscribers(deleted.ChannelID, func(subscribers []string) []string {
+		var remaining []string
+		for _, subscriber := range subscribers {
+			if subscriber != userID {
+				remaining = append(remaining, subscriber)
+			}
+		}
+		return remaining
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to remove channel subscriber")
+	}
+	return deleted, nil
+}
+
+func (kv Client) GetChannelSubscribers(channelID string) ([]string, error) {
+	var subscribers []string
+	if err := kv.client.KV.Get(channelSubscribersPrefix+channelID, &subscribers); err != nil {
+		return nil, errors.Wrap(err, "failed to get channel subscribers")
+	}
+	return subscribers, nil
+}
+
+// errNoChannelSubscribers stops updateChannelSubscribers from deleting a missing key, as an atomic
+// delete of a missing key never succeeds.
+var errNoChannelSubscribers = errors.New("no channel subscribers")
+
+// updateChannelSubscribers atomically updates the index of the users subscribed to a channel,
+// which spares reading every user's subscriptions for each post.
+func (kv Client) updateChannelSubscribers(channelID string, update func(subscribers []string) []string) error {
+	err := kv.client.KV.SetAtomicWithRetries(channelSubscribersPrefix+channelID, func(oldValue []byte) (any, error) {
+		var subscribers []string
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &subscribers); err != nil {
+				return nil, err
+			}
+		}
+
+		if subscribers = update(subscribers); len(subscribers) > 0 {
+			return subscribers, nil
+		} else if len(oldValue) == 0 {
+			return nil, errNoChannelSubscribers
+		}
+		return nil, nil
+	})
+	if errors.Is(err, errNoChannelSubscribers) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,45 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+// targetIDsPrefix holds the IDs of each user's targets, rather than their URLs which hold the
+// credentials of their service, so that the targets removed from a new list can be told.
+const targetIDsPrefix = "target_ids-"
+
+// errTargetIDsUnchanged stops SaveTargetIDs when the IDs didn't change, which saves a write and
+// avoids the atomic delete of a missing key, which never succeeds.
+var errTargetIDsUnchanged = errors.New("target IDs unchanged")
+
+func (kv Client) SaveTargetIDs(userID string, targetIDs []string) ([]string, bool, error) {
+	targetIDs = slices.Clone(targetIDs)
+	slices.Sort(targetIDs)
+	targetIDs = slices.Compact(targetIDs)
+
+	var previous []string
+	err := kv.client.KV.SetAtomicWithRetries(targetIDsPrefix+userID, func(oldValue []byte) (any, error) {
+		previous = nil
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &previous); err != nil {
+				return nil, err
+			}
+		}
+
+		if slices.Equal(previous, targetIDs) {
+			return nil, errTargetIDsUnchanged
+		} else if len(targetIDs) > 0 {
+			return targetIDs, nil
+		}
+		return nil, nil
+	})
+	if errors.Is(err, errTargetIDsUnchanged) {
+		return previous, false, nil
+	} else if err != nil {
+		return nil, false, errors.Wrap(err, "failed to save target IDs")
+	}
+	return previous, true, nil
+}
@@ -0,0 +1,40 @@
+package kvstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveTargetIDs(t *testing.T) {
+	assert := assert.New(t)
+	kv, values, writes := newMemoryClient(t)
+
+	previous, changed, err := kv.SaveTargetIDs("user", []string{"b", "a"})
+	require.NoError(t, err)
+	assert.True(changed)
+	assert.Nil(previous)
+
+	// The same targets in another order don't write
+	previous, changed, err = kv.SaveTargetIDs("user", []string{"a", "b"})
+	require.NoError(t, err)
+	assert.False(changed)
+	assert.Equal([]string{"a", "b"}, previous)
+	assert.Equal(1, writes[targetIDsPrefix+"user"])
+
+	previous, changed, err = kv.SaveTargetIDs("user", []string{"a"})
+	require.NoError(t, err)
+	assert.True(changed)
+	assert.Equal([]string{"a", "b"}, previous)
+
+	// The key is deleted with the last target
+	_, changed, err = kv.SaveTargetIDs("user", nil)
+	require.NoError(t, err)
+	assert.True(changed)
+	assert.NotContains(values, targetIDsPrefix+"user")
+
+	_, changed, err = kv.SaveTargetIDs("user", nil)
+	require.NoError(t, err)
+	assert.False(changed)
+}
@@ -0,0 +1,172 @@
+package kvstore
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	watchRulesPrefix = "watches-"
+	watchersKey      = "watchers"
+)
+
+// WatchRule notifies a user of the posts containing a phrase or matching a regular expression,
+// whether or not they mention the user.
+type WatchRule struct {
+	ID string `json:"id"`
+
+	// Pattern is the phrase to look for, or a regular expression if Regex is set. Both are case-insensitive.
+	Pattern string `json:"pattern"`
+	Regex   bool   `json:"regex,omitempty"`
+
+	// TeamIDs and ChannelIDs restrict the rule to the posts of some teams or channels, empty for every post.
+	TeamIDs    []string `json:"team_ids,omitempty"`
+	ChannelIDs []string `json:"channel_ids,omitempty"`
+
+	// PublicChannels also matches the posts of the public channels the user hasn't joined, in the
+	// teams they belong to.
+	PublicChannels bool `json:"public_channels,omitempty"`
+
+	// RateLimit is the number of notifications per hour the rule can trigger, zero for the default.
+	RateLimit int `json:"rate_limit,omitempty"`
+
+	CreateAt int64 `json:"create_at"`
+}
+
+// InScope returns whether the rule applies to the posts of a channel. Direct and group messages have
+// no team, so rules restricted to teams don't apply to them.
+func (r *WatchRule) InScope(teamID, channelID string) bool {
+	if len(r.TeamIDs) > 0 && !containsString(r.TeamIDs, teamID) {
+		return false
+	}
+	return len(r.ChannelIDs) == 0 || containsString(r.ChannelIDs, channelID)
+}
+
+func (kv Client) GetWatchRules(userID string) ([]*WatchRule, error) {
+	var rules []*WatchRule
+	if err := kv.client.KV.Get(watchRulesPrefix+userID, &rules); err != nil {
+		return nil, errors.Wrap(err, "failed to get watch rules")
+	}
+	return rules, nil
+}
+
+func (kv Client) AddWatchRule(userID string, rule *WatchRule) error {
+	err := kv.client.KV.SetAtomicWithRetries(watchRulesPrefix+userID, func(oldValue []byte) (any, error) {
+		var rules []*WatchRule
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &rules); err != nil {
+				return nil, err
+			}
+		}
+		return append(rules, rule), nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to save watch rule")
+	}
+
+	err = kv.updateWatchers(func(watchers []string) []string {
+		if containsString(watchers, userID) {
+			return watchers
+		}
+		return append(watchers, userID)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to add watcher")
+	}
+	return nil
+}
+
+// errWatchRuleNotFound stops DeleteWatchRule from writing when the rule doesn't exist.
+var errWatchRuleNotFound = errors.New("watch rule not found")
+
+func (kv Client) DeleteWatchRule(userID, ruleID string) (*WatchRule, error) {
+	var deleted *WatchRule
+	var remaining []*WatchRule
+	err := kv.client.KV.SetAtomicWithRetries(watchRulesPrefix+userID, func(oldValue []byte) (any, error) {
+		var rules []*WatchRule
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &rules); err != nil {
+				return nil, err
+			}
+		}
+
+		deleted = nil
+		remaining = nil
+		for _, rule := range rules {
+			if rule.ID == ruleID {
+				deleted = rule
+			} else {
+				remaining = append(remaining, rule)
+			}
+		}
+		if deleted == nil {
+			return nil, errWatchRuleNotFound
+		}
+
+		if len(remaining) == 0 {
+			return nil, nil
+		}
+		return remaining, nil
+	})
+	if errors.Is(err, errWatchRuleNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to delete watch rule")
+	}
+
+	// The user stays a watcher while they have other rules
+	if len(remaining) > 0 {
+		return deleted, nil
+	}
+
+	err = kv.updateWatchers(func(watchers []string) []string {
+		var others []string
+		for _, watcher := range watchers {
+			if watcher != userID {
+				others = append(others, watcher)
+			}
+		}
+		return others
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to remove watcher")
+	}
+	return deleted, nil
+}
+
+func (kv Client) GetWatchers() ([]string, error) {
+	var watchers []string
+	if err := kv.client.KV.Get(watchersKey, &watchers); err != nil {
+		return nil, errors.Wrap(err, "failed to get watchers")
+	}
+	return watchers, nil
+}
+
+// errNoWatchers stops updateWatchers from deleting a missing key, as an atomic delete of a missing
+// key never succeeds.
+var errNoWatchers = errors.New("no watchers")
+
+// updateWatchers atomically updates the index of the users with watch rules, which spares listing
+// every key of the KV store for each post.
+func (kv Client) updateWatchers(update func(watchers []string) []string) error {
+	err := kv.client.KV.SetAtomicWithRetries(watchersKey, func(oldValue []byte) (any, error) {
+		var watchers []string
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &watchers); err != nil {
+				return nil, err
+			}
+		}
+
+		if watchers = update(watchers); len(watchers) > 0 {
+			return watchers, nil
+		} else if len(oldValue) == 0 {
+			return nil, errNoWatchers
+		}
+		return nil, nil
+	})
+	if errors.Is(err, errNoWatchers) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,16 @@
+package kvstore
+
+import (
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const welcomedPrefix = "welcomed-"
+
+func (kv Client) MarkWelcomed(userID string) (bool, error) {
+	marked, err := kv.client.KV.Set(welcomedPrefix+userID, true, pluginapi.SetAtomic(nil))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to mark user as welcomed")
+	}
+	return marked, nil
+}
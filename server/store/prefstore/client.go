@@ -0,0 +1,54 @@
+package prefstore
+
+import (
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/pkg/errors"
+)
+
+// We expose the user preferences through this interface so that the server and the webapp user
+// settings agree on a single place where each setting is stored.
+
+type Client struct {
+	api plugin.API
+}
+
+func NewPreferenceStore(api plugin.API) PreferenceStore {
+	return Client{
+		api: api,
+	}
+}
+
+// GetPreference looks the preference up in the full list rather than asking for it by name, as the
+// server reports a missing preference as an error that can't be told apart from a failed lookup.
+func (c Client) GetPreference(userID, name string) (string, error) {
+	preferences, appErr := c.api.GetPreferencesForUser(userID)
+	if appErr != nil {
+		return "", errors.Wrapf(appErr, "failed to get preference %s", name)
+	}
+
+	for _, preference := range preferences {
+		if preference.Category == Category && preference.Name == name {
+			return preference.Value, nil
+		}
+	}
+	return "", nil
+}
+
+// SetPreferences saves the preferences in a single update, so that the webapp is told of the change once.
+func (c Client) SetPreferences(userID string, values map[string]string) error {
+	preferences := make(model.Preferences, 0, len(values))
+	for name, value := range values {
+		preferences = append(preferences, model.Preference{
+			UserId:   userID,
+			Category: Category,
+			Name:     name,
+			Value:    value,
+		})
+	}
+
+	if appErr := c.api.UpdatePreferencesForUser(userID, preferences); appErr != nil {
+		return errors.Wrap(appErr, "failed to save preferences")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package prefstore
+
+// Category is the preference category the webapp user settings are saved under.
+const Category = "pp_com.mattermost.plugin-shoutrr"
+
+const (
+	// NotificationServices is the comma separated list of Shoutrrr URLs configured by the user.
+	NotificationServices = "notification_services"
+
+	// ServiceLabels is a JSON object mapping the target IDs of the user's services to their labels.
+	ServiceLabels = "service_labels"
+
+	// DisabledServices is the comma separated list of the target IDs of the services the user
+	// disabled without removing them.
+	DisabledServices = "disabled_services"
+
+	// PriorityMapping is a JSON object overriding the Shoutrrr params sent for each priority.
+	PriorityMapping = "priority_mapping"
+
+	// MessageTemplate is the Go text/template used to render the user's notifications.
+	MessageTemplate = "message_template"
+
+	// TitleTemplate is the Go text/template used to render the title of the user's notifications.
+	TitleTemplate = "title_template"
+
+	// MessageFormats is a JSON object overriding the format messages are sent in for each scheme.
+	MessageFormats = "message_formats"
+
+	// DigestInterval is the number of minutes mentions are batched for before being sent as a
+	// digest, empty or zero to notify each mention immediately.
+	DigestInterval = "digest_interval"
+
+	// DigestImmediateDMs is "false" when direct messages should wait for the digest too.
+	DigestImmediateDMs = "digest_immediate_dms"
+
+	// UserRateLimit is the number of notifications per minute the user can receive in total, empty
+	// to use the admin's limit.
+	UserRateLimit = "user_rate_limit"
+
+	// TargetRateLimit is the number of notifications per minute each of the user's services can
+	// receive, empty to use the admin's limit.
+	TargetRateLimit = "target_rate_limit"
+
+	// EditNotifications is "false" when the user doesn't want to be notified of edits that mention them.
+	EditNotifications = "edit_notifications"
+
+	// ReactionNotifications is "true" when the user wants to be notified of reactions to their posts.
+	ReactionNotifications = "reaction_notifications"
+
+	// ReactionEmojiAllowlist is the comma separated list of the only emoji whose reactions are
+	// notified, empty to notify every emoji.
+	ReactionEmojiAllowlist = "reaction_emoji_allowlist"
+
+	// ReactionEmojiDenylist is the comma separated list of emoji whose reactions are never notified.
+	ReactionEmojiDenylist = "reaction_emoji_denylist"
+
+	// FollowPushSettings is "true" when the user's notifications follow their Mattermost push
+	// notification preferences.
+	FollowPushSettings = "follow_push_settings"
+
+	// SnoozeEndMessage is "true" when the user wants a direct message from the plugin's bot when
+	// their snooze ends.
+	SnoozeEndMessage = "snooze_end_message"
+)
+
+type PreferenceStore interface {
+	// GetPreference returns the value of one of the plugin's user preferences, or an empty string if unset.
+	GetPreference(userID, name string) (string, error)
+
+	// SetPreferences saves some of the plugin's user preferences, keyed by name.
+	SetPreferences(userID string, values map[string]string) error
+}
@@ -0,0 +1,109 @@
+package prefstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Target is one of the Shoutrrr URLs a user configured, with the label and state the slash
+// command manages alongside the list of URLs the webapp edits.
+type Target struct {
+	// ID identifies the target without exposing its credentials.
+	ID    string
+	URL   string
+	Label string
+
+	// Enabled is false for targets the user disabled without removing them.
+	Enabled bool
+}
+
+// TargetID identifies a user's target in the KV store and in the preferences without storing its
+// credentials again.
+func TargetID(serviceURL string) string {
+	hash := sha256.Sum256([]byte(serviceURL))
+	return hex.EncodeToString(hash[:8])
+}
+
+// SplitList splits a comma separated preference, ignoring empty entries.
+func SplitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// GetTargets returns the user's targets, in the order they were added.
+func GetTargets(store PreferenceStore, userID string) ([]*Target, error) {
+	services, err := store.GetPreference(userID, NotificationServices)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{}
+	labelsStr, err := store.GetPreference(userID, ServiceLabels)
+	if err != nil {
+		return nil, err
+	}
+	if labelsStr != "" {
+		if err := json.Unmarshal([]byte(labelsStr), &labels); err != nil {
+			return nil, errors.Wrap(err, "failed to parse service labels")
+		}
+	}
+
+	disabled, err := store.GetPreference(userID, DisabledServices)
+	if err != nil {
+		return nil, err
+	}
+	disabledIDs := map[string]bool{}
+	for _, id := range SplitList(disabled) {
+		disabledIDs[id] = true
+	}
+
+	var targets []*Target
+	for _, serviceURL := range SplitList(services) {
+		id := TargetID(serviceURL)
+		targets = append(targets, &Target{
+			ID:      id,
+			URL:     serviceURL,
+			Label:   labels[id],
+			Enabled: !disabledIDs[id],
+		})
+	}
+	return targets, nil
+}
+
+// SaveTargets replaces the user's targets. The labels and states of the targets that aren't in
+// the list anymore are dropped along with them.
+func SaveTargets(store PreferenceStore, userID string, targets []*Target) error {
+	urls := make([]string, 0, len(targets))
+	labels := map[string]string{}
+	var disabled []string
+	for _, target := range targets {
+		urls = append(urls, target.URL)
+		id := TargetID(target.URL)
+		if target.Label != "" {
+			labels[id] = target.Label
+		}
+		if !target.Enabled {
+			disabled = append(disabled, id)
+		}
+	}
+
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode service labels")
+	}
+
+	return store.SetPreferences(userID, map[string]string{
+		NotificationServices: strings.Join(urls, ","),
+		ServiceLabels:        string(labelsJSON),
+		DisabledServices:     strings.Join(disabled, ","),
+	})
+}
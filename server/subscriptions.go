@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+var (
+	subscriptionForbiddenMessage = &i18n.Message{
+		ID:    "api.subscription.forbidden",
+		Other: "You can only subscribe to channels you are a member of",
+	}
+	subscriptionNotFoundMessage = &i18n.Message{
+		ID:    "api.subscription.not_found",
+		Other: "Subscription not found",
+	}
+)
+
+// notifySubscribers forwards a post to the users subscribed to its channel whose filters match it,
+// except the users already notified of a mention in the post.
+func (p *Plugin) notifySubscribers(post *model.Post, mentions map[string]MentionType) {
+	if post.IsSystemMessage() {
+		return
+	}
+
+	subscribers, err := p.kvstore.GetChannelSubscribers(post.ChannelId)
+	if err != nil {
+		p.API.LogError("Failed to get channel subscribers", "error", err.Error(), "channelId", post.ChannelId)
+		return
+	}
+	if len(subscribers) == 0 {
+		return
+	}
+
+	sender, appErr := p.API.GetUser(post.UserId)
+	if appErr != nil {
+		p.API.LogError("Failed to get sender for subscriptions", "error", appErr.Error())
+		return
+	}
+
+	mentionTypes := make(map[string]string)
+	for _, userID := range subscribers {
+		if _, mentioned := mentions[userID]; mentioned || userID == post.UserId {
+			continue
+		}
+
+		// Users who left the channel or lost access to it keep their subscriptions but don't get its posts
+		if !p.canReadChannel(userID, post.ChannelId) {
+			continue
+		}
+
+		subscriptions, err := p.kvstore.GetSubscriptions(userID)
+		if err != nil {
+			p.API.LogError("Failed to get subscriptions", "error", err.Error(), "userId", userID)
+			continue
+		}
+
+		for _, subscription := range subscriptions {
+			if subscription.ChannelID == post.ChannelId && subscription.Matches(post, sender.Username) {
+				mentionTypes[userID] = notification.MentionTypeSubscription
+				break
+			}
+		}
+	}
+	if len(mentionTypes) == 0 {
+		return
+	}
+
+	p.notifyMentionedUsers(post, mentionTypes, 0)
+}
+
+// canReadChannel returns whether the user is a member of the channel and allowed to read its posts.
+func (p *Plugin) canReadChannel(userID, channelID string) bool {
+	if _, appErr := p.API.GetChannelMember(channelID, userID); appErr != nil {
+		return false
+	}
+	return p.API.HasPermissionToChannel(userID, channelID, model.PermissionReadChannelContent)
+}
+
+// GetSubscriptions returns the channel subscriptions of the user.
+func (p *Plugin) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	subscriptions, err := p.kvstore.GetSubscriptions(userID)
+	if err != nil {
+		p.API.LogError("Failed to get subscriptions", "error", err.Error(), "userId", userID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if subscriptions == nil {
+		subscriptions = []*kvstore.Subscription{}
+	}
+
+	p.writeJSON(w, http.StatusOK, subscriptions)
+}
+
+// CreateSubscription subscribes the user to a channel they can read.
+func (p *Plugin) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	var request kvstore.Subscription
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.ChannelID == "" {
+		http.Error(w, p.localizer.Localize(userID, invalidRequestBodyMessage, nil), http.StatusBadRequest)
+		return
+	}
+
+	if !p.canReadChannel(userID, request.ChannelID) {
+		http.Error(w, p.localizer.Localize(userID, subscriptionForbiddenMessage, nil), http.StatusForbidden)
+		return
+	}
+
+	subscription := &kvstore.Subscription{
+		ID:        model.NewId(),
+		ChannelID: request.ChannelID,
+		Filter:    request.Filter.Normalize(),
+		CreateAt:  model.GetMillis(),
+	}
+	if err := p.kvstore.AddSubscription(userID, subscription); err != nil {
+		p.API.LogError("Failed to save subscription", "error", err.Error(), "userId", userID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p.writeJSON(w, http.StatusCreated, subscription)
+}
+
+// DeleteSubscription deletes one of the user's channel subscriptions.
+func (p *Plugin) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	deleted, err := p.kvstore.DeleteSubscription(userID, mux.Vars(r)["subscriptionId"])
+	if err != nil {
+		p.API.LogError("Failed to delete subscription", "error", err.Error(), "userId", userID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if deleted == nil {
+		http.Error(w, p.localizer.Localize(userID, subscriptionNotFoundMessage, nil), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
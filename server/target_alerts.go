@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/notification"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	disableTargetAction = "disable"
+	testTargetAction    = "test"
+
+	// targetIDContextKey is the key of the target ID in the context of the alerts' buttons.
+	targetIDContextKey = "target_id"
+)
+
+var (
+	targetFailingMessage = &i18n.Message{
+		ID:    "alert.target_failing",
+		Other: "Your Shoutrrr notifications to {{.Target}} failed {{.Count}} times in a row. The last error was: {{.Error}}",
+	}
+	disableTargetButton = &i18n.Message{
+		ID:    "alert.button.disable",
+		Other: "Disable target",
+	}
+	testTargetButton = &i18n.Message{
+		ID:    "alert.button.test",
+		Other: "Send a test",
+	}
+	targetDisabledAlertMessage = &i18n.Message{
+		ID:    "alert.disabled",
+		Other: "Disabled {{.Target}}. Enable it again with /shoutrrr enable once it's fixed.",
+	}
+	targetTestSentMessage = &i18n.Message{
+		ID:    "alert.test_sent",
+		Other: "Sent a test notification to {{.Target}}",
+	}
+	targetTestFailedMessage = &i18n.Message{
+		ID:    "alert.test_failed",
+		Other: "The test notification to {{.Target}} failed: {{.Error}}",
+	}
+	targetGoneMessage = &i18n.Message{
+		ID:    "alert.target_gone",
+		Other: "This target was removed from your settings",
+	}
+)
+
+// describeTarget names a target in the bot's messages, by its label or its redacted URL.
+func describeTarget(target *prefstore.Target) string {
+	if target.Label != "" {
+		return "**" + target.Label + "**"
+	}
+	return "`" + notification.RedactURL(target.URL) + "`"
+}
+
+// findUserTarget returns the user's target with the ID, nil if they have none, along with all
+// their targets.
+func (p *Plugin) findUserTarget(userID, targetID string) (*prefstore.Target, []*prefstore.Target, error) {
+	targets, err := prefstore.GetTargets(p.prefstore, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, target := range targets {
+		if target.ID == targetID {
+			return target, targets, nil
+		}
+	}
+	return nil, targets, nil
+}
+
+// onDeliveryFailed warns the user from the bot once one of their targets has failed as many times
+// in a row as the admin allows, with buttons to disable or test it.
+func (p *Plugin) onDeliveryFailed(userID, serviceURL string, status *kvstore.DeliveryStatus) {
+	threshold := p.getConfiguration().FailureAlertThreshold
+	if threshold <= 0 || status.ConsecutiveFailures != threshold {
+		return
+	}
+
+	target, _, err := p.findUserTarget(userID, status.TargetID)
+	if err != nil || target == nil {
+		// The target was removed meanwhile
+		return
+	}
+
+	actionURL := "/plugins/" + pluginID + "/api/v1/targets/actions/"
+	context := map[string]any{targetIDContextKey: target.ID}
+
+	post := &model.Post{
+		Message: p.localizer.Localize(userID, targetFailingMessage, map[string]any{
+			"Target": describeTarget(target),
+			"Count":  status.ConsecutiveFailures,
+			"Error":  status.LastError,
+		}),
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Actions: []*model.PostAction{
+			{
+				Id:    disableTargetAction,
+				Name:  p.localizer.Localize(userID, disableTargetButton, nil),
+				Style: "danger",
+				Integration: &model.PostActionIntegration{
+					URL:     actionURL + disableTargetAction,
+					Context: context,
+				},
+			},
+			{
+				Id:   testTargetAction,
+				Name: p.localizer.Localize(userID, testTargetButton, nil),
+				Integration: &model.PostActionIntegration{
+					URL:     actionURL + testTargetAction,
+					Context: context,
+				},
+			},
+		},
+	}})
+
+	if err := p.client.Post.DM(p.botUserID, userID, post); err != nil {
+		p.API.LogError("Failed to send target failure alert", "error", err.Error(), "userId", userID)
+	}
+}
+
+// HandleTargetAction handles the buttons of the failure alerts, which disable or test one of the
+// user's targets.
+func (p *Plugin) HandleTargetAction(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.UserId != userID {
+		http.Error(w, p.localizer.Localize(userID, invalidRequestBodyMessage, nil), http.StatusBadRequest)
+		return
+	}
+	targetID, _ := request.Context[targetIDContextKey].(string)
+
+	target, targets, err := p.findUserTarget(userID, targetID)
+	if err != nil {
+		p.API.LogError("Failed to get targets", "error", err.Error(), "userId", userID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if target == nil {
+		p.writeJSON(w, http.StatusOK, &model.PostActionIntegrationResponse{
+			EphemeralText: p.localizer.Localize(userID, targetGoneMessage, nil),
+		})
+		return
+	}
+
+	var response model.PostActionIntegrationResponse
+	switch mux.Vars(r)["action"] {
+	case disableTargetAction:
+		target.Enabled = false
+		if err := prefstore.SaveTargets(p.prefstore, userID, targets); err != nil {
+			p.API.LogError("Failed to save targets", "error", err.Error(), "userId", userID)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response.EphemeralText = p.localizer.Localize(userID, targetDisabledAlertMessage, map[string]any{"Target": describeTarget(target)})
+	case testTargetAction:
+		if err := p.notificationService.SendTestNotification(userID, target.URL); err != nil {
+			response.EphemeralText = p.localizer.Localize(userID, targetTestFailedMessage, map[string]any{
+				"Target": describeTarget(target),
+				"Error":  err.Error(),
+			})
+		} else {
+			response.EphemeralText = p.localizer.Localize(userID, targetTestSentMessage, map[string]any{"Target": describeTarget(target)})
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	p.writeJSON(w, http.StatusOK, &response)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/prefstore"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// memoryPreferences is a preference store keeping the preferences of a single user in memory.
+type memoryPreferences map[string]string
+
+func (m memoryPreferences) GetPreference(_, name string) (string, error) {
+	return m[name], nil
+}
+
+func (m memoryPreferences) SetPreferences(_ string, values map[string]string) error {
+	for name, value := range values {
+		m[name] = value
+	}
+	return nil
+}
+
+func TestOnDeliveryFailed(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &plugintest.API{}
+	plugin := &Plugin{
+		client:    pluginapi.NewClient(api, &plugintest.Driver{}),
+		prefstore: memoryPreferences{prefstore.NotificationServices: "ntfy://ntfy.sh/secret-topic"},
+		botUserID: "bot",
+	}
+	plugin.setConfiguration(&configuration{FailureAlertThreshold: 3})
+	targetID := prefstore.TargetID("ntfy://ntfy.sh/secret-topic")
+
+	// Below and past the threshold, nothing is sent
+	plugin.onDeliveryFailed("user", "ntfy://ntfy.sh/secret-topic", &kvstore.DeliveryStatus{TargetID: targetID, ConsecutiveFailures: 2})
+	plugin.onDeliveryFailed("user", "ntfy://ntfy.sh/secret-topic", &kvstore.DeliveryStatus{TargetID: targetID, ConsecutiveFailures: 4})
+
+	var alert *model.Post
+	api.On("GetDirectChannel", "bot", "user").Return(&model.Channel{Id: "dm"}, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		alert = post.Clone()
+		return alert
+	}, nil)
+
+	plugin.onDeliveryFailed("user", "ntfy://ntfy.sh/secret-topic", &kvstore.DeliveryStatus{
+		TargetID:            targetID,
+		ConsecutiveFailures: 3,
+		LastError:           "got response status 401",
+	})
+
+	assert.NotNil(alert)
+	assert.Equal("dm", alert.ChannelId)
+	assert.Equal("Your Shoutrrr notifications to `ntfy://ntfy.sh/***` failed 3 times in a row. The last error was: got response status 401", alert.Message)
+	actions := alert.Attachments()[0].Actions
+	assert.Len(actions, 2)
+	assert.Equal("/plugins/"+pluginID+"/api/v1/targets/actions/disable", actions[0].Integration.URL)
+	assert.Equal(targetID, actions[0].Integration.Context[targetIDContextKey])
+}
+
+func TestHandleTargetAction(t *testing.T) {
+	assert := assert.New(t)
+
+	preferences := memoryPreferences{prefstore.NotificationServices: "ntfy://ntfy.sh/secret-topic"}
+	plugin := &Plugin{prefstore: preferences}
+	targetID := prefstore.TargetID("ntfy://ntfy.sh/secret-topic")
+
+	run := func(action, userID, targetID string) (int, string) {
+		body, _ := json.Marshal(&model.PostActionIntegrationRequest{
+			UserId:  userID,
+			Context: map[string]any{targetIDContextKey: targetID},
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/targets/actions/"+action, strings.NewReader(string(body)))
+		r.Header.Set("Mattermost-User-ID", "user")
+		plugin.ServeHTTP(nil, w, r)
+
+		var response model.PostActionIntegrationResponse
+		_ = json.NewDecoder(w.Result().Body).Decode(&response)
+		return w.Result().StatusCode, response.EphemeralText
+	}
+
+	status, _ := run(disableTargetAction, "someone-else", targetID)
+	assert.Equal(http.StatusBadRequest, status)
+
+	status, text := run(disableTargetAction, "user", "unknown")
+	assert.Equal(http.StatusOK, status)
+	assert.Equal("This target was removed from your settings", text)
+
+	status, text = run(disableTargetAction, "user", targetID)
+	assert.Equal(http.StatusOK, status)
+	assert.Equal("Disabled `ntfy://ntfy.sh/***`. Enable it again with /shoutrrr enable once it's fixed.", text)
+	assert.Equal(targetID, preferences[prefstore.DisabledServices])
+}
+
+func TestBotPostsAreIgnored(t *testing.T) {
+	api := &plugintest.API{}
+	defer api.AssertExpectations(t)
+	plugin := &Plugin{
+		client:    pluginapi.NewClient(api, &plugintest.Driver{}),
+		botUserID: "bot",
+	}
+	plugin.SetAPI(api)
+
+	// Any call to the API would fail the test, as none is expected
+	plugin.MessageHasBeenPosted(nil, &model.Post{Id: "alert", UserId: "bot", ChannelId: "dm", Message: "@user your target failed"})
+}
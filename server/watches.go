@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxWatchRules is the number of watch rules each user can have.
+	maxWatchRules = 25
+
+	// maxWatchPatternLength is the number of bytes in a phrase or regular expression.
+	maxWatchPatternLength = 256
+
+	// maxWatchProgramSize bounds the number of instructions a compiled regular expression can have.
+	// Go's regular expressions run in linear time, so this bounds the cost of matching each byte.
+	maxWatchProgramSize = 2000
+
+	// maxWatchTextLength is the number of bytes of a post that watch rules are matched against.
+	maxWatchTextLength = 16 * 1024
+
+	// watchEvaluationBudget is how long the watch rules of each user can take to match a single
+	// post. The user's remaining rules are skipped once it's exceeded, so that slow rules can't hold
+	// up posting, while the rules of the other users still get their own budget.
+	watchEvaluationBudget = 10 * time.Millisecond
+
+	// maxCachedWatchPatterns is the number of compiled regular expressions kept between posts.
+	maxCachedWatchPatterns = 1000
+
+	// defaultPublicWatchRateLimit is the number of notifications per hour of the rules matching the
+	// public channels the user hasn't joined, unless they set their own. They can match the posts
+	// of a whole server, so they're limited by default, unlike the rules for the user's channels.
+	defaultPublicWatchRateLimit = 10
+)
+
+var (
+	watchRuleInvalidMessage = &i18n.Message{
+		ID:    "api.watch.invalid",
+		Other: "Invalid watch rule: {{.Error}}",
+	}
+	watchRuleLimitMessage = &i18n.Message{
+		ID:    "api.watch.limit",
+		Other: "You can have at most {{.Max}} watch rules",
+	}
+	watchRuleRateLimitMessage = &i18n.Message{
+		ID:    "api.watch.rate_limit",
+		Other: "The rate limit can't be negative",
+	}
+	watchRuleNotFoundMessage = &i18n.Message{
+		ID:    "api.watch.not_found",
+		Other: "Watch rule not found",
+	}
+)
+
+// compileWatchRule compiles the pattern of a watch rule into a case-insensitive regular
+// expression. Phrases match whole words, with any whitespace between them.
+func compileWatchRule(rule *kvstore.WatchRule) (*regexp.Regexp, error) {
+	pattern := strings.TrimSpace(rule.Pattern)
+	if pattern == "" {
+		return nil, errors.New("the pattern is empty")
+	}
+	if len(pattern) > maxWatchPatternLength {
+		return nil, errors.Errorf("the pattern is longer than %d characters", maxWatchPatternLength)
+	}
+
+	if !rule.Regex {
+		words := strings.Fields(pattern)
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		expr := strings.Join(words, `\s+`)
+
+		// Word boundaries only apply next to word characters, so that phrases like "c++" still match
+		if first, _ := utf8.DecodeRuneInString(pattern); isWordRune(first) {
+			expr = `\b` + expr
+		}
+		if last, _ := utf8.DecodeLastRuneInString(pattern); isWordRune(last) {
+			expr += `\b`
+		}
+		pattern = expr
+	}
+	pattern = "(?i)" + pattern
+
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	if len(prog.Inst) > maxWatchProgramSize {
+		return nil, errors.New("the regular expression is too complex")
+	}
+
+	return regexp.Compile(pattern)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// watchPatternCache keeps the compiled patterns of the watch rules, as they're matched against
+// every post. It's cleared once full, which is rare enough that a smarter eviction isn't worth it.
+var watchPatternCache = &regexpCache{patterns: map[string]*regexp.Regexp{}}
+
+type regexpCache struct {
+	lock     sync.Mutex
+	patterns map[string]*regexp.Regexp
+}
+
+// get returns the compiled pattern of a rule, or nil if it doesn't compile.
+func (c *regexpCache) get(rule *kvstore.WatchRule) *regexp.Regexp {
+	key := rule.Pattern
+	if rule.Regex {
+		key = "regex:" + key
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if re, ok := c.patterns[key]; ok {
+		return re
+	}
+
+	// Rules are validated when they're saved, so a rule that doesn't compile is cached as nil
+	re, _ := compileWatchRule(rule)
+	if len(c.patterns) >= maxCachedWatchPatterns {
+		c.patterns = map[string]*regexp.Regexp{}
+	}
+	c.patterns[key] = re
+	return re
+}
+
+// getWatchText returns the text of a post that watch rules are matched against: the fields where
+// mentions are possible, truncated to maxWatchTextLength bytes.
+func getWatchText(post *model.Post) string {
+	text := strings.Join(getMentionsEnabledFields(post), "\n")
+	if len(text) <= maxWatchTextLength {
+		return text
+	}
+
+	text = text[:maxWatchTextLength]
+	for !utf8.ValidString(text) {
+		text = text[:len(text)-1]
+	}
+	return text
+}
+
+// notifyWatchers notifies the users with a watch rule matching a post, except the users already
+// notified of a mention in the post. Rules match the posts of the channels the user is a member
+// of, and of the public channels of their teams if the rule opts in.
+func (p *Plugin) notifyWatchers(post *model.Post, mentions map[string]MentionType) {
+	if post.IsSystemMessage() {
+		return
+	}
+
+	watchers, err := p.kvstore.GetWatchers()
+	if err != nil {
+		p.API.LogError("Failed to get watchers", "error", err.Error())
+		return
+	}
+	if len(watchers) == 0 {
+		return
+	}
+
+	channel, appErr := p.API.GetChannel(post.ChannelId)
+	if appErr != nil {
+		p.API.LogError("Failed to get channel for watch rules", "error", appErr.Error())
+		return
+	}
+
+	text := getWatchText(post)
+
+	mentionTypes := make(map[string]string)
+	for _, userID := range watchers {
+		if _, mentioned := mentions[userID]; mentioned || userID == post.UserId {
+			continue
+		}
+
+		rules, err := p.kvstore.GetWatchRules(userID)
+		if err != nil {
+			p.API.LogError("Failed to get watch rules", "error", err.Error(), "userId", userID)
+			continue
+		}
+
+		matched, complete := matchWatchRules(rules, channel, text, time.Now().Add(watchEvaluationBudget))
+		if !complete {
+			p.API.LogWarn("Watch rules exceeded their evaluation budget, skipping the remaining rules", "userId", userID, "postId", post.Id)
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		member := p.canReadChannel(userID, post.ChannelId)
+		public := !member && p.canReadPublicChannel(userID, channel)
+		for _, rule := range matched {
+			if !member && !(public && rule.PublicChannels) {
+				continue
+			}
+			if !p.takeWatchRuleToken(userID, rule) {
+				p.API.LogDebug("Watch rule rate limited", "userId", userID, "ruleId", rule.ID)
+				continue
+			}
+
+			mentionTypes[userID] = formatMentionType(WatchMention)
+			break
+		}
+	}
+	if len(mentionTypes) == 0 {
+		return
+	}
+
+	p.notifyMentionedUsers(post, mentionTypes, 0)
+}
+
+// matchWatchRules returns the rules that apply to the channel and match the text, and whether
+// every rule was evaluated before the deadline. Only the first maxWatchRules rules are evaluated.
+func matchWatchRules(rules []*kvstore.WatchRule, channel *model.Channel, text string, deadline time.Time) ([]*kvstore.WatchRule, bool) {
+	if len(rules) > maxWatchRules {
+		rules = rules[:maxWatchRules]
+	}
+
+	var matched []*kvstore.WatchRule
+	for _, rule := range rules {
+		if !rule.InScope(channel.TeamId, channel.Id) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return matched, false
+		}
+		if re := watchPatternCache.get(rule); re != nil && re.MatchString(text) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched, true
+}
+
+// canReadPublicChannel returns whether the channel is public and the user can read it without
+// joining it, as a member of its team.
+func (p *Plugin) canReadPublicChannel(userID string, channel *model.Channel) bool {
+	if channel.Type != model.ChannelTypeOpen {
+		return false
+	}
+	if member, appErr := p.API.GetTeamMember(channel.TeamId, userID); appErr != nil || member.DeleteAt != 0 {
+		return false
+	}
+	return p.API.HasPermissionToChannel(userID, channel.Id, model.PermissionReadChannelContent)
+}
+
+// takeWatchRuleToken returns whether the rule can trigger another notification within its hourly
+// rate limit. Like the notification rate limits, it lets notifications through when the KV store fails.
+func (p *Plugin) takeWatchRuleToken(userID string, rule *kvstore.WatchRule) bool {
+	limit := rule.RateLimit
+	if limit == 0 && rule.PublicChannels {
+		limit = defaultPublicWatchRateLimit
+	}
+	if limit <= 0 {
+		return true
+	}
+
+	taken, err := p.kvstore.TakeRateLimitToken("watch-"+userID+"-"+rule.ID, limit, time.Hour)
+	if err != nil {
+		p.API.LogWarn("Failed to check watch rule rate limit", "userId", userID, "ruleId", rule.ID, "error", err.Error())
+		return true
+	}
+	return taken
+}
+
+// GetWatchRules returns the watch rules of the user.
+func (p *Plugin) GetWatchRules(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	rules, err := p.kvstore.GetWatchRules(userID)
+	if err != nil {
+		p.API.LogError("Failed to get watch rules", "error", err.Error(), "userId", userID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rules == nil {
+		rules = []*kvstore.WatchRule{}
+	}
+
+	p.writeJSON(w, http.StatusOK, rules)
+}
+
+// CreateWatchRule adds a watch rule for the user, once its pattern is validated.
+func (p *Plugin) CreateWatchRule(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	var request kvstore.WatchRule
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, p.localizer.Localize(userID, invalidRequestBodyMessage, nil), http.StatusBadRequest)
+		return
+	}
+
+	if request.RateLimit < 0 {
+		http.Error(w, p.localizer.Localize(userID, watchRuleRateLimitMessage, nil), http.StatusBadRequest)
+		return
+	}
+
+	rule := &kvstore.WatchRule{
+		ID:             model.NewId(),
+		Pattern:        strings.TrimSpace(request.Pattern),
+		Regex:          request.Regex,
+		TeamIDs:        request.TeamIDs,
+		ChannelIDs:     request.ChannelIDs,
+		PublicChannels: request.PublicChannels,
+		RateLimit:      request.RateLimit,
+		CreateAt:       model.GetMillis(),
+	}
+	if _, err := compileWatchRule(rule); err != nil {
+		http.Error(w, p.localizer.Localize(userID, watchRuleInvalidMessage, map[string]any{"Error": err.Error()}), http.StatusBadRequest)
+		return
+	}
+
+	rules, err := p.kvstore.GetWatchRules(userID)
+	if err != nil {
+		p.API.LogError("Failed to get watch rules", "error", err.Error(), "userId", userID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(rules) >= maxWatchRules {
+		http.Error(w, p.localizer.Localize(userID, watchRuleLimitMessage, map[string]any{"Max": maxWatchRules}), http.StatusBadRequest)
+		return
+	}
+
+	if err := p.kvstore.AddWatchRule(userID, rule); err != nil {
+		p.API.LogError("Failed to save watch rule", "error", err.Error(), "userId", userID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p.writeJSON(w, http.StatusCreated, rule)
+}
+
+// DeleteWatchRule deletes one of the user's watch rules.
+func (p *Plugin) DeleteWatchRule(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	deleted, err := p.kvstore.DeleteWatchRule(userID, mux.Vars(r)["watchId"])
+	if err != nil {
+		p.API.LogError("Failed to delete watch rule", "error", err.Error(), "userId", userID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if deleted == nil {
+		http.Error(w, p.localizer.Localize(userID, watchRuleNotFoundMessage, nil), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
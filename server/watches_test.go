@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/store/kvstore"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileWatchRule(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		rule    kvstore.WatchRule
+		matches []string
+		misses  []string
+		err     bool
+	}{
+		{
+			name:    "phrase",
+			rule:    kvstore.WatchRule{Pattern: "release  train"},
+			matches: []string{"The Release\ntrain leaves at 5", "release train."},
+			misses:  []string{"prerelease train", "release trains", "release-train"},
+		},
+		{
+			name:    "phrase with symbols",
+			rule:    kvstore.WatchRule{Pattern: "C++"},
+			matches: []string{"anyone using c++?"},
+			misses:  []string{"c+"},
+		},
+		{
+			name:    "regex",
+			rule:    kvstore.WatchRule{Pattern: `sev[12]\b`, Regex: true},
+			matches: []string{"SEV1 declared", "this is a sev2"},
+			misses:  []string{"sev3", "sev10"},
+		},
+		{
+			name: "invalid regex",
+			rule: kvstore.WatchRule{Pattern: `(unclosed`, Regex: true},
+			err:  true,
+		},
+		{
+			name: "empty",
+			rule: kvstore.WatchRule{Pattern: "  "},
+			err:  true,
+		},
+		{
+			name: "too long",
+			rule: kvstore.WatchRule{Pattern: strings.Repeat("a", maxWatchPatternLength+1)},
+			err:  true,
+		},
+		{
+			name: "too complex",
+			rule: kvstore.WatchRule{Pattern: `\d{1000}\s{1000}\w{1000}`, Regex: true},
+			err:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			re, err := compileWatchRule(&tc.rule)
+			if tc.err {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			for _, text := range tc.matches {
+				assert.True(re.MatchString(text), text)
+			}
+			for _, text := range tc.misses {
+				assert.False(re.MatchString(text), text)
+			}
+		})
+	}
+}
+
+func TestMatchWatchRules(t *testing.T) {
+	assert := assert.New(t)
+
+	channel := &model.Channel{Id: "channel", TeamId: "team"}
+	dm := &model.Channel{Id: "dm"}
+	deadline := time.Now().Add(time.Minute)
+
+	match := func(rules []*kvstore.WatchRule, channel *model.Channel, text string) []*kvstore.WatchRule {
+		matched, complete := matchWatchRules(rules, channel, text, deadline)
+		assert.True(complete)
+		return matched
+	}
+
+	rules := []*kvstore.WatchRule{
+		{Pattern: "outage", TeamIDs: []string{"other-team"}},
+		{Pattern: "deploy", ChannelIDs: []string{"channel"}},
+		{Pattern: "(invalid", Regex: true},
+	}
+	assert.Empty(match(rules, channel, "outage in progress"))
+	assert.Equal([]*kvstore.WatchRule{rules[1]}, match(rules, channel, "Deploy done"))
+	assert.Empty(match(rules, dm, "Deploy done"))
+
+	teamRules := []*kvstore.WatchRule{
+		{Pattern: "lunch", TeamIDs: []string{"team"}},
+		{Pattern: "lunch|dinner", Regex: true},
+	}
+	assert.Equal(teamRules, match(teamRules, channel, "lunch?"))
+	assert.Equal(teamRules[1:], match(teamRules, dm, "lunch?"))
+}
+
+func TestMatchWatchRulesBudget(t *testing.T) {
+	assert := assert.New(t)
+
+	channel := &model.Channel{Id: "channel", TeamId: "team"}
+	rules := []*kvstore.WatchRule{{Pattern: "deploy"}}
+
+	// Once a user's budget is spent, their remaining rules are skipped
+	matched, complete := matchWatchRules(rules, channel, "deploy done", time.Now().Add(-time.Millisecond))
+	assert.Empty(matched)
+	assert.False(complete)
+
+	// Each user gets their own budget, so the next one is still matched
+	matched, complete = matchWatchRules(rules, channel, "deploy done", time.Now().Add(watchEvaluationBudget))
+	assert.Equal(rules, matched)
+	assert.True(complete)
+
+	// Rules beyond the limit aren't evaluated
+	many := make([]*kvstore.WatchRule, maxWatchRules+1)
+	for i := range many {
+		many[i] = &kvstore.WatchRule{Pattern: "nope"}
+	}
+	many[maxWatchRules] = &kvstore.WatchRule{Pattern: "deploy"}
+	matched, complete = matchWatchRules(many, channel, "deploy done", time.Now().Add(watchEvaluationBudget))
+	assert.Empty(matched)
+	assert.True(complete)
+}
+
+func TestGetWatchText(t *testing.T) {
+	assert := assert.New(t)
+
+	post := &model.Post{Message: "hello"}
+	post.AddProp("attachments", []*model.SlackAttachment{{Pretext: "build", Text: "failed"}})
+	assert.Equal("hello\nbuild\nfailed", getWatchText(post))
+
+	long := &model.Post{Message: strings.Repeat("é", maxWatchTextLength)}
+	text := getWatchText(long)
+	assert.LessOrEqual(len(text), maxWatchTextLength)
+	assert.True(strings.HasPrefix(long.Message, text))
+	assert.Equal(maxWatchTextLength/2, len([]rune(text)))
+}
+
+func TestCanReadPublicChannel(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &plugintest.API{}
+	api.On("GetTeamMember", "team", "member").Return(&model.TeamMember{TeamId: "team", UserId: "member"}, nil)
+	api.On("GetTeamMember", "team", "former").Return(&model.TeamMember{TeamId: "team", UserId: "former", DeleteAt: 1}, nil)
+	api.On("GetTeamMember", "team", "stranger").Return(nil, model.NewAppError("GetTeamMember", "not_found", nil, "", http.StatusNotFound))
+	api.On("HasPermissionToChannel", "member", "public", model.PermissionReadChannelContent).Return(true)
+	plugin := &Plugin{}
+	plugin.SetAPI(api)
+
+	public := &model.Channel{Id: "public", TeamId: "team", Type: model.ChannelTypeOpen}
+	private := &model.Channel{Id: "private", TeamId: "team", Type: model.ChannelTypePrivate}
+
+	assert.True(plugin.canReadPublicChannel("member", public))
+	assert.False(plugin.canReadPublicChannel("former", public))
+	assert.False(plugin.canReadPublicChannel("stranger", public))
+	assert.False(plugin.canReadPublicChannel("member", private))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost-plugin-shoutrrr/server/i18n"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+var welcomeMessage = &i18n.Message{
+	ID: "welcome.message",
+	Other: `#### Welcome to Shoutrrr notifications
+Your mentions are now also sent to the targets you added. To get the most out of them:
+- /shoutrrr test sends a test notification to check that your targets work.
+- /shoutrrr list shows your targets, and /shoutrrr rename labels them so you can tell them apart.
+- /shoutrrr snooze 2h pauses your notifications, and /shoutrrr mute ~channel silences a channel.
+- /shoutrrr status shows everything you're notified of.
+
+Channel subscriptions, watch rules, digests and message templates are in your Shoutrrr settings.`,
+}
+
+// sendWelcomeMessage sends the setup guide from the bot to a user who configured their first
+// targets, whether they used the settings or a slash command, unless they already got it.
+func (p *Plugin) sendWelcomeMessage(userID string) {
+	welcomed, err := p.kvstore.MarkWelcomed(userID)
+	if err != nil {
+		p.API.LogError("Failed to mark user as welcomed", "error", err.Error(), "userId", userID)
+		return
+	}
+	if !welcomed {
+		return
+	}
+
+	post := &model.Post{Message: p.localizer.Localize(userID, welcomeMessage, nil)}
+	if err := p.client.Post.DM(p.botUserID, userID, post); err != nil {
+		p.API.LogError("Failed to send welcome message", "error", err.Error(), "userId", userID)
+	}
+}
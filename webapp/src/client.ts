@@ -0,0 +1,123 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import {Client4} from 'mattermost-redux/client';
+
+import manifest from '@/manifest';
+
+const apiUrl = () => `${Client4.getUrl()}/plugins/${manifest.id}/api/v1`;
+
+export type TemplatePreview = {
+    preview?: string;
+    error?: string;
+};
+
+// previewTemplate validates a notification message or title template on the server and renders it with sample data.
+export async function previewTemplate(template: string, title = false): Promise<TemplatePreview> {
+    const response = await fetch(`${apiUrl()}/templates/preview`, Client4.getOptions({
+        method: 'post',
+        body: JSON.stringify({template, title}),
+    }));
+
+    return response.json();
+}
+
+export type Subscription = {
+    id: string;
+    channel_id: string;
+    senders?: string[];
+    keywords?: string[];
+    root_only?: boolean;
+    create_at: number;
+};
+
+// getSubscriptions returns the channel subscriptions of the current user.
+export async function getSubscriptions(): Promise<Subscription[]> {
+    const response = await fetch(`${apiUrl()}/subscriptions`, Client4.getOptions({method: 'get'}));
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+
+    return response.json();
+}
+
+// createSubscription subscribes the current user to a channel they are a member of.
+export async function createSubscription(subscription: Omit<Subscription, 'id' | 'create_at'>): Promise<Subscription> {
+    const response = await fetch(`${apiUrl()}/subscriptions`, Client4.getOptions({
+        method: 'post',
+        body: JSON.stringify(subscription),
+    }));
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+
+    return response.json();
+}
+
+// deleteSubscription deletes one of the current user's channel subscriptions.
+export async function deleteSubscription(id: string): Promise<void> {
+    const response = await fetch(`${apiUrl()}/subscriptions/${id}`, Client4.getOptions({method: 'delete'}));
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+}
+
+export type WatchRule = {
+    id: string;
+    pattern: string;
+    regex?: boolean;
+    team_ids?: string[];
+    channel_ids?: string[];
+    public_channels?: boolean;
+    rate_limit?: number;
+    create_at: number;
+};
+
+// getWatchRules returns the watch rules of the current user.
+export async function getWatchRules(): Promise<WatchRule[]> {
+    const response = await fetch(`${apiUrl()}/watches`, Client4.getOptions({method: 'get'}));
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+
+    return response.json();
+}
+
+// createWatchRule adds a watch rule for the current user, once the server validated its pattern.
+export async function createWatchRule(rule: Omit<WatchRule, 'id' | 'create_at'>): Promise<WatchRule> {
+    const response = await fetch(`${apiUrl()}/watches`, Client4.getOptions({
+        method: 'post',
+        body: JSON.stringify(rule),
+    }));
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+
+    return response.json();
+}
+
+// deleteWatchRule deletes one of the current user's watch rules.
+export async function deleteWatchRule(id: string): Promise<void> {
+    const response = await fetch(`${apiUrl()}/watches/${id}`, Client4.getOptions({method: 'delete'}));
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+}
+
+export type TargetHealth = {
+    health: 'unknown' | 'healthy' | 'degraded' | 'broken';
+    consecutive_failures?: number;
+    last_error?: string;
+    last_attempt_at?: number;
+    last_success_at?: number;
+};
+
+// getTargetHealth returns the health of the current user's targets, keyed by target ID.
+export async function getTargetHealth(): Promise<{[id: string]: TargetHealth}> {
+    const response = await fetch(`${apiUrl()}/targets/health`, Client4.getOptions({method: 'get'}));
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+
+    return response.json();
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import React, { useState } from 'react';
+import { useSelector } from 'react-redux';
+
+import type { PluginCustomSettingComponent } from '@mattermost/types/plugins/user_settings';
+
+const intervals: {value: string; label: string}[] = [
+    {value: '', label: 'Off, notify me of each mention'},
+    {value: '15', label: 'Every 15 minutes'},
+    {value: '30', label: 'Every 30 minutes'},
+    {value: '60', label: 'Every hour'},
+];
+
+const DigestSettings: PluginCustomSettingComponent = ({ informChange }) => {
+    const userPreferences = useSelector((state: any) => state.entities.preferences.myPreferences);
+    const savedInterval = (userPreferences[`pp_com.mattermost.plugin-shoutrr--digest_interval`] || {}).value || '';
+    const savedImmediateDMs = (userPreferences[`pp_com.mattermost.plugin-shoutrr--digest_immediate_dms`] || {}).value !== 'false';
+    const [interval, setDigestInterval] = useState<string>(savedInterval);
+    const [immediateDMs, setImmediateDMs] = useState<boolean>(savedImmediateDMs);
+
+    const handleIntervalChange = (value: string) => {
+        setDigestInterval(value);
+        informChange('digest_interval', value);
+    };
+
+    const handleImmediateDMsChange = (checked: boolean) => {
+        setImmediateDMs(checked);
+        informChange('digest_immediate_dms', checked ? '' : 'false');
+    };
+
+    return (
+        <div className='form-group'>
+            <select
+                className='form-control'
+                value={interval}
+                onChange={(e) => handleIntervalChange(e.target.value)}
+            >
+                {intervals.map(({value, label}) => (
+                    <option
+                        key={value}
+                        value={value}
+                    >
+                        {label}
+                    </option>
+                ))}
+            </select>
+            <div className='checkbox mt-2'>
+                <label>
+                    <input
+                        type='checkbox'
+                        checked={immediateDMs}
+                        disabled={interval === ''}
+                        onChange={(e) => handleImmediateDMsChange(e.target.checked)}
+                    />
+                    {' Notify me of direct messages immediately'}
+                </label>
+            </div>
+            <p className='mt-2 mb-0 text-muted small'>
+                Mentions are summarized by channel in a single notification. Urgent messages are always sent immediately.
+            </p>
+        </div>
+    );
+};
+
+export default DigestSettings;
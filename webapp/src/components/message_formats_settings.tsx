@@ -0,0 +1,92 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import React, { useState } from 'react';
+import { useSelector } from 'react-redux';
+
+import type { PluginCustomSettingComponent } from '@mattermost/types/plugins/user_settings';
+
+// The formats each service can display. Services not listed only receive plain text.
+const serviceFormats: {[scheme: string]: {label: string; formats: string[]}} = {
+    telegram: {label: 'Telegram', formats: ['plain', 'html', 'markdownv2']},
+    smtp: {label: 'Email', formats: ['plain', 'html']},
+    slack: {label: 'Slack', formats: ['plain', 'slack']},
+    discord: {label: 'Discord', formats: ['plain', 'markdown']},
+    mattermost: {label: 'Mattermost', formats: ['plain', 'markdown']},
+    rocketchat: {label: 'Rocket.Chat', formats: ['plain', 'markdown']},
+    teams: {label: 'Teams', formats: ['plain', 'markdown']},
+    zulip: {label: 'Zulip', formats: ['plain', 'markdown']},
+};
+
+const formatLabels: {[format: string]: string} = {
+    plain: 'Plain text',
+    markdown: 'Markdown',
+    slack: 'Slack mrkdwn',
+    html: 'HTML',
+    markdownv2: 'MarkdownV2',
+};
+
+const parseFormats = (value: string): {[scheme: string]: string} => {
+    try {
+        return JSON.parse(value) || {};
+    } catch (e) {
+        return {};
+    }
+};
+
+const MessageFormatsSettings: PluginCustomSettingComponent = ({ informChange }) => {
+    const userPreferences = useSelector((state: any) => state.entities.preferences.myPreferences);
+    const savedFormats = (userPreferences[`pp_com.mattermost.plugin-shoutrr--message_formats`] || {}).value || '';
+    const [formats, setFormats] = useState<{[scheme: string]: string}>(parseFormats(savedFormats));
+
+    const handleChange = (scheme: string, format: string) => {
+        const updated = {...formats};
+        if (format === '') {
+            delete updated[scheme];
+        } else {
+            updated[scheme] = format;
+        }
+
+        setFormats(updated);
+        informChange('message_formats', Object.keys(updated).length ? JSON.stringify(updated) : '');
+    };
+
+    return (
+        <div className='form-group'>
+            {Object.entries(serviceFormats).map(([scheme, {label, formats: options}]) => (
+                <div
+                    key={scheme}
+                    className='d-flex align-items-center mb-2'
+                >
+                    <label
+                        className='mb-0 mr-3'
+                        style={{minWidth: '120px'}}
+                    >
+                        {label}
+                    </label>
+                    <select
+                        className='form-control'
+                        value={formats[scheme] || ''}
+                        onChange={(e) => handleChange(scheme, e.target.value)}
+                    >
+                        <option value=''>Automatic</option>
+                        {options.map((format) => (
+                            <option
+                                key={format}
+                                value={format}
+                            >
+                                {formatLabels[format]}
+                            </option>
+                        ))}
+                    </select>
+                </div>
+            ))}
+            <p className='mt-2 mb-0 text-muted small'>
+                By default, messages use the richest format each service displays, except emails which are sent as plain text.
+                Matrix and push services always receive plain text.
+            </p>
+        </div>
+    );
+};
+
+export default MessageFormatsSettings;
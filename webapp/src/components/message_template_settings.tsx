@@ -0,0 +1,65 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import React, { useState, useEffect } from 'react';
+import { useSelector } from 'react-redux';
+
+import type { PluginCustomSettingComponent } from '@mattermost/types/plugins/user_settings';
+
+import { previewTemplate } from '@/client';
+
+const MessageTemplateSettings: PluginCustomSettingComponent = ({ informChange }) => {
+    const userPreferences = useSelector((state: any) => state.entities.preferences.myPreferences);
+    const savedTemplate = (userPreferences[`pp_com.mattermost.plugin-shoutrr--message_template`] || {}).value || '';
+    const [template, setTemplate] = useState<string>(savedTemplate);
+    const [preview, setPreview] = useState<string>('');
+    const [error, setError] = useState<string>('');
+
+    // Validate and preview the template on the server, only saving it once it renders.
+    useEffect(() => {
+        const timeout = setTimeout(async () => {
+            try {
+                const result = await previewTemplate(template);
+                if (result.error) {
+                    setError(result.error);
+                    setPreview('');
+                    return;
+                }
+
+                setError('');
+                setPreview(result.preview || '');
+                if (template !== savedTemplate) {
+                    informChange('message_template', template);
+                }
+            } catch (e) {
+                setError('Unable to preview the template.');
+            }
+        }, 500);
+
+        return () => clearTimeout(timeout);
+    }, [template]);
+
+    return (
+        <div className='form-group'>
+            <textarea
+                className='form-control'
+                rows={3}
+                placeholder='You were mentioned by @{{.SenderUsername}} in {{.Channel}}: {{.Message}}'
+                value={template}
+                onChange={(e) => setTemplate(e.target.value)}
+            />
+            {error && <p className='text-danger mt-2 mb-0'>{error}</p>}
+            {preview && (
+                <div className='mt-2 p-3 border rounded bg-white'>
+                    <strong>Preview:</strong> {preview}
+                </div>
+            )}
+            <p className='mt-2 mb-0 text-muted small'>
+                Go template with the fields <code>{'{{.SenderUsername}}'}</code>, <code>{'{{.SenderDisplayName}}'}</code>, <code>{'{{.Channel}}'}</code>, <code>{'{{.ChannelName}}'}</code>, <code>{'{{.Team}}'}</code>, <code>{'{{.TeamName}}'}</code>, <code>{'{{.MentionType}}'}</code>, <code>{'{{.Message}}'}</code>, <code>{'{{.Attachments}}'}</code>, <code>{'{{.Permalink}}'}</code> and <code>{'{{.ThreadRootExcerpt}}'}</code>.
+                Leave empty to use the default template.
+            </p>
+        </div>
+    );
+};
+
+export default MessageTemplateSettings;
@@ -0,0 +1,50 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import React, { useState } from 'react';
+import { useSelector } from 'react-redux';
+
+import type { PluginCustomSettingComponent } from '@mattermost/types/plugins/user_settings';
+
+const PriorityMappingSettings: PluginCustomSettingComponent = ({ informChange }) => {
+    const userPreferences = useSelector((state: any) => state.entities.preferences.myPreferences);
+    const savedMapping = (userPreferences[`pp_com.mattermost.plugin-shoutrr--priority_mapping`] || {}).value || '';
+    const [mapping, setMapping] = useState<string>(savedMapping);
+    const [error, setError] = useState<string>('');
+
+    const handleChange = (value: string) => {
+        setMapping(value);
+        if (value.trim() === '') {
+            setError('');
+            informChange('priority_mapping', '');
+            return;
+        }
+
+        try {
+            JSON.parse(value);
+            setError('');
+            informChange('priority_mapping', value);
+        } catch (e) {
+            setError('The priority mapping must be a valid JSON object.');
+        }
+    };
+
+    return (
+        <div className='form-group'>
+            <textarea
+                className='form-control'
+                rows={5}
+                placeholder='{"ntfy": {"high": {"priority": "5"}}}'
+                value={mapping}
+                onChange={(e) => handleChange(e.target.value)}
+            />
+            {error && <p className='text-danger mt-2 mb-0'>{error}</p>}
+            <p className='mt-2 mb-0 text-muted small'>
+                Override the params sent to each service for the priorities <code>min</code>, <code>low</code>, <code>default</code>, <code>high</code> and <code>max</code>.
+                Direct messages and mentions are sent as <code>high</code>, urgent posts as <code>max</code>.
+            </p>
+        </div>
+    );
+};
+
+export default PriorityMappingSettings;
@@ -0,0 +1,63 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import React, { useState } from 'react';
+import { useSelector } from 'react-redux';
+
+import type { PluginCustomSettingComponent } from '@mattermost/types/plugins/user_settings';
+
+const limits: {name: string; label: string}[] = [
+    {name: 'user_rate_limit', label: 'In total'},
+    {name: 'target_rate_limit', label: 'Per service'},
+];
+
+const RateLimitSettings: PluginCustomSettingComponent = ({ informChange }) => {
+    const userPreferences = useSelector((state: any) => state.entities.preferences.myPreferences);
+    const [values, setValues] = useState<{[name: string]: string}>(() => {
+        const saved: {[name: string]: string} = {};
+        limits.forEach(({name}) => {
+            saved[name] = (userPreferences[`pp_com.mattermost.plugin-shoutrr--${name}`] || {}).value || '';
+        });
+        return saved;
+    });
+
+    const handleChange = (name: string, value: string) => {
+        const limit = parseInt(value, 10);
+        const saved = limit > 0 ? String(limit) : '';
+
+        setValues({...values, [name]: value});
+        informChange(name, saved);
+    };
+
+    return (
+        <div className='form-group'>
+            {limits.map(({name, label}) => (
+                <div
+                    key={name}
+                    className='d-flex align-items-center mb-2'
+                >
+                    <label
+                        className='mb-0 mr-3'
+                        style={{minWidth: '120px'}}
+                    >
+                        {label}
+                    </label>
+                    <input
+                        type='number'
+                        min='1'
+                        className='form-control'
+                        placeholder='No limit'
+                        value={values[name]}
+                        onChange={(e) => handleChange(name, e.target.value)}
+                    />
+                </div>
+            ))}
+            <p className='mt-2 mb-0 text-muted small'>
+                Maximum notifications per minute. Notifications over the limit are summarized in a single follow-up.
+                Limits set by your administrator still apply.
+            </p>
+        </div>
+    );
+};
+
+export default RateLimitSettings;
@@ -0,0 +1,76 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import React, { useState } from 'react';
+import { useSelector } from 'react-redux';
+
+import type { PluginCustomSettingComponent } from '@mattermost/types/plugins/user_settings';
+
+const emojiLists: {name: string; label: string; placeholder: string}[] = [
+    {name: 'reaction_emoji_allowlist', label: 'Only these emoji', placeholder: 'Any emoji, e.g. tada, +1'},
+    {name: 'reaction_emoji_denylist', label: 'Except these emoji', placeholder: 'None, e.g. eyes'},
+];
+
+const ReactionSettings: PluginCustomSettingComponent = ({ informChange }) => {
+    const userPreferences = useSelector((state: any) => state.entities.preferences.myPreferences);
+    const getSaved = (name: string) => (userPreferences[`pp_com.mattermost.plugin-shoutrr--${name}`] || {}).value || '';
+    const [enabled, setEnabled] = useState<boolean>(getSaved('reaction_notifications') === 'true');
+    const [lists, setLists] = useState<{[name: string]: string}>(() => {
+        const saved: {[name: string]: string} = {};
+        emojiLists.forEach(({name}) => {
+            saved[name] = getSaved(name);
+        });
+        return saved;
+    });
+
+    const handleEnabledChange = (checked: boolean) => {
+        setEnabled(checked);
+        informChange('reaction_notifications', checked ? 'true' : '');
+    };
+
+    const handleListChange = (name: string, value: string) => {
+        setLists({...lists, [name]: value});
+        informChange(name, value);
+    };
+
+    return (
+        <div className='form-group'>
+            <div className='checkbox'>
+                <label>
+                    <input
+                        type='checkbox'
+                        checked={enabled}
+                        onChange={(e) => handleEnabledChange(e.target.checked)}
+                    />
+                    {' Notify me when someone reacts to my messages'}
+                </label>
+            </div>
+            {emojiLists.map(({name, label, placeholder}) => (
+                <div
+                    key={name}
+                    className='d-flex align-items-center mt-2'
+                >
+                    <label
+                        className='mb-0 mr-3'
+                        style={{minWidth: '140px'}}
+                    >
+                        {label}
+                    </label>
+                    <input
+                        type='text'
+                        className='form-control'
+                        placeholder={placeholder}
+                        disabled={!enabled}
+                        value={lists[name]}
+                        onChange={(e) => handleListChange(name, e.target.value)}
+                    />
+                </div>
+            ))}
+            <p className='mt-2 mb-0 text-muted small'>
+                Enter emoji names separated by commas. Reactions added within a minute are sent together.
+            </p>
+        </div>
+    );
+};
+
+export default ReactionSettings;
@@ -0,0 +1,139 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import React, { useEffect, useState } from 'react';
+import { useSelector } from 'react-redux';
+
+import type { Channel } from '@mattermost/types/channels';
+import type { PluginCustomSettingComponent } from '@mattermost/types/plugins/user_settings';
+
+import { createSubscription, deleteSubscription, getSubscriptions } from '../client';
+import type { Subscription } from '../client';
+
+const splitList = (value: string): string[] => value.split(',').map((entry) => entry.trim()).filter(Boolean);
+
+// Subscriptions are saved through the plugin's API as soon as they change, so this setting never calls informChange.
+const SubscriptionSettings: PluginCustomSettingComponent = () => {
+    const channels: {[id: string]: Channel} = useSelector((state: any) => state.entities.channels.channels);
+    const myMembers: {[id: string]: unknown} = useSelector((state: any) => state.entities.channels.myMembers);
+    const [subscriptions, setSubscriptions] = useState<Subscription[]>([]);
+    const [channelId, setChannelId] = useState('');
+    const [senders, setSenders] = useState('');
+    const [keywords, setKeywords] = useState('');
+    const [rootOnly, setRootOnly] = useState(false);
+    const [error, setError] = useState('');
+
+    useEffect(() => {
+        getSubscriptions().then(setSubscriptions).catch((e) => setError(e.message));
+    }, []);
+
+    const channelOptions = Object.keys(myMembers).
+        map((id) => channels[id]).
+        filter((channel) => channel && (channel.type === 'O' || channel.type === 'P')).
+        sort((a, b) => a.display_name.localeCompare(b.display_name));
+
+    const channelName = (id: string) => (channels[id] ? channels[id].display_name : id);
+
+    const handleAdd = async () => {
+        setError('');
+        try {
+            const subscription = await createSubscription({
+                channel_id: channelId,
+                senders: splitList(senders),
+                keywords: splitList(keywords),
+                root_only: rootOnly,
+            });
+            setSubscriptions([...subscriptions, subscription]);
+            setSenders('');
+            setKeywords('');
+            setRootOnly(false);
+        } catch (e: any) {
+            setError(e.message);
+        }
+    };
+
+    const handleDelete = async (id: string) => {
+        setError('');
+        try {
+            await deleteSubscription(id);
+            setSubscriptions(subscriptions.filter((subscription) => subscription.id !== id));
+        } catch (e: any) {
+            setError(e.message);
+        }
+    };
+
+    return (
+        <div className='form-group'>
+            {subscriptions.map((subscription) => (
+                <div
+                    key={subscription.id}
+                    className='d-flex align-items-center mb-2'
+                >
+                    <span className='mr-3'>
+                        <strong>{channelName(subscription.channel_id)}</strong>
+                        {subscription.senders?.length ? ` from ${subscription.senders.map((sender) => `@${sender}`).join(', ')}` : ''}
+                        {subscription.keywords?.length ? ` containing ${subscription.keywords.join(', ')}` : ''}
+                        {subscription.root_only ? ' (new threads only)' : ''}
+                    </span>
+                    <button
+                        type='button'
+                        className='btn btn-link'
+                        onClick={() => handleDelete(subscription.id)}
+                    >
+                        Remove
+                    </button>
+                </div>
+            ))}
+            <select
+                className='form-control mb-2'
+                value={channelId}
+                onChange={(e) => setChannelId(e.target.value)}
+            >
+                <option value=''>Select a channel</option>
+                {channelOptions.map((channel) => (
+                    <option
+                        key={channel.id}
+                        value={channel.id}
+                    >
+                        {channel.display_name}
+                    </option>
+                ))}
+            </select>
+            <input
+                type='text'
+                className='form-control mb-2'
+                placeholder='Only from these users, e.g. alice, bob'
+                value={senders}
+                onChange={(e) => setSenders(e.target.value)}
+            />
+            <input
+                type='text'
+                className='form-control mb-2'
+                placeholder='Only containing one of these words, e.g. outage, sev1'
+                value={keywords}
+                onChange={(e) => setKeywords(e.target.value)}
+            />
+            <div className='checkbox'>
+                <label>
+                    <input
+                        type='checkbox'
+                        checked={rootOnly}
+                        onChange={(e) => setRootOnly(e.target.checked)}
+                    />
+                    {' Only new threads, not replies'}
+                </label>
+            </div>
+            <button
+                type='button'
+                className='btn btn-primary'
+                disabled={channelId === ''}
+                onClick={handleAdd}
+            >
+                Subscribe
+            </button>
+            {error && <p className='text-danger mt-2 mb-0'>{error}</p>}
+        </div>
+    );
+};
+
+export default SubscriptionSettings;
@@ -7,12 +7,49 @@ import { useSelector } from 'react-redux';
 import manifest from '@/manifest';
 import type { PluginCustomSettingComponent } from '@mattermost/types/plugins/user_settings';
 
+import {getTargetHealth, TargetHealth} from '../client';
+
+// targetId matches the server's prefstore.TargetID: the first 8 bytes of the URL's SHA-256, in hex.
+const targetId = async (service: string): Promise<string> => {
+    const hash = await crypto.subtle.digest('SHA-256', new TextEncoder().encode(service));
+    return Array.from(new Uint8Array(hash).slice(0, 8)).map((b) => b.toString(16).padStart(2, '0')).join('');
+};
+
+const parseLabels = (value: string): {[id: string]: string} => {
+    try {
+        return JSON.parse(value) || {};
+    } catch {
+        return {};
+    }
+};
+
+// healthStyles describe the targets whose latest notifications failed, healthy targets aren't marked.
+const healthStyles: {[health: string]: {className: string; text: string}} = {
+    degraded: {className: 'text-warning', text: '(degraded)'},
+    broken: {className: 'text-danger', text: '(broken)'},
+};
+
 const NotificationServicesSettings: PluginCustomSettingComponent = ({ informChange }) => {
     const [services, setServices] = useState<string[]>([]);
     const [currentService, setCurrentService] = useState<string>('');
     const userPreferences = useSelector((state: any) => state.entities.preferences.myPreferences);
     const savedServices = (userPreferences[`pp_com.mattermost.plugin-shoutrr--notification_services`] || {}).value || '';
 
+    // Labels and disabled targets are managed with the /shoutrrr command
+    const labels = parseLabels((userPreferences['pp_com.mattermost.plugin-shoutrr--service_labels'] || {}).value || '{}');
+    const disabled = ((userPreferences['pp_com.mattermost.plugin-shoutrr--disabled_services'] || {}).value || '').split(',');
+    const [ids, setIds] = useState<{[service: string]: string}>({});
+    const [health, setHealth] = useState<{[id: string]: TargetHealth}>({});
+
+    useEffect(() => {
+        getTargetHealth().then(setHealth).catch(() => setHealth({}));
+    }, []);
+
+    useEffect(() => {
+        Promise.all(services.map(async (service) => [service, await targetId(service)])).
+            then((entries) => setIds(Object.fromEntries(entries)));
+    }, [services]);
+
     useEffect(() => {
         if (savedServices) {
             setServices(savedServices.split(',').map((s: string) => s.trim()).filter(Boolean));
@@ -103,7 +140,19 @@ const NotificationServicesSettings: PluginCustomSettingComponent = ({ informChan
                     <ul className='list-group'>
                         {services.map((service, index) => (
                             <li key={index} className='list-group-item d-flex justify-content-between align-items-center'>
-                                {service}
+                                <span>
+                                    {labels[ids[service]] && <strong className='mr-2'>{labels[ids[service]]}</strong>}
+                                    {service}
+                                    {disabled.includes(ids[service]) && <span className='text-muted ml-2'>{'(disabled)'}</span>}
+                                    {healthStyles[health[ids[service]]?.health] && (
+                                        <span
+                                            className={`${healthStyles[health[ids[service]].health].className} ml-2`}
+                                            title={health[ids[service]].last_error}
+                                        >
+                                            {healthStyles[health[ids[service]].health].text}
+                                        </span>
+                                    )}
+                                </span>
                                 <button
                                     className='btn btn-sm btn-danger'
                                     style={{position: 'absolute', right: '10px'}}
@@ -0,0 +1,190 @@
+// Copyright (c) 2023-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import React, { useEffect, useState } from 'react';
+import { useSelector } from 'react-redux';
+
+import type { Channel } from '@mattermost/types/channels';
+import type { PluginCustomSettingComponent } from '@mattermost/types/plugins/user_settings';
+import type { Team } from '@mattermost/types/teams';
+
+import { createWatchRule, deleteWatchRule, getWatchRules } from '../client';
+import type { WatchRule } from '../client';
+
+// Scopes are encoded as "team:<id>" or "channel:<id>" in the scope select, empty for everywhere.
+const parseScope = (scope: string): Pick<WatchRule, 'team_ids' | 'channel_ids'> => {
+    const [kind, id] = scope.split(':');
+    if (kind === 'team') {
+        return {team_ids: [id]};
+    }
+    if (kind === 'channel') {
+        return {channel_ids: [id]};
+    }
+    return {};
+};
+
+// Watch rules are saved through the plugin's API as soon as they change, so this setting never calls informChange.
+const WatchSettings: PluginCustomSettingComponent = () => {
+    const channels: {[id: string]: Channel} = useSelector((state: any) => state.entities.channels.channels);
+    const myChannelMembers: {[id: string]: unknown} = useSelector((state: any) => state.entities.channels.myMembers);
+    const teams: {[id: string]: Team} = useSelector((state: any) => state.entities.teams.teams);
+    const myTeamMembers: {[id: string]: unknown} = useSelector((state: any) => state.entities.teams.myMembers);
+    const [rules, setRules] = useState<WatchRule[]>([]);
+    const [pattern, setPattern] = useState('');
+    const [regex, setRegex] = useState(false);
+    const [scope, setScope] = useState('');
+    const [publicChannels, setPublicChannels] = useState(false);
+    const [rateLimit, setRateLimit] = useState('');
+    const [error, setError] = useState('');
+
+    useEffect(() => {
+        getWatchRules().then(setRules).catch((e) => setError(e.message));
+    }, []);
+
+    const teamOptions = Object.keys(myTeamMembers).
+        map((id) => teams[id]).
+        filter(Boolean).
+        sort((a, b) => a.display_name.localeCompare(b.display_name));
+
+    const channelOptions = Object.keys(myChannelMembers).
+        map((id) => channels[id]).
+        filter((channel) => channel && (channel.type === 'O' || channel.type === 'P')).
+        sort((a, b) => a.display_name.localeCompare(b.display_name));
+
+    const scopeLabel = (rule: WatchRule) => {
+        const names = [
+            ...(rule.team_ids || []).map((id) => (teams[id] ? teams[id].display_name : id)),
+            ...(rule.channel_ids || []).map((id) => (channels[id] ? `~${channels[id].display_name}` : id)),
+        ];
+        const where = names.length ? ` in ${names.join(', ')}` : '';
+        const publicLabel = rule.public_channels ? ', including public channels you haven\'t joined' : '';
+        const limitLabel = rule.rate_limit ? ` (at most ${rule.rate_limit} per hour)` : '';
+        return where + publicLabel + limitLabel;
+    };
+
+    const handleAdd = async () => {
+        setError('');
+        try {
+            const rule = await createWatchRule({
+                pattern,
+                regex,
+                public_channels: publicChannels,
+                rate_limit: parseInt(rateLimit, 10) || 0,
+                ...parseScope(scope),
+            });
+            setRules([...rules, rule]);
+            setPattern('');
+            setRegex(false);
+            setPublicChannels(false);
+            setRateLimit('');
+        } catch (e: any) {
+            setError(e.message);
+        }
+    };
+
+    const handleDelete = async (id: string) => {
+        setError('');
+        try {
+            await deleteWatchRule(id);
+            setRules(rules.filter((rule) => rule.id !== id));
+        } catch (e: any) {
+            setError(e.message);
+        }
+    };
+
+    return (
+        <div className='form-group'>
+            {rules.map((rule) => (
+                <div
+                    key={rule.id}
+                    className='d-flex align-items-center mb-2'
+                >
+                    <span className='mr-3'>
+                        {rule.regex ? <code>{`/${rule.pattern}/`}</code> : <strong>{`"${rule.pattern}"`}</strong>}
+                        {scopeLabel(rule)}
+                    </span>
+                    <button
+                        type='button'
+                        className='btn btn-link'
+                        onClick={() => handleDelete(rule.id)}
+                    >
+                        Remove
+                    </button>
+                </div>
+            ))}
+            <input
+                type='text'
+                className='form-control mb-2'
+                placeholder={regex ? 'Regular expression, e.g. sev[12]|outage' : 'Phrase, e.g. release train'}
+                value={pattern}
+                onChange={(e) => setPattern(e.target.value)}
+            />
+            <div className='checkbox'>
+                <label>
+                    <input
+                        type='checkbox'
+                        checked={regex}
+                        onChange={(e) => setRegex(e.target.checked)}
+                    />
+                    {' Regular expression'}
+                </label>
+            </div>
+            <select
+                className='form-control mb-2'
+                value={scope}
+                onChange={(e) => setScope(e.target.value)}
+            >
+                <option value=''>In all my channels</option>
+                <optgroup label='Teams'>
+                    {teamOptions.map((team) => (
+                        <option
+                            key={team.id}
+                            value={`team:${team.id}`}
+                        >
+                            {team.display_name}
+                        </option>
+                    ))}
+                </optgroup>
+                <optgroup label='Channels'>
+                    {channelOptions.map((channel) => (
+                        <option
+                            key={channel.id}
+                            value={`channel:${channel.id}`}
+                        >
+                            {channel.display_name}
+                        </option>
+                    ))}
+                </optgroup>
+            </select>
+            <div className='checkbox'>
+                <label>
+                    <input
+                        type='checkbox'
+                        checked={publicChannels}
+                        onChange={(e) => setPublicChannels(e.target.checked)}
+                    />
+                    {' Also watch the public channels of my teams that I haven\'t joined'}
+                </label>
+            </div>
+            <input
+                type='number'
+                min={0}
+                className='form-control mb-2'
+                placeholder={publicChannels ? 'Notifications per hour, 10 by default' : 'Notifications per hour, unlimited by default'}
+                value={rateLimit}
+                onChange={(e) => setRateLimit(e.target.value)}
+            />
+            <button
+                type='button'
+                className='btn btn-primary'
+                disabled={pattern.trim() === ''}
+                onClick={handleAdd}
+            >
+                Watch
+            </button>
+            {error && <p className='text-danger mt-2 mb-0'>{error}</p>}
+        </div>
+    );
+};
+
+export default WatchSettings;
@@ -8,12 +8,22 @@ import type {GlobalState} from '@mattermost/types/store';
 import type {
     PluginConfiguration,
     PluginConfigurationSection,
-    PluginConfigurationCustomSetting
+    PluginConfigurationCustomSetting,
+    PluginConfigurationRadioSetting
 } from '@mattermost/types/plugins/user_settings';
 
 import manifest from '@/manifest';
 import type {PluginRegistry} from '@/types/mattermost-webapp';
 import NotificationServicesSettings from './components/user_settings';
+import PriorityMappingSettings from './components/priority_mapping_settings';
+import MessageTemplateSettings from './components/message_template_settings';
+import TitleTemplateSettings from './components/title_template_settings';
+import MessageFormatsSettings from './components/message_formats_settings';
+import DigestSettings from './components/digest_settings';
+import RateLimitSettings from './components/rate_limit_settings';
+import ReactionSettings from './components/reaction_settings';
+import SubscriptionSettings from './components/subscription_settings';
+import WatchSettings from './components/watch_settings';
 
 export default class Plugin {
     // eslint-disable-next-line @typescript-eslint/no-unused-vars, @typescript-eslint/no-empty-function
@@ -35,6 +45,122 @@ export default class Plugin {
                             component: NotificationServicesSettings
                         } as PluginConfigurationCustomSetting
                     ]
+                } as PluginConfigurationSection,
+                {
+                    title: 'Notification Message',
+                    settings: [
+                        {
+                            type: 'custom',
+                            name: 'message_template',
+                            title: 'Message Template',
+                            helpText: 'Customize the text of your notifications with a Go template.',
+                            component: MessageTemplateSettings
+                        } as PluginConfigurationCustomSetting,
+                        {
+                            type: 'custom',
+                            name: 'title_template',
+                            title: 'Title Template',
+                            helpText: 'Customize the title and email subject of your notifications with a Go template.',
+                            component: TitleTemplateSettings
+                        } as PluginConfigurationCustomSetting,
+                        {
+                            type: 'custom',
+                            name: 'message_formats',
+                            title: 'Message Format',
+                            helpText: 'Choose how your notifications are formatted on each service.',
+                            component: MessageFormatsSettings
+                        } as PluginConfigurationCustomSetting
+                    ]
+                } as PluginConfigurationSection,
+                {
+                    title: 'Notification Triggers',
+                    settings: [
+                        {
+                            type: 'radio',
+                            name: 'follow_push_settings',
+                            title: 'Mattermost Push Settings',
+                            helpText: 'Follow your Mattermost mobile push notification settings, including the channels set to notify every post and your status.',
+                            default: 'false',
+                            options: [
+                                {value: 'true', text: 'Follow my push settings'},
+                                {value: 'false', text: 'Off'}
+                            ]
+                        } as PluginConfigurationRadioSetting,
+                        {
+                            type: 'radio',
+                            name: 'snooze_end_message',
+                            title: 'Snooze Reminder',
+                            helpText: 'Get a direct message from the Shoutrrr bot when a snooze set with /shoutrrr snooze ends.',
+                            default: 'false',
+                            options: [
+                                {value: 'true', text: 'On'},
+                                {value: 'false', text: 'Off'}
+                            ]
+                        } as PluginConfigurationRadioSetting,
+                        {
+                            type: 'radio',
+                            name: 'edit_notifications',
+                            title: 'Edited Messages',
+                            helpText: 'Get notified when a message is edited to mention you.',
+                            default: 'true',
+                            options: [
+                                {value: 'true', text: 'On'},
+                                {value: 'false', text: 'Off'}
+                            ]
+                        } as PluginConfigurationRadioSetting,
+                        {
+                            type: 'custom',
+                            name: 'reaction_notifications',
+                            title: 'Reactions',
+                            helpText: 'Get notified when someone reacts to your messages.',
+                            component: ReactionSettings
+                        } as PluginConfigurationCustomSetting,
+                        {
+                            type: 'custom',
+                            name: 'channel_subscriptions',
+                            title: 'Channel Subscriptions',
+                            helpText: 'Forward every post of a channel to your services, not just the ones mentioning you.',
+                            component: SubscriptionSettings
+                        } as PluginConfigurationCustomSetting,
+                        {
+                            type: 'custom',
+                            name: 'watch_rules',
+                            title: 'Watch Rules',
+                            helpText: 'Get notified of posts containing a phrase or matching a regular expression, even when they don\'t mention you.',
+                            component: WatchSettings
+                        } as PluginConfigurationCustomSetting
+                    ]
+                } as PluginConfigurationSection,
+                {
+                    title: 'Notification Digest',
+                    settings: [
+                        {
+                            type: 'custom',
+                            name: 'digest_interval',
+                            title: 'Digest',
+                            helpText: 'Receive a periodic summary of your mentions instead of a notification for each one.',
+                            component: DigestSettings
+                        } as PluginConfigurationCustomSetting,
+                        {
+                            type: 'custom',
+                            name: 'user_rate_limit',
+                            title: 'Rate Limits',
+                            helpText: 'Limit how many notifications you receive per minute during busy conversations.',
+                            component: RateLimitSettings
+                        } as PluginConfigurationCustomSetting
+                    ]
+                } as PluginConfigurationSection,
+                {
+                    title: 'Notification Priority',
+                    settings: [
+                        {
+                            type: 'custom',
+                            name: 'priority_mapping',
+                            title: 'Priority Mapping',
+                            helpText: 'Override the priority params sent to services such as ntfy, Pushover, Gotify and Telegram.',
+                            component: PriorityMappingSettings
+                        } as PluginConfigurationCustomSetting
+                    ]
                 } as PluginConfigurationSection
             ]
         };